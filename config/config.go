@@ -1,13 +1,26 @@
 package config
 
 import (
+	"encoding/base64"
 	"log"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
+// OIDCProviderConfig describes one OpenID Connect provider users can sign in with
+type OIDCProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	RedirectURL  string
+}
+
 type Config struct {
 	ServerPort         string
 	DBHost             string
@@ -17,21 +30,74 @@ type Config struct {
 	DBSSLMode          string
 	DBName             string
 	JWTSecret          string
-	JWTExpirationHours int
+	JWTKeys            string
+	JWTIssuer          string
+	JWTAudience        string
+	EncryptionKey      string
+	AccessTokenMinutes int
+	RefreshTokenDays   int
+
+	AdminEmails    []string
+	TrustedProxies []string
+
+	LoginThrottleStore  string
+	LoginMaxFailures    int
+	LoginMaxIPFailures  int
+	LoginLockoutMinutes int
+
+	PasswordMinLength   int
+	PasswordMinEntropy  int
+	CommonPasswordsFile string
+	Argon2MemoryKB      int
+	Argon2Time          int
+	Argon2Parallelism   int
+
+	AppName                  string
+	AppBaseURL               string
+	PasswordResetMinutes     int
+	MagicLinkMinutes         int
+	MagicLinkEmailLimit      int
+	MagicLinkIPLimit         int
+	EmailVerificationHours   int
+	RequireEmailVerification bool
+	TaskMaxDepth             int
+
+	MailDriver   string
+	MailFrom     string
+	MailLogFile  string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+
+	OIDCProviders []OIDCProviderConfig
+
+	WebAuthnRPID    string
+	WebAuthnRPName  string
+	WebAuthnOrigins []string
+
+	RemindersEnabled      bool
+	ReminderPollSeconds   int
+	ReminderBatchSize     int
+	ReminderMaxAttempts   int
+	WebhookSecret         string
+	WebhookTimeoutSeconds int
+	WebhookAllowPrivate   bool
+
+	TrashRetentionDays        int
+	TrashPurgeIntervalMinutes int
 }
 
 var AppConfig *Config
 
+// defaultJWTSecret is only acceptable for local development
+const defaultJWTSecret = "default-secret"
+
 func LoadConfig() {
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, relying on environment variables")
 	}
 
-	expirationHours, err := strconv.Atoi(getEnv("JWT_EXPIRATION_HOURS", "24"))
-	if err != nil {
-		expirationHours = 24
-	}
-
 	AppConfig = &Config{
 		ServerPort:         getEnv("SERVER_PORT", "8080"),
 		DBHost:             getEnv("DB_HOST", "localhost"),
@@ -40,8 +106,94 @@ func LoadConfig() {
 		DBPassword:         getEnv("DB_PASSWORD", "password"),
 		DBName:             getEnv("DB_NAME", "todo_db"),
 		DBSSLMode:          getEnv("DB_SSLMODE", "disable"),
-		JWTSecret:          getEnv("JWT_SECRET", "default-secret"),
-		JWTExpirationHours: expirationHours,
+		JWTSecret:          getEnv("JWT_SECRET", defaultJWTSecret),
+		JWTKeys:            getEnv("JWT_KEYS", ""),
+		EncryptionKey:      getEnv("ENCRYPTION_KEY", ""),
+		AccessTokenMinutes: getEnvInt("JWT_ACCESS_TOKEN_MINUTES", 15),
+		RefreshTokenDays:   getEnvInt("REFRESH_TOKEN_DAYS", 30),
+
+		AdminEmails:    getEnvList("ADMIN_EMAILS"),
+		TrustedProxies: getEnvList("TRUSTED_PROXIES"),
+
+		LoginThrottleStore:  getEnv("LOGIN_THROTTLE_STORE", "postgres"),
+		LoginMaxFailures:    getEnvInt("LOGIN_MAX_FAILURES", 10),
+		LoginMaxIPFailures:  getEnvInt("LOGIN_MAX_IP_FAILURES", 50),
+		LoginLockoutMinutes: getEnvInt("LOGIN_LOCKOUT_MINUTES", 15),
+
+		PasswordMinLength:   getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordMinEntropy:  getEnvInt("PASSWORD_MIN_ENTROPY", 50),
+		CommonPasswordsFile: getEnv("COMMON_PASSWORDS_FILE", ""),
+		Argon2MemoryKB:      getEnvInt("ARGON2_MEMORY_KB", 64*1024),
+		Argon2Time:          getEnvInt("ARGON2_TIME", 3),
+		Argon2Parallelism:   getEnvInt("ARGON2_PARALLELISM", 2),
+
+		AppName:                  getEnv("APP_NAME", "Todo API"),
+		AppBaseURL:               getEnv("APP_BASE_URL", "http://localhost:8080"),
+		PasswordResetMinutes:     getEnvInt("PASSWORD_RESET_MINUTES", 30),
+		MagicLinkMinutes:         getEnvInt("MAGIC_LINK_MINUTES", 15),
+		MagicLinkEmailLimit:      getEnvInt("MAGIC_LINK_EMAIL_LIMIT", 3),
+		MagicLinkIPLimit:         getEnvInt("MAGIC_LINK_IP_LIMIT", 20),
+		EmailVerificationHours:   getEnvInt("EMAIL_VERIFICATION_HOURS", 24),
+		RequireEmailVerification: getEnvBool("REQUIRE_EMAIL_VERIFICATION", false),
+		TaskMaxDepth:             getEnvInt("TASK_MAX_DEPTH", 5),
+
+		MailDriver:   getEnv("MAIL_DRIVER", "log"),
+		MailFrom:     getEnv("MAIL_FROM", "no-reply@localhost"),
+		MailLogFile:  getEnv("MAIL_LOG_FILE", ""),
+		SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+
+		WebAuthnRPID:    getEnv("WEBAUTHN_RP_ID", ""),
+		WebAuthnOrigins: getEnvList("WEBAUTHN_ORIGINS"),
+
+		RemindersEnabled:      getEnvBool("REMINDERS_ENABLED", true),
+		ReminderPollSeconds:   getEnvInt("REMINDER_POLL_SECONDS", 15),
+		ReminderBatchSize:     getEnvInt("REMINDER_BATCH_SIZE", 50),
+		ReminderMaxAttempts:   getEnvInt("REMINDER_MAX_ATTEMPTS", 5),
+		WebhookSecret:         getEnv("WEBHOOK_SECRET", ""),
+		WebhookTimeoutSeconds: getEnvInt("WEBHOOK_TIMEOUT_SECONDS", 10),
+		WebhookAllowPrivate:   getEnvBool("WEBHOOK_ALLOW_PRIVATE", false),
+
+		TrashRetentionDays:        getEnvInt("TRASH_RETENTION_DAYS", 30),
+		TrashPurgeIntervalMinutes: getEnvInt("TRASH_PURGE_INTERVAL_MINUTES", 60),
+	}
+
+	AppConfig.OIDCProviders = loadOIDCProviders(AppConfig.AppBaseURL)
+
+	// tokens are issued by and for this API unless it sits behind a shared identity setup
+	AppConfig.JWTIssuer = getEnv("JWT_ISSUER", AppConfig.AppBaseURL)
+	AppConfig.JWTAudience = getEnv("JWT_AUDIENCE", AppConfig.AppBaseURL)
+
+	// passkeys are bound to a domain, by default the one the app is served from
+	AppConfig.WebAuthnRPName = getEnv("WEBAUTHN_RP_NAME", AppConfig.AppName)
+	if AppConfig.WebAuthnRPID == "" {
+		if base, err := url.Parse(AppConfig.AppBaseURL); err == nil {
+			AppConfig.WebAuthnRPID = base.Hostname()
+		}
+	}
+	if len(AppConfig.WebAuthnOrigins) == 0 {
+		AppConfig.WebAuthnOrigins = []string{AppConfig.AppBaseURL}
+	}
+
+	// the secret also signs emailed links, so it matters even with asymmetric JWT keys
+	if AppConfig.JWTSecret == defaultJWTSecret && getEnv("GIN_MODE", "debug") != "debug" {
+		log.Fatal("JWT_SECRET must be set to a non-default value outside debug mode")
+	}
+
+	// secrets kept in the database, such as TOTP seeds, are encrypted with this key
+	if AppConfig.EncryptionKey == "" {
+		if getEnv("GIN_MODE", "debug") != "debug" {
+			log.Fatal("ENCRYPTION_KEY must be set outside debug mode")
+		}
+	} else if key, err := base64.StdEncoding.DecodeString(AppConfig.EncryptionKey); err != nil || len(key) != 32 {
+		log.Fatal("ENCRYPTION_KEY must be 32 random bytes, base64 encoded")
+	}
+
+	if AppConfig.Argon2Time < 1 || AppConfig.Argon2Parallelism < 1 || AppConfig.Argon2Parallelism > 255 ||
+		AppConfig.Argon2MemoryKB < 8*AppConfig.Argon2Parallelism {
+		log.Fatal("ARGON2_TIME, ARGON2_PARALLELISM and ARGON2_MEMORY_KB are out of range")
 	}
 
 	log.Println("Configuration loaded successfully")
@@ -54,3 +206,65 @@ func getEnv(key, defaultValue string) string {
 
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	val, err := strconv.Atoi(getEnv(key, strconv.Itoa(defaultValue)))
+	if err != nil {
+		log.Printf("Invalid value for %s, using default %d", key, defaultValue)
+		return defaultValue
+	}
+
+	return val
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	val, err := strconv.ParseBool(getEnv(key, strconv.FormatBool(defaultValue)))
+	if err != nil {
+		log.Printf("Invalid value for %s, using default %t", key, defaultValue)
+		return defaultValue
+	}
+
+	return val
+}
+
+func getEnvList(key string) []string {
+	var values []string
+	for _, val := range strings.Split(getEnv(key, ""), ",") {
+		if val = strings.TrimSpace(val); val != "" {
+			values = append(values, val)
+		}
+	}
+
+	return values
+}
+
+// loadOIDCProviders reads OIDC_PROVIDERS=name,... and the OIDC_<NAME>_* settings of each provider
+func loadOIDCProviders(appBaseURL string) []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+
+	for _, name := range getEnvList("OIDC_PROVIDERS") {
+		name = strings.ToLower(name)
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+
+		provider := OIDCProviderConfig{
+			Name:         name,
+			Issuer:       getEnv(prefix+"ISSUER", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			Scopes:       getEnvList(prefix + "SCOPES"),
+			RedirectURL:  getEnv(prefix+"REDIRECT_URL", appBaseURL+"/api/v1/auth/oidc/"+name+"/callback"),
+		}
+
+		if provider.Issuer == "" || provider.ClientID == "" {
+			log.Fatalf("%sISSUER and %sCLIENT_ID are required", prefix, prefix)
+		}
+
+		if len(provider.Scopes) == 0 {
+			provider.Scopes = []string{"openid", "email", "profile"}
+		}
+
+		providers = append(providers, provider)
+	}
+
+	return providers
+}
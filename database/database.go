@@ -6,6 +6,7 @@ import (
 
 	"github.com/lieucongduy182/go-gin-todo-api/config"
 	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/utils"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -32,10 +33,73 @@ func Connect() {
 
 	fmt.Println("✅ Connected to Database!")
 	// Migrate the schema
-	if err := DB.AutoMigrate(&models.User{}, &models.Task{}); err != nil {
+	if err := DB.AutoMigrate(
+		&models.User{},
+		&models.Task{},
+		&models.Tag{},
+		&models.Project{},
+		&models.TaskDependency{},
+		&models.TaskRevision{},
+		&models.Reminder{},
+		&models.ReminderDelivery{},
+		&models.Notification{},
+		&models.RefreshToken{},
+		&models.RevokedToken{},
+		&models.PasswordResetToken{},
+		&models.RecoveryCode{},
+		&models.PersonalAccessToken{},
+		&models.Role{},
+		&models.LoginAttempt{},
+		&models.AuditEvent{},
+		&models.UserIdentity{},
+		&models.Session{},
+		&models.MagicLinkToken{},
+		&models.WebAuthnCredential{},
+		&models.WebAuthnCeremony{},
+	); err != nil {
 		log.Fatal("Failed to migrate database", err)
 	}
 
+	// users.is_admin was replaced by users.role
+	if DB.Migrator().HasColumn(&models.User{}, "is_admin") {
+		if err := DB.Exec("UPDATE users SET role = ? WHERE is_admin", models.RoleAdmin).Error; err != nil {
+			log.Fatal("Failed to migrate admin flags to roles", err)
+		}
+
+		if err := DB.Migrator().DropColumn(&models.User{}, "is_admin"); err != nil {
+			log.Fatal("Failed to drop users.is_admin", err)
+		}
+	}
+
+	// TOTP secrets used to be stored in plain text
+	var totpUsers []models.User
+	if err := DB.Select("id", "totp_secret").Where("totp_secret <> ''").Find(&totpUsers).Error; err != nil {
+		log.Fatal("Failed to load TOTP secrets", err)
+	}
+
+	for _, user := range totpUsers {
+		if utils.IsEncrypted(user.TOTPSecret) {
+			continue
+		}
+
+		sealed, err := utils.EncryptString(user.TOTPSecretContext(), user.TOTPSecret)
+		if err != nil {
+			log.Fatal("Failed to encrypt TOTP secret", err)
+		}
+
+		if err := DB.Model(&user).UpdateColumn("totp_secret", sealed).Error; err != nil {
+			log.Fatal("Failed to encrypt TOTP secret", err)
+		}
+	}
+
+	if len(cfg.AdminEmails) > 0 {
+		if err := DB.Model(&models.User{}).
+			Where("email IN ?", cfg.AdminEmails).
+			Update("role", models.RoleAdmin).Error; err != nil {
+			log.Fatal("Failed to promote admin users", err)
+		}
+	}
+
 	fmt.Println("✅ Database migrated successfully!")
 }
 
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/service"
+)
+
+type AdminHandler struct {
+	userService  service.UserService
+	adminService service.AdminService
+	roleService  service.RoleService
+}
+
+func NewAdminHandler(
+	userService service.UserService,
+	adminService service.AdminService,
+	roleService service.RoleService,
+) *AdminHandler {
+	return &AdminHandler{
+		userService:  userService,
+		adminService: adminService,
+		roleService:  roleService,
+	}
+}
+
+// respondAdminError maps admin and role service errors to HTTP responses
+func respondAdminError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, repository.ErrRoleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+	case errors.Is(err, service.ErrCannotModifySelf),
+		errors.Is(err, service.ErrCannotImpersonate),
+		errors.Is(err, service.ErrCannotDisable),
+		errors.Is(err, service.ErrBuiltinRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrRoleAlreadyExists),
+		errors.Is(err, service.ErrRoleInUse),
+		errors.Is(err, service.ErrLastAdmin):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	page, pageSize := parsePagination(c)
+
+	response, err := h.userService.GetAllUsers(page, pageSize)
+	if err != nil {
+		respondAdminError(c, err, "Failed to fetch users")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	userID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	user, err := h.adminService.GetUser(userID)
+	if err != nil {
+		respondAdminError(c, err, "Failed to fetch user")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": user})
+}
+
+func (h *AdminHandler) DisableUser(c *gin.Context) {
+	actorID := c.MustGet("userID").(uint)
+
+	userID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	user, err := h.adminService.DisableUser(actorID, userID)
+	if err != nil {
+		respondAdminError(c, err, "Failed to disable user")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    user,
+		"message": "Disabled User Successfully",
+	})
+}
+
+func (h *AdminHandler) EnableUser(c *gin.Context) {
+	actorID := c.MustGet("userID").(uint)
+
+	userID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	user, err := h.adminService.EnableUser(actorID, userID)
+	if err != nil {
+		respondAdminError(c, err, "Failed to enable user")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    user,
+		"message": "Enabled User Successfully",
+	})
+}
+
+func (h *AdminHandler) AssignRole(c *gin.Context) {
+	actorID := c.MustGet("userID").(uint)
+
+	userID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var input models.AssignRoleRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.adminService.AssignRole(actorID, userID, input.Role)
+	if err != nil {
+		respondAdminError(c, err, "Failed to assign role")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    user,
+		"message": "Assigned Role Successfully",
+	})
+}
+
+func (h *AdminHandler) ImpersonateUser(c *gin.Context) {
+	actorID := c.MustGet("userID").(uint)
+
+	userID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	response, err := h.adminService.Impersonate(actorID, userID)
+	if err != nil {
+		respondAdminError(c, err, "Failed to impersonate user")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": response})
+}
+
+func (h *AdminHandler) ListRoles(c *gin.Context) {
+	roles, err := h.roleService.ListRoles()
+	if err != nil {
+		respondAdminError(c, err, "Failed to fetch roles")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  roles,
+		"count": len(roles),
+	})
+}
+
+func (h *AdminHandler) CreateRole(c *gin.Context) {
+	var input models.CreateRoleRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, err := h.roleService.CreateRole(&input)
+	if err != nil {
+		respondAdminError(c, err, "Failed to create role")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":    role,
+		"message": "Created Role Successfully",
+	})
+}
+
+func (h *AdminHandler) UpdateRole(c *gin.Context) {
+	var input models.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, err := h.roleService.UpdateRole(c.Param("name"), &input)
+	if err != nil {
+		respondAdminError(c, err, "Failed to update role")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    role,
+		"message": "Updated Role Successfully",
+	})
+}
+
+func (h *AdminHandler) DeleteRole(c *gin.Context) {
+	if err := h.roleService.DeleteRole(c.Param("name")); err != nil {
+		respondAdminError(c, err, "Failed to delete role")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Deleted Role Successfully"})
+}
@@ -1,15 +1,34 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/lieucongduy182/go-gin-todo-api/database"
 	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/service"
 	"github.com/lieucongduy182/go-gin-todo-api/utils"
 )
 
-func Register(c *gin.Context) {
+type AuthHandler struct {
+	userService   service.UserService
+	tokenService  service.TokenService
+	loginThrottle service.LoginThrottleService
+}
+
+func NewAuthHandler(
+	userService service.UserService,
+	tokenService service.TokenService,
+	loginThrottle service.LoginThrottleService,
+) *AuthHandler {
+	return &AuthHandler{
+		userService:   userService,
+		tokenService:  tokenService,
+		loginThrottle: loginThrottle,
+	}
+}
+
+func (h *AuthHandler) Register(c *gin.Context) {
 	var input models.RegisterRequest
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -17,101 +36,149 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	// Check if user already exist
-	var existingUser models.User
-	if err := database.DB.Where("email = ? OR username = ?", input.Email, input.Username).First(&existingUser).Error; err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "User with this email or username already exists"})
+	response, err := h.userService.Register(&input, clientInfo(c))
+	if err != nil {
+		if errors.Is(err, service.ErrUserAlreadyExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "User with this email or username already exists"})
+			return
+		}
+
+		if errors.Is(err, utils.ErrWeakPassword) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register user"})
 		return
 	}
 
-	user := models.User{
-		Username: input.Username,
-		Email:    input.Email,
-	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Register successfully",
+		"data":    response,
+	})
+}
 
-	// hash password
-	if err := user.HashPassword(input.Password); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+func (h *AuthHandler) Login(c *gin.Context) {
+	var input models.LoginRequest
+
+	// Validate input
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := database.DB.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+	response, challenge, err := h.userService.Login(&input, clientInfo(c))
+	if err != nil {
+		if respondThrottled(c, err) {
+			return
+		}
+
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+			return
+		}
+
+		if errors.Is(err, service.ErrAccountDisabled) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account has been disabled"})
+			return
+		}
+
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to login"})
 		return
 	}
 
-	token, err := utils.GenerateToken(user.ID, user.Email)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+	if challenge != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Two-factor authentication required",
+			"data":    challenge,
+		})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "Register successfully",
-		"user": gin.H{
-			"id":       user.ID,
-			"email":    user.Email,
-			"username": user.Username,
-		},
-		"token": token,
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Login successfully",
+		"data":    response,
 	})
 }
 
-func Login(c *gin.Context) {
-	var input models.LoginRequest
-
-	// Validate input
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+func (h *AuthHandler) UnlockAccount(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'token' is required"})
 		return
 	}
 
-	// Find User
-	var user models.User
-	if err := database.DB.Where("email = ?", input.Email).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+	if err := h.loginThrottle.Unlock(token); err != nil {
+		if errors.Is(err, service.ErrInvalidUnlockToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired unlock link"})
+			return
+		}
+
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock account"})
 		return
 	}
 
-	// Check password
-	if err := user.CheckPassword(input.Password); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlocked, you can sign in again"})
+}
+
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var input models.RefreshTokenRequest
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Generate token
-	token, err := utils.GenerateToken(user.ID, user.Email)
+	response, err := h.tokenService.Refresh(input.RefreshToken, clientInfo(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		if errors.Is(err, service.ErrInvalidRefreshToken) || errors.Is(err, service.ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Login successfully",
-		"user": gin.H{
-			"id":       user.ID,
-			"email":    user.Email,
-			"username": user.Username,
-		},
-		"token": token,
+		"message": "Refreshed token successfully",
+		"data":    response,
 	})
 }
 
-func GetProfile(c *gin.Context) {
+func (h *AuthHandler) Logout(c *gin.Context) {
+	claims := c.MustGet("claims").(*utils.Claims)
+
+	// the body is optional: the session behind the access token is ended either way
+	var input models.LogoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := h.tokenService.Logout(claims, input.RefreshToken); err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logout successfully"})
+}
+
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
 	userID := c.MustGet("userID").(uint)
 
-	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	if err := h.tokenService.LogoutAll(userID); err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout from all devices"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"user": gin.H{
-			"id":        user.ID,
-			"email":     user.Email,
-			"username":  user.Username,
-			"createdAt": user.CreatedAt,
-		},
-	})
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out from all devices successfully"})
 }
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/service"
+	"github.com/lieucongduy182/go-gin-todo-api/utils"
+)
+
+const (
+	defaultPage     = 1
+	defaultPageSize = 20
+	maxPageSize     = 100
+
+	maxUserAgentLength  = 512
+	maxDeviceNameLength = 100
+)
+
+// parsePagination reads page and page_size from the query string, falling back to sane defaults
+func parsePagination(c *gin.Context) (int, int) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", strconv.Itoa(defaultPage)))
+	if err != nil || page < 1 {
+		page = defaultPage
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultPageSize)))
+	if err != nil || pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	return page, pageSize
+}
+
+// splitQueryList splits a comma separated query value, dropping empty items
+func splitQueryList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// parseIDParam parses a numeric path parameter and writes a 400 response if it is invalid
+func parseIDParam(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return 0, false
+	}
+
+	return uint(id), true
+}
+
+// etag is the strong entity tag of a resource version
+func etag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// etagMatches reports whether an If-Match or If-None-Match header matches tag.
+// Weak entity tags only match with weak comparison, which If-None-Match uses and If-Match does not.
+func etagMatches(header, tag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+
+		if candidate == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ifMatchVersion resolves an If-Match header to the version it pins a write to.
+// current loads the version the resource has now; its errors are written with respond.
+// The version is nil without the header, and ok is false once a response has been written.
+func ifMatchVersion(
+	c *gin.Context,
+	current func() (uint, error),
+	respond func(*gin.Context, error, string),
+	fallback string,
+) (version *uint, ok bool) {
+	match := c.GetHeader("If-Match")
+	if match == "" {
+		return nil, true
+	}
+
+	loaded, err := current()
+	if err != nil {
+		respond(c, err, fallback)
+		return nil, false
+	}
+
+	if !etagMatches(match, etag(loaded), false) {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Task has changed since it was read"})
+		return nil, false
+	}
+
+	return &loaded, true
+}
+
+// respondVersionError writes the response for a write that lost against another version of a task
+func respondVersionError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrPreconditionFailed):
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Task has changed since it was read"})
+	case errors.Is(err, repository.ErrTaskVersionConflict):
+		// without If-Match the client did not ask for a precondition, the write just lost a race
+		if c.GetHeader("If-Match") != "" {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Task has changed since it was read"})
+		} else {
+			c.JSON(http.StatusConflict, gin.H{"error": "Task was changed by another request, please retry"})
+		}
+	default:
+		return false
+	}
+
+	return true
+}
+
+// respondThrottled writes a 429 with Retry-After if err is a login throttling error
+func respondThrottled(c *gin.Context, err error) bool {
+	var throttled *service.LoginThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+
+	seconds := int(math.Ceil(throttled.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Too many failed login attempts, please try again later",
+		"retry_after": seconds,
+	})
+
+	return true
+}
+
+// clientInfo describes the calling device for session records.
+// Clients may name themselves with an X-Device-Name header, otherwise the User-Agent is summarised.
+func clientInfo(c *gin.Context) *models.ClientInfo {
+	userAgent := truncate(c.Request.UserAgent(), maxUserAgentLength)
+
+	deviceName := truncate(strings.TrimSpace(c.GetHeader("X-Device-Name")), maxDeviceNameLength)
+	if deviceName == "" {
+		deviceName = utils.DescribeUserAgent(userAgent)
+	}
+
+	return &models.ClientInfo{
+		IP:         c.ClientIP(),
+		UserAgent:  userAgent,
+		DeviceName: deviceName,
+	}
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+
+	return strings.ToValidUTF8(s[:max], "")
+}
@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/utils"
+)
+
+// GetJWKS publishes the public keys other services use to verify our access tokens
+func GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, utils.PublicJWKS())
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/config"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/service"
+)
+
+// magicLinkPage asks for a click before the emailed link is used,
+// so mail scanners and link previews that open it do not log anybody in
+var magicLinkPage = template.Must(template.New("magic-link").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Sign in to {{.AppName}}</title>
+</head>
+<body>
+{{if .Token}}<form method="post" action="/api/v1/auth/magic-link/callback">
+<input type="hidden" name="token" value="{{.Token}}">
+<p>Continue to sign in to {{.AppName}}.</p>
+<button type="submit">Sign in</button>
+</form>
+{{else}}<p>This sign-in link is invalid or has expired. Request a new one to sign in to {{.AppName}}.</p>
+{{end}}</body>
+</html>
+`))
+
+type MagicLinkHandler struct {
+	magicLinkService service.MagicLinkService
+}
+
+func NewMagicLinkHandler(magicLinkService service.MagicLinkService) *MagicLinkHandler {
+	return &MagicLinkHandler{magicLinkService: magicLinkService}
+}
+
+func (h *MagicLinkHandler) RequestLink(c *gin.Context) {
+	var input models.MagicLinkRequest
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.magicLinkService.RequestLink(&input, clientInfo(c)); err != nil {
+		var limited *service.MagicLinkRateLimitedError
+		if errors.As(err, &limited) {
+			seconds := int(math.Ceil(limited.RetryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Too many login links requested, please try again later",
+				"retry_after": seconds,
+			})
+			return
+		}
+
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send login link"})
+		return
+	}
+
+	// same answer whether or not the account exists
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If an account exists for this email, a login link has been sent",
+	})
+}
+
+// ConfirmLink is where the emailed link points. It only shows a confirmation page; the link is used by Callback.
+func (h *MagicLinkHandler) ConfirmLink(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'token' is required"})
+		return
+	}
+
+	status := http.StatusOK
+	if err := h.magicLinkService.CheckLink(token); err != nil {
+		if !errors.Is(err, service.ErrInvalidMagicLink) {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login link"})
+			return
+		}
+
+		status = http.StatusUnauthorized
+		token = ""
+	}
+
+	var page bytes.Buffer
+	if err := magicLinkPage.Execute(&page, gin.H{"AppName": config.AppConfig.AppName, "Token": token}); err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login link"})
+		return
+	}
+
+	// the token is in the URL, so it must not leak through caches or the Referer header
+	c.Header("Cache-Control", "no-store")
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Header("Content-Security-Policy", "default-src 'none'; form-action 'self'; frame-ancestors 'none'")
+	c.Data(status, "text/html; charset=utf-8", page.Bytes())
+}
+
+// Callback uses the link, either from the confirmation page's form or as JSON from a client app
+func (h *MagicLinkHandler) Callback(c *gin.Context) {
+	var input models.MagicLinkLoginRequest
+	if err := c.ShouldBind(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, challenge, err := h.magicLinkService.Login(input.Token, clientInfo(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidMagicLink):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired login link"})
+		case errors.Is(err, service.ErrAccountDisabled):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account has been disabled"})
+		default:
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to login"})
+		}
+		return
+	}
+
+	if challenge != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Two-factor authentication required",
+			"data":    challenge,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Login successfully",
+		"data":    response,
+	})
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/service"
+)
+
+type MFAHandler struct {
+	mfaService service.MFAService
+}
+
+func NewMFAHandler(mfaService service.MFAService) *MFAHandler {
+	return &MFAHandler{mfaService: mfaService}
+}
+
+// respondMFAError maps MFA service errors to HTTP responses
+func respondMFAError(c *gin.Context, err error, fallback string) {
+	if respondThrottled(c, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, service.ErrTOTPAlreadyEnabled),
+		errors.Is(err, service.ErrTOTPNotEnrolled),
+		errors.Is(err, service.ErrTOTPNotEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidMFACode),
+		errors.Is(err, service.ErrInvalidMFAToken),
+		errors.Is(err, service.ErrIncorrectPassword):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrAccountDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account has been disabled"})
+	default:
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+func (h *MFAHandler) EnrollTOTP(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	enrollment, err := h.mfaService.EnrollTOTP(userID)
+	if err != nil {
+		respondMFAError(c, err, "Failed to start two-factor enrolment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    enrollment,
+		"message": "Scan the QR code and confirm with a code from your authenticator app",
+	})
+}
+
+func (h *MFAHandler) ConfirmTOTP(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var input models.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.mfaService.ConfirmTOTP(userID, input.Code, clientInfo(c))
+	if err != nil {
+		respondMFAError(c, err, "Failed to enable two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    codes,
+		"message": "Two-factor authentication enabled, store your recovery codes somewhere safe",
+	})
+}
+
+func (h *MFAHandler) DisableTOTP(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var input models.DisableTOTPRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.mfaService.DisableTOTP(userID, &input, clientInfo(c)); err != nil {
+		respondMFAError(c, err, "Failed to disable two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var input models.TOTPCodeRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.mfaService.RegenerateRecoveryCodes(userID, input.Code, clientInfo(c))
+	if err != nil {
+		respondMFAError(c, err, "Failed to regenerate recovery codes")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    codes,
+		"message": "Recovery codes regenerated, previous codes no longer work",
+	})
+}
+
+func (h *MFAHandler) CompleteLogin(c *gin.Context) {
+	var input models.MFALoginRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.mfaService.CompleteLogin(&input, clientInfo(c))
+	if err != nil {
+		respondMFAError(c, err, "Failed to login")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Login successfully",
+		"data":    response,
+	})
+}
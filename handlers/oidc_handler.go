@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/config"
+	"github.com/lieucongduy182/go-gin-todo-api/service"
+)
+
+const (
+	oidcStateCookie     = "oidc_state"
+	oidcStateCookiePath = "/api/v1/auth/oidc"
+)
+
+type OIDCHandler struct {
+	oidcService service.OIDCService
+}
+
+func NewOIDCHandler(oidcService service.OIDCService) *OIDCHandler {
+	return &OIDCHandler{oidcService: oidcService}
+}
+
+// respondOIDCError maps OIDC service errors to HTTP responses
+func respondOIDCError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrOIDCProviderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown sign-in provider"})
+	case errors.Is(err, service.ErrInvalidOIDCState):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrOIDCAuthFailed):
+		c.Error(err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": service.ErrOIDCAuthFailed.Error()})
+	case errors.Is(err, service.ErrOIDCEmailNotVerified),
+		errors.Is(err, service.ErrAccountDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrOIDCAccountNotLinked),
+		errors.Is(err, service.ErrUsernameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+func (h *OIDCHandler) ListProviders(c *gin.Context) {
+	providers := h.oidcService.Providers()
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  providers,
+		"count": len(providers),
+	})
+}
+
+// Login redirects the browser to the provider. The state, nonce and PKCE verifier
+// travel in a signed, HTTP-only cookie until the callback.
+func (h *OIDCHandler) Login(c *gin.Context) {
+	request, err := h.oidcService.StartLogin(c.Param("provider"))
+	if err != nil {
+		respondOIDCError(c, err, "Failed to start sign-in")
+		return
+	}
+
+	setOIDCStateCookie(c, request.State, int(service.OIDCStateTTL.Seconds()))
+	c.Redirect(http.StatusFound, request.URL)
+}
+
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	if providerErr := c.Query("error"); providerErr != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "Sign-in was cancelled or rejected by the provider",
+			"provider_error":    providerErr,
+			"error_description": c.Query("error_description"),
+		})
+		return
+	}
+
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameters 'code' and 'state' are required"})
+		return
+	}
+
+	storedState, err := c.Cookie(oidcStateCookie)
+	if err != nil {
+		respondOIDCError(c, service.ErrInvalidOIDCState, "Failed to sign in")
+		return
+	}
+
+	// the state is single-use whatever the outcome
+	setOIDCStateCookie(c, "", -1)
+
+	response, challenge, err := h.oidcService.CompleteLogin(
+		c.Request.Context(), c.Param("provider"), code, state, storedState, clientInfo(c),
+	)
+	if err != nil {
+		respondOIDCError(c, err, "Failed to sign in")
+		return
+	}
+
+	if challenge != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Two-factor authentication required",
+			"data":    challenge,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Login successfully",
+		"data":    response,
+	})
+}
+
+func setOIDCStateCookie(c *gin.Context, value string, maxAge int) {
+	// Lax, not Strict: the cookie has to come back on the top-level redirect from the provider
+	c.SetSameSite(http.SameSiteLaxMode)
+	secure := strings.HasPrefix(config.AppConfig.AppBaseURL, "https://")
+	c.SetCookie(oidcStateCookie, value, maxAge, oidcStateCookiePath, "", secure, true)
+}
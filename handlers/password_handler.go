@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/service"
+	"github.com/lieucongduy182/go-gin-todo-api/utils"
+)
+
+type PasswordHandler struct {
+	passwordResetService service.PasswordResetService
+}
+
+func NewPasswordHandler(passwordResetService service.PasswordResetService) *PasswordHandler {
+	return &PasswordHandler{passwordResetService: passwordResetService}
+}
+
+func (h *PasswordHandler) ForgotPassword(c *gin.Context) {
+	var input models.ForgotPasswordRequest
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.passwordResetService.RequestReset(&input); err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request password reset"})
+		return
+	}
+
+	// same answer whether or not the account exists
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If an account exists for this email, a password reset link has been sent",
+	})
+}
+
+func (h *PasswordHandler) ResetPassword(c *gin.Context) {
+	var input models.ResetPasswordRequest
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.passwordResetService.ResetPassword(&input); err != nil {
+		if errors.Is(err, service.ErrInvalidResetToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired password reset token"})
+			return
+		}
+
+		if errors.Is(err, utils.ErrWeakPassword) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully, please login again"})
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/service"
+)
+
+type PersonalAccessTokenHandler struct {
+	patService service.PersonalAccessTokenService
+}
+
+func NewPersonalAccessTokenHandler(patService service.PersonalAccessTokenService) *PersonalAccessTokenHandler {
+	return &PersonalAccessTokenHandler{patService: patService}
+}
+
+func (h *PersonalAccessTokenHandler) CreateToken(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var input models.CreatePersonalAccessTokenRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := h.patService.Create(userID, &input)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create personal access token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":    token,
+		"message": "Created token successfully, copy it now as it will not be shown again",
+	})
+}
+
+func (h *PersonalAccessTokenHandler) ListTokens(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	tokens, err := h.patService.List(userID)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch personal access tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  tokens,
+		"count": len(tokens),
+	})
+}
+
+func (h *PersonalAccessTokenHandler) RevokeToken(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	tokenID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.patService.Revoke(userID, tokenID); err != nil {
+		if errors.Is(err, repository.ErrPersonalAccessTokenNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Personal access token not found"})
+			return
+		}
+
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke personal access token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Revoked token successfully"})
+}
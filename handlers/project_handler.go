@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/service"
+)
+
+type ProjectHandler struct {
+	projectService service.ProjectService
+	taskService    service.TaskService
+}
+
+func NewProjectHandler(projectService service.ProjectService, taskService service.TaskService) *ProjectHandler {
+	return &ProjectHandler{projectService: projectService, taskService: taskService}
+}
+
+// respondProjectError maps project service errors to HTTP responses
+func respondProjectError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrProjectNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+	case errors.Is(err, service.ErrInvalidProjectName),
+		errors.Is(err, service.ErrInvalidProjectDeleteMode),
+		errors.Is(err, service.ErrInvalidTagMode),
+		errors.Is(err, service.ErrInvalidTagName):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+func (h *ProjectHandler) ListProjects(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	// archived projects are hidden unless ?include_archived=true
+	includeArchived := c.Query("include_archived") == "true"
+
+	projects, err := h.projectService.ListProjects(userID, includeArchived)
+	if err != nil {
+		respondProjectError(c, err, "Failed to fetch projects")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  projects,
+		"count": len(projects),
+	})
+}
+
+func (h *ProjectHandler) GetProject(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	projectID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	project, err := h.projectService.GetProject(projectID, userID)
+	if err != nil {
+		respondProjectError(c, err, "Failed to fetch project")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": project})
+}
+
+func (h *ProjectHandler) CreateProject(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var input models.CreateProjectRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project, err := h.projectService.CreateProject(userID, &input)
+	if err != nil {
+		respondProjectError(c, err, "Failed to create project")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": project, "message": "Created Project successfully"})
+}
+
+func (h *ProjectHandler) UpdateProject(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	projectID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var input models.UpdateProjectRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project, err := h.projectService.UpdateProject(projectID, userID, &input)
+	if err != nil {
+		respondProjectError(c, err, "Failed to update project")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    project,
+		"message": "Updated Project Successfully",
+	})
+}
+
+// DeleteProject removes a project; ?mode=cascade deletes its tasks too, the default mode=inbox keeps them
+func (h *ProjectHandler) DeleteProject(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	projectID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.projectService.DeleteProject(projectID, userID, c.Query("mode")); err != nil {
+		respondProjectError(c, err, "Failed to delete project")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Deleted Project Successfully"})
+}
+
+func (h *ProjectHandler) GetProjectTasks(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	projectID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if _, err := h.projectService.GetProject(projectID, userID); err != nil {
+		respondProjectError(c, err, "Failed to fetch tasks")
+		return
+	}
+
+	page, pageSize := parsePagination(c)
+	filter := &models.TaskFilter{
+		Tags:      splitQueryList(c.Query("tags")),
+		TagMode:   c.Query("tag_mode"),
+		ProjectID: &projectID,
+	}
+
+	response, err := h.taskService.GetUserTaskPaginated(userID, filter, page, pageSize)
+	if err != nil {
+		respondProjectError(c, err, "Failed to fetch tasks")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *ProjectHandler) GetProjectStats(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	projectID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if _, err := h.projectService.GetProject(projectID, userID); err != nil {
+		respondProjectError(c, err, "Failed to fetch project stats")
+		return
+	}
+
+	stats, err := h.taskService.GetTaskByStats(userID, &models.TaskFilter{ProjectID: &projectID})
+	if err != nil {
+		respondProjectError(c, err, "Failed to fetch project stats")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": stats})
+}
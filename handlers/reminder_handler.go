@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/service"
+)
+
+type ReminderHandler struct {
+	reminderService     service.ReminderService
+	notificationService service.NotificationService
+}
+
+func NewReminderHandler(reminderService service.ReminderService, notificationService service.NotificationService) *ReminderHandler {
+	return &ReminderHandler{
+		reminderService:     reminderService,
+		notificationService: notificationService,
+	}
+}
+
+// respondReminderError maps reminder and notification service errors to HTTP responses
+func respondReminderError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrTaskNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+	case errors.Is(err, repository.ErrReminderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reminder not found"})
+	case errors.Is(err, repository.ErrNotificationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+	case errors.Is(err, service.ErrInvalidReminderTime),
+		errors.Is(err, service.ErrReminderNeedsDueDate),
+		errors.Is(err, service.ErrInvalidWebhookURL):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+func (h *ReminderHandler) ListReminders(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	reminders, err := h.reminderService.ListReminders(taskID, userID)
+	if err != nil {
+		respondReminderError(c, err, "Failed to fetch reminders")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  reminders,
+		"count": len(reminders),
+	})
+}
+
+func (h *ReminderHandler) CreateReminder(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var input models.CreateReminderRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reminder, err := h.reminderService.CreateReminder(taskID, userID, &input)
+	if err != nil {
+		respondReminderError(c, err, "Failed to create reminder")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": reminder, "message": "Created Reminder successfully"})
+}
+
+func (h *ReminderHandler) DeleteReminder(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	reminderID, ok := parseIDParam(c, "reminderId")
+	if !ok {
+		return
+	}
+
+	if err := h.reminderService.DeleteReminder(taskID, reminderID, userID); err != nil {
+		respondReminderError(c, err, "Failed to delete reminder")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Deleted Reminder Successfully"})
+}
+
+func (h *ReminderHandler) ListNotifications(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	notifications, unread, err := h.notificationService.ListNotifications(userID, c.Query("unread") == "true")
+	if err != nil {
+		respondReminderError(c, err, "Failed to fetch notifications")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   notifications,
+		"count":  len(notifications),
+		"unread": unread,
+	})
+}
+
+func (h *ReminderHandler) MarkNotificationRead(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	notificationID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.notificationService.MarkRead(notificationID, userID); err != nil {
+		respondReminderError(c, err, "Failed to update notification")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+}
+
+func (h *ReminderHandler) MarkAllNotificationsRead(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	if err := h.notificationService.MarkAllRead(userID); err != nil {
+		respondReminderError(c, err, "Failed to update notifications")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "All notifications marked as read"})
+}
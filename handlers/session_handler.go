@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/service"
+	"github.com/lieucongduy182/go-gin-todo-api/utils"
+)
+
+type SessionHandler struct {
+	sessionService service.SessionService
+}
+
+func NewSessionHandler(sessionService service.SessionService) *SessionHandler {
+	return &SessionHandler{sessionService: sessionService}
+}
+
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	claims := c.MustGet("claims").(*utils.Claims)
+
+	sessions, err := h.sessionService.List(claims.UserID, claims.SessionID)
+	if err != nil {
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  sessions,
+		"count": len(sessions),
+	})
+}
+
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	if err := h.sessionService.Revoke(userID, c.Param("id")); err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Revoked Session Successfully"})
+}
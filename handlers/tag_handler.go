@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/service"
+)
+
+type TagHandler struct {
+	tagService service.TagService
+}
+
+func NewTagHandler(tagService service.TagService) *TagHandler {
+	return &TagHandler{tagService: tagService}
+}
+
+// respondTagError maps tag service errors to HTTP responses
+func respondTagError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrTagNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+	case errors.Is(err, service.ErrTagAlreadyExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidTagName):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+func (h *TagHandler) ListTags(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	tags, err := h.tagService.ListTags(userID)
+	if err != nil {
+		respondTagError(c, err, "Failed to fetch tags")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  tags,
+		"count": len(tags),
+	})
+}
+
+func (h *TagHandler) CreateTag(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var input models.CreateTagRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag, err := h.tagService.CreateTag(userID, &input)
+	if err != nil {
+		respondTagError(c, err, "Failed to create tag")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": tag, "message": "Created Tag successfully"})
+}
+
+func (h *TagHandler) UpdateTag(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	tagID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var input models.UpdateTagRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag, err := h.tagService.UpdateTag(tagID, userID, &input)
+	if err != nil {
+		respondTagError(c, err, "Failed to update tag")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    tag,
+		"message": "Updated Tag Successfully",
+	})
+}
+
+func (h *TagHandler) DeleteTag(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	tagID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.tagService.DeleteTag(tagID, userID); err != nil {
+		respondTagError(c, err, "Failed to delete tag")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Deleted Tag Successfully"})
+}
@@ -1,20 +1,137 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/lieucongduy182/go-gin-todo-api/database"
 	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/service"
+	"github.com/lieucongduy182/go-gin-todo-api/utils"
 )
 
-func GetTasks(c *gin.Context) {
+type TaskHandler struct {
+	taskService service.TaskService
+}
+
+func NewTaskHandler(taskService service.TaskService) *TaskHandler {
+	return &TaskHandler{taskService: taskService}
+}
+
+// respondTaskError maps task service errors to HTTP responses
+func respondTaskError(c *gin.Context, err error, fallback string) {
+	if respondVersionError(c, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, repository.ErrTaskNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+	case errors.Is(err, repository.ErrProjectNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+	case errors.Is(err, service.ErrParentTaskNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Parent task not found"})
+	case errors.Is(err, service.ErrBlockerNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Blocking task not found"})
+	case errors.Is(err, repository.ErrDependencyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dependency not found"})
+	case errors.Is(err, repository.ErrRevisionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+	case errors.Is(err, service.ErrTaskBlocked):
+		c.JSON(http.StatusConflict, gin.H{"error": "Task cannot be completed while the tasks blocking it are open"})
+	case errors.Is(err, repository.ErrDependencyCycle):
+		c.JSON(http.StatusConflict, gin.H{"error": "Dependency would create a cycle"})
+	case errors.Is(err, service.ErrProjectArchived):
+		c.JSON(http.StatusConflict, gin.H{"error": "Tasks cannot be added to an archived project"})
+	case errors.Is(err, service.ErrInvalidPriority),
+		errors.Is(err, service.ErrInvalidTagMode),
+		errors.Is(err, service.ErrInvalidTagName),
+		errors.Is(err, service.ErrTaskTooDeep),
+		errors.Is(err, service.ErrRecurrenceNoDue),
+		errors.Is(err, utils.ErrInvalidRRule):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrTaskNotRecurring),
+		errors.Is(err, service.ErrSeriesEnded):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrEmailNotVerified):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Please verify your email address before creating tasks"})
+	default:
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// ifMatch pins a write to the version of the task named by If-Match
+func (h *TaskHandler) ifMatch(c *gin.Context, taskID, userID uint, fallback string) (*uint, bool) {
+	return ifMatchVersion(c, func() (uint, error) {
+		task, err := h.taskService.GetTask(taskID, userID)
+		if err != nil {
+			return 0, err
+		}
+		return task.Version, nil
+	}, respondTaskError, fallback)
+}
+
+func (h *TaskHandler) GetTasks(c *gin.Context) {
 	userID := c.MustGet("userID").(uint)
+	page, pageSize := parsePagination(c)
+
+	// ?tags=work,urgent&tag_mode=all|any
+	filter := &models.TaskFilter{
+		Tags:    splitQueryList(c.Query("tags")),
+		TagMode: c.Query("tag_mode"),
+	}
+	if !parseProjectFilter(c, filter) {
+		return
+	}
 
-	var tasks []models.Task
-	if err := database.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&tasks).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
+	response, err := h.taskService.GetUserTaskPaginated(userID, filter, page, pageSize)
+	if err != nil {
+		respondTaskError(c, err, "Failed to fetch tasks")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// parseProjectFilter applies ?project_id=<id>|inbox to filter and writes a 400 response if it is invalid
+func parseProjectFilter(c *gin.Context, filter *models.TaskFilter) bool {
+	value := c.Query("project_id")
+	switch value {
+	case "":
+		return true
+	case "inbox":
+		filter.InboxOnly = true
+		return true
+	}
+
+	projectID, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || projectID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "project_id must be a project id or 'inbox'"})
+		return false
+	}
+
+	id := uint(projectID)
+	filter.ProjectID = &id
+	return true
+}
+
+func (h *TaskHandler) SearchTasks(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	keyword := strings.TrimSpace(c.Query("q"))
+	if keyword == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'q' is required"})
+		return
+	}
+
+	tasks, err := h.taskService.SearchTasks(userID, keyword)
+	if err != nil {
+		respondTaskError(c, err, "Failed to search tasks")
 		return
 	}
 
@@ -24,46 +141,228 @@ func GetTasks(c *gin.Context) {
 	})
 }
 
-func GetTask(c *gin.Context) {
+func (h *TaskHandler) GetStats(c *gin.Context) {
 	userID := c.MustGet("userID").(uint)
-	taskID := c.Param("id")
 
-	var task models.Task
-	if err := database.DB.Where("id = ? AND user_id = ?", taskID, userID).First(&task).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Task not found"})
+	filter := &models.TaskFilter{}
+	if !parseProjectFilter(c, filter) {
+		return
+	}
+
+	stats, err := h.taskService.GetTaskByStats(userID, filter)
+	if err != nil {
+		respondTaskError(c, err, "Failed to fetch task stats")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": stats})
+}
+
+func (h *TaskHandler) GetTasksByPriority(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	tasks, err := h.taskService.GetTasksByPriority(userID, c.Param("priority"))
+	if err != nil {
+		respondTaskError(c, err, "Failed to fetch tasks")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  tasks,
+		"count": len(tasks),
+	})
+}
+
+func (h *TaskHandler) GetTasksByStatus(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var completed bool
+	switch c.Param("status") {
+	case "completed":
+		completed = true
+	case "pending":
+		completed = false
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Status must be 'completed' or 'pending'"})
+		return
+	}
+
+	tasks, err := h.taskService.GetTasksByStatus(userID, completed)
+	if err != nil {
+		respondTaskError(c, err, "Failed to fetch tasks")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  tasks,
+		"count": len(tasks),
+	})
+}
+
+func (h *TaskHandler) GetTask(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	task, err := h.taskService.GetTask(taskID, userID)
+	if err != nil {
+		respondTaskError(c, err, "Failed to fetch task")
+		return
+	}
+
+	tag := etag(task.Version)
+	c.Header("ETag", tag)
+	if match := c.GetHeader("If-None-Match"); match != "" && etagMatches(match, tag, true) {
+		c.Status(http.StatusNotModified)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"data": task})
 }
 
-func CreateTask(c *gin.Context) {
+func (h *TaskHandler) GetSubtasks(c *gin.Context) {
 	userID := c.MustGet("userID").(uint)
-	var input models.CreateTaskRequest
 
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	tasks, err := h.taskService.GetSubtasks(taskID, userID)
+	if err != nil {
+		respondTaskError(c, err, "Failed to fetch subtasks")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  tasks,
+		"count": len(tasks),
+	})
+}
+
+func (h *TaskHandler) GetTaskTree(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	tree, err := h.taskService.GetTaskTree(taskID, userID)
+	if err != nil {
+		respondTaskError(c, err, "Failed to fetch task tree")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": tree})
+}
+
+func (h *TaskHandler) GetDependencies(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	dependencies, err := h.taskService.GetDependencies(taskID, userID)
+	if err != nil {
+		respondTaskError(c, err, "Failed to fetch dependencies")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": dependencies})
+}
+
+func (h *TaskHandler) AddDependency(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var input models.AddDependencyRequest
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	task := models.Task{
-		UserID:      userID,
-		Title:       input.Title,
-		Description: input.Description,
-		Completed:   false,
+	dependencies, err := h.taskService.AddDependency(taskID, userID, &input)
+	if err != nil {
+		respondTaskError(c, err, "Failed to add dependency")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": dependencies, "message": "Added Dependency successfully"})
+}
+
+func (h *TaskHandler) RemoveDependency(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	blockedByID, ok := parseIDParam(c, "blockedById")
+	if !ok {
+		return
+	}
+
+	if err := h.taskService.RemoveDependency(taskID, blockedByID, userID); err != nil {
+		respondTaskError(c, err, "Failed to remove dependency")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Removed Dependency Successfully"})
+}
+
+// GetNextTasks lists open tasks in the order they can be worked on; unblocked ones come first
+func (h *TaskHandler) GetNextTasks(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	tasks, err := h.taskService.GetNextTasks(userID)
+	if err != nil {
+		respondTaskError(c, err, "Failed to fetch tasks")
+		return
 	}
 
-	if err := database.DB.Create(&task).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
+	c.JSON(http.StatusOK, gin.H{
+		"data":  tasks,
+		"count": len(tasks),
+	})
+}
+
+func (h *TaskHandler) CreateTask(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	var input models.CreateTaskRequest
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	task, err := h.taskService.CreateTask(userID, &input)
+	if err != nil {
+		respondTaskError(c, err, "Failed to create task")
+		return
+	}
+
+	c.Header("ETag", etag(task.Version))
 	c.JSON(http.StatusCreated, gin.H{"data": task, "message": "Created Task successfully"})
 }
 
-func UpdateTask(c *gin.Context) {
+func (h *TaskHandler) UpdateTask(c *gin.Context) {
 	userID := c.MustGet("userID").(uint)
-	taskID := c.Param("id")
+
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
 
 	var input models.UpdateTaskRequest
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -72,48 +371,176 @@ func UpdateTask(c *gin.Context) {
 		return
 	}
 
-	var task models.Task
-	if err := database.DB.Where("id = ? AND user_id = ?", taskID, userID).First(&task).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+	version, ok := h.ifMatch(c, taskID, userID, "Failed to update task")
+	if !ok {
 		return
 	}
+	input.Version = version
 
-	// Update fields
-	if input.Title != "" {
-		task.Title = input.Title
+	task, err := h.taskService.UpdateTask(taskID, userID, &input)
+	if err != nil {
+		respondTaskError(c, err, "Failed to update task")
+		return
 	}
 
-	if input.Description != "" {
-		task.Description = input.Description
+	c.Header("ETag", etag(task.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"data":    task,
+		"message": "Updated Task Successfully",
+	})
+}
+
+func (h *TaskHandler) MoveTask(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
 	}
 
-	if input.Completed != nil {
-		task.Completed = *input.Completed
+	var input models.MoveTaskRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	if err := database.DB.Save(&task).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+	version, ok := h.ifMatch(c, taskID, userID, "Failed to move task")
+	if !ok {
 		return
 	}
+	input.Version = version
 
+	task, err := h.taskService.MoveTask(taskID, userID, &input)
+	if err != nil {
+		respondTaskError(c, err, "Failed to move task")
+		return
+	}
+
+	c.Header("ETag", etag(task.Version))
 	c.JSON(http.StatusOK, gin.H{
 		"data":    task,
-		"message": "Updated Task Successfully",
+		"message": "Moved Task Successfully",
 	})
 }
 
-func DeleteTask(c *gin.Context) {
+// SkipOccurrence moves a recurring task to its next occurrence without completing it
+func (h *TaskHandler) SkipOccurrence(c *gin.Context) {
 	userID := c.MustGet("userID").(uint)
-	taskID := c.Param("id")
 
-	var task models.Task
-	if err := database.DB.Where("id = ? AND user_id = ?", taskID, userID).First(&task).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	version, ok := h.ifMatch(c, taskID, userID, "Failed to skip occurrence")
+	if !ok {
+		return
+	}
+
+	task, err := h.taskService.SkipOccurrence(taskID, userID, version)
+	if err != nil {
+		respondTaskError(c, err, "Failed to skip occurrence")
+		return
+	}
+
+	c.Header("ETag", etag(task.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"data":    task,
+		"message": "Skipped Occurrence Successfully",
+	})
+}
+
+// EndRecurrence stops a series; the current occurrence is kept as a one-off task
+func (h *TaskHandler) EndRecurrence(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	version, ok := h.ifMatch(c, taskID, userID, "Failed to end recurrence")
+	if !ok {
+		return
+	}
+
+	task, err := h.taskService.EndRecurrence(taskID, userID, version)
+	if err != nil {
+		respondTaskError(c, err, "Failed to end recurrence")
+		return
+	}
+
+	c.Header("ETag", etag(task.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"data":    task,
+		"message": "Ended Recurrence Successfully",
+	})
+}
+
+func (h *TaskHandler) GetTaskHistory(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	page, pageSize := parsePagination(c)
+
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	response, err := h.taskService.GetTaskHistory(taskID, userID, page, pageSize)
+	if err != nil {
+		respondTaskError(c, err, "Failed to fetch task history")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *TaskHandler) RevertTask(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	revisionID, ok := parseIDParam(c, "revision")
+	if !ok {
+		return
+	}
+
+	version, ok := h.ifMatch(c, taskID, userID, "Failed to revert task")
+	if !ok {
+		return
+	}
+
+	task, err := h.taskService.RevertTask(taskID, revisionID, userID, version)
+	if err != nil {
+		respondTaskError(c, err, "Failed to revert task")
+		return
+	}
+
+	c.Header("ETag", etag(task.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"data":    task,
+		"message": "Reverted Task Successfully",
+	})
+}
+
+func (h *TaskHandler) DeleteTask(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	version, ok := h.ifMatch(c, taskID, userID, "Failed to delete task")
+	if !ok {
 		return
 	}
 
-	if err := database.DB.Delete(&task).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete task"})
+	if err := h.taskService.DeleteTask(taskID, userID, version); err != nil {
+		respondTaskError(c, err, "Failed to delete task")
 		return
 	}
 
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/service"
+)
+
+type TrashHandler struct {
+	trashService service.TrashService
+	taskService  service.TaskService
+}
+
+func NewTrashHandler(trashService service.TrashService, taskService service.TaskService) *TrashHandler {
+	return &TrashHandler{
+		trashService: trashService,
+		taskService:  taskService,
+	}
+}
+
+// respondTrashError maps trash service errors to HTTP responses
+func respondTrashError(c *gin.Context, err error, fallback string) {
+	if respondVersionError(c, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, repository.ErrTaskNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found in trash"})
+	case errors.Is(err, service.ErrParentTaskTrashed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+func (h *TrashHandler) ListTrash(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+	page, pageSize := parsePagination(c)
+
+	response, err := h.trashService.ListTrash(userID, page, pageSize)
+	if err != nil {
+		respondTrashError(c, err, "Failed to fetch trash")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *TrashHandler) RestoreTask(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	version, ok := ifMatchVersion(c, func() (uint, error) {
+		task, err := h.trashService.GetTrashedTask(taskID, userID)
+		if err != nil {
+			return 0, err
+		}
+		return task.Version, nil
+	}, respondTrashError, "Failed to restore task")
+	if !ok {
+		return
+	}
+
+	if err := h.trashService.RestoreTask(taskID, userID, version); err != nil {
+		respondTrashError(c, err, "Failed to restore task")
+		return
+	}
+
+	task, err := h.taskService.GetTask(taskID, userID)
+	if err != nil {
+		respondTaskError(c, err, "Failed to fetch task")
+		return
+	}
+
+	c.Header("ETag", etag(task.Version))
+	c.JSON(http.StatusOK, gin.H{"data": task, "message": "Restored Task Successfully"})
+}
+
+func (h *TrashHandler) PurgeTask(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	taskID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.trashService.PurgeTask(taskID, userID); err != nil {
+		respondTrashError(c, err, "Failed to delete task")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Deleted Task Permanently"})
+}
+
+func (h *TrashHandler) EmptyTrash(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	purged, err := h.trashService.EmptyTrash(userID)
+	if err != nil {
+		respondTrashError(c, err, "Failed to empty trash")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Emptied Trash Successfully",
+		"deleted": purged,
+	})
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/service"
+	"github.com/lieucongduy182/go-gin-todo-api/utils"
+)
+
+type UserHandler struct {
+	userService service.UserService
+}
+
+func NewUserHandler(userService service.UserService) *UserHandler {
+	return &UserHandler{userService: userService}
+}
+
+// respondUserError maps user service errors to HTTP responses
+func respondUserError(c *gin.Context, err error, fallback string) {
+	if respondThrottled(c, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, service.ErrIncorrectPassword):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password incorrect"})
+	case errors.Is(err, service.ErrRecentLoginNeeded):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrUsernameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": "Username already taken"})
+	case errors.Is(err, utils.ErrWeakPassword):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+func (h *UserHandler) GetProfile(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	user, err := h.userService.GetProfile(userID)
+	if err != nil {
+		respondUserError(c, err, "Failed to fetch profile")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": user})
+}
+
+func (h *UserHandler) UpdateProfile(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var input models.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.userService.UpdateProfile(userID, &input)
+	if err != nil {
+		respondUserError(c, err, "Failed to update profile")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    user,
+		"message": "Updated Profile Successfully",
+	})
+}
+
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var input models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.userService.ChangePassword(userID, &input, clientInfo(c))
+	if err != nil {
+		respondUserError(c, err, "Failed to change password")
+		return
+	}
+
+	// every other session was signed out, this one continues with the new tokens
+	c.JSON(http.StatusOK, gin.H{"message": "Changed Password Successfully", "data": response})
+}
+
+func (h *UserHandler) DeleteAccount(c *gin.Context) {
+	claims := c.MustGet("claims").(*utils.Claims)
+
+	// the body is optional for accounts without a password
+	var input models.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.userService.DeleteAccount(claims.UserID, &input, claims.SessionID, clientInfo(c)); err != nil {
+		respondUserError(c, err, "Failed to delete account")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Deleted Account Successfully"})
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/service"
+)
+
+type VerificationHandler struct {
+	verificationService service.EmailVerificationService
+}
+
+func NewVerificationHandler(verificationService service.EmailVerificationService) *VerificationHandler {
+	return &VerificationHandler{verificationService: verificationService}
+}
+
+func (h *VerificationHandler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'token' is required"})
+		return
+	}
+
+	user, err := h.verificationService.Verify(token)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidVerificationToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification link"})
+			return
+		}
+
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    user,
+		"message": "Email verified successfully",
+	})
+}
+
+func (h *VerificationHandler) ResendVerification(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	if err := h.verificationService.Resend(userID); err != nil {
+		if errors.Is(err, service.ErrEmailAlreadyVerified) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email already verified"})
+			return
+		}
+
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resend verification email"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Verification email sent"})
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/service"
+)
+
+type WebAuthnHandler struct {
+	webAuthnService service.WebAuthnService
+}
+
+func NewWebAuthnHandler(webAuthnService service.WebAuthnService) *WebAuthnHandler {
+	return &WebAuthnHandler{webAuthnService: webAuthnService}
+}
+
+// respondWebAuthnError maps passkey service errors to HTTP responses
+func respondWebAuthnError(c *gin.Context, err error, fallback string) {
+	if respondThrottled(c, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, repository.ErrWebAuthnCredentialNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Passkey not found"})
+	case errors.Is(err, service.ErrInvalidWebAuthnCeremony):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPasskeyAlreadyRegistered),
+		errors.Is(err, service.ErrNoPasskeys):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidPasskey),
+		errors.Is(err, service.ErrPasskeyCloned),
+		errors.Is(err, service.ErrInvalidMFAToken):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrAccountDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account has been disabled"})
+	default:
+		c.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+func (h *WebAuthnHandler) BeginRegistration(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	ceremony, err := h.webAuthnService.BeginRegistration(userID)
+	if err != nil {
+		respondWebAuthnError(c, err, "Failed to start passkey registration")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    ceremony,
+		"message": "Pass the options to navigator.credentials.create() and send back the result",
+	})
+}
+
+func (h *WebAuthnHandler) FinishRegistration(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	var input models.WebAuthnRegistrationFinishRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	registration, err := h.webAuthnService.FinishRegistration(userID, &input)
+	if err != nil {
+		// a rejected attestation is a bad request here, not a failed login
+		if errors.Is(err, service.ErrInvalidPasskey) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		respondWebAuthnError(c, err, "Failed to register passkey")
+		return
+	}
+
+	message := "Passkey registered"
+	if len(registration.RecoveryCodes) > 0 {
+		message = "Passkey registered and two-factor login enabled, store your recovery codes somewhere safe"
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":    registration,
+		"message": message,
+	})
+}
+
+func (h *WebAuthnHandler) ListCredentials(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	passkeys, err := h.webAuthnService.ListCredentials(userID)
+	if err != nil {
+		respondWebAuthnError(c, err, "Failed to fetch passkeys")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  passkeys,
+		"count": len(passkeys),
+	})
+}
+
+func (h *WebAuthnHandler) DeleteCredential(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.webAuthnService.DeleteCredential(userID, id); err != nil {
+		respondWebAuthnError(c, err, "Failed to delete passkey")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Deleted Passkey Successfully"})
+}
+
+func (h *WebAuthnHandler) BeginLogin(c *gin.Context) {
+	var input models.WebAuthnLoginBeginRequest
+
+	// the body is optional: without an email any discoverable passkey may answer
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ceremony, err := h.webAuthnService.BeginLogin(&input)
+	if err != nil {
+		respondWebAuthnError(c, err, "Failed to start passkey login")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    ceremony,
+		"message": "Pass the options to navigator.credentials.get() and send back the result",
+	})
+}
+
+func (h *WebAuthnHandler) FinishLogin(c *gin.Context) {
+	var input models.WebAuthnLoginFinishRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.webAuthnService.FinishLogin(&input, clientInfo(c))
+	if err != nil {
+		respondWebAuthnError(c, err, "Failed to login")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Login successfully",
+		"data":    response,
+	})
+}
+
+func (h *WebAuthnHandler) BeginSecondFactor(c *gin.Context) {
+	var input models.WebAuthnMFABeginRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ceremony, err := h.webAuthnService.BeginSecondFactor(&input)
+	if err != nil {
+		respondWebAuthnError(c, err, "Failed to start passkey verification")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    ceremony,
+		"message": "Pass the options to navigator.credentials.get() and send back the result",
+	})
+}
+
+func (h *WebAuthnHandler) FinishSecondFactor(c *gin.Context) {
+	var input models.WebAuthnMFAFinishRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.webAuthnService.FinishSecondFactor(&input, clientInfo(c))
+	if err != nil {
+		respondWebAuthnError(c, err, "Failed to login")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Login successfully",
+		"data":    response,
+	})
+}
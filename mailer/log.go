@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// LogMailer is meant for local development: instead of delivering emails it appends
+// them to a file, or writes them to the server log when no file is configured.
+type LogMailer struct {
+	mu   sync.Mutex
+	path string
+	from string
+}
+
+func NewLogMailer(path, from string) *LogMailer {
+	return &LogMailer{path: path, from: from}
+}
+
+// Send implements Mailer.
+func (l *LogMailer) Send(msg *Message) error {
+	entry := fmt.Sprintf("=== %s\nFrom: %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC3339), l.from, msg.To, msg.Subject, msg.Body)
+
+	if l.path == "" {
+		log.Print("📧 Outgoing email\n" + entry)
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(entry)
+	return err
+}
@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"log"
+
+	"github.com/lieucongduy182/go-gin-todo-api/config"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers plain text emails
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+// New returns the Mailer selected by MAIL_DRIVER
+func New(cfg *config.Config) Mailer {
+	switch cfg.MailDriver {
+	case "smtp":
+		return NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	case "log", "":
+		return NewLogMailer(cfg.MailLogFile, cfg.MailFrom)
+	default:
+		log.Printf("Unknown mail driver %q, falling back to log mailer", cfg.MailDriver)
+		return NewLogMailer(cfg.MailLogFile, cfg.MailFrom)
+	}
+}
+
+// SendAsync delivers msg in the background and logs failures.
+// Callers use it when the response must not depend on delivery, e.g. to avoid leaking
+// whether an email address is registered through response timing.
+func SendAsync(m Mailer, msg *Message) {
+	go func() {
+		if err := m.Send(msg); err != nil {
+			log.Printf("Failed to send email to %s: %v", msg.To, err)
+		}
+	}()
+}
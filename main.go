@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -10,24 +15,44 @@ import (
 	"github.com/lieucongduy182/go-gin-todo-api/database"
 	"github.com/lieucongduy182/go-gin-todo-api/middleware"
 	"github.com/lieucongduy182/go-gin-todo-api/routes"
+	"github.com/lieucongduy182/go-gin-todo-api/utils"
 )
 
+// shutdownTimeout bounds how long in-flight requests may take to finish on shutdown
+const shutdownTimeout = 15 * time.Second
+
 func main() {
 	// Load configuration
 	config.LoadConfig()
 
+	// Load JWT signing keys
+	if err := utils.LoadSigningKeys(config.AppConfig.JWTKeys); err != nil {
+		log.Fatal("Failed to load JWT signing keys: ", err)
+	}
+
+	// Load the list of passwords too common to allow
+	if err := utils.LoadCommonPasswords(config.AppConfig.CommonPasswordsFile); err != nil {
+		log.Fatal("Failed to load common passwords: ", err)
+	}
+
 	// Connect to database
 	database.Connect()
 
 	// Initialize Gin router
 	router := gin.Default()
 
+	// c.ClientIP() feeds login throttling, so forwarded headers are only honoured from known proxies
+	if err := router.SetTrustedProxies(config.AppConfig.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES: ", err)
+	}
+
 	// CORS middleware
 	router.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, If-Match, If-None-Match")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "ETag")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -41,14 +66,46 @@ func main() {
 	router.GET("/health", healthCheck)
 
 	// setup routes
-	routes.SetupRoutes(router)
+	jobs := routes.SetupRoutes(router)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// background jobs stop once the server has drained
+	jobCtx, cancelJobs := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job.Run(jobCtx)
+		}()
+	}
+
+	server := &http.Server{
+		Addr:    ":" + config.AppConfig.ServerPort,
+		Handler: router,
+	}
+
+	go func() {
+		log.Printf("Server starting on port %s", config.AppConfig.ServerPort)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start server: ", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Print("Shutting down server...")
 
-	serverAddr := ":" + config.AppConfig.ServerPort
-	log.Printf("Server starting on port %s", config.AppConfig.ServerPort)
-	if err := router.Run(serverAddr); err != nil {
-		log.Fatal("Failed to start server: ", err)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Print("Server forced to shut down: ", err)
 	}
 
+	cancelJobs()
+	wg.Wait()
+	log.Print("Server stopped")
 }
 
 func healthCheck(ctx *gin.Context) {
@@ -1,42 +1,143 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/lieucongduy182/go-gin-todo-api/utils"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/service"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware accepts JWT access tokens only.
+// Personal access tokens are rejected here; routes that allow them use ScopedAuthMiddleware.
+func AuthMiddleware(tokenService service.TokenService, sessionService service.SessionService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
+		tokenString, ok := bearerToken(c)
+		if !ok {
 			return
 		}
 
-		// check bearer token format
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
+		if strings.HasPrefix(tokenString, models.PATPrefix) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Personal access tokens cannot be used for this endpoint"})
 			c.Abort()
 			return
 		}
 
-		tokenString := parts[1]
-		claims, err := utils.ValidateToken(tokenString)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
+		if !authenticateJWT(c, tokenService, sessionService, tokenString) {
 			return
 		}
 
-		// Set user info in context
-		c.Set("userID", claims.UserID)
-		c.Set("email", claims.Email)
-
 		c.Next()
 	}
 }
+
+// ScopedAuthMiddleware returns a factory for middleware that accepts JWTs as well as
+// personal access tokens carrying the given scope. JWTs are not limited by scopes.
+func ScopedAuthMiddleware(
+	tokenService service.TokenService,
+	sessionService service.SessionService,
+	patService service.PersonalAccessTokenService,
+) func(scope string) gin.HandlerFunc {
+	return func(scope string) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			tokenString, ok := bearerToken(c)
+			if !ok {
+				return
+			}
+
+			if !strings.HasPrefix(tokenString, models.PATPrefix) {
+				if !authenticateJWT(c, tokenService, sessionService, tokenString) {
+					return
+				}
+
+				c.Next()
+				return
+			}
+
+			pat, user, err := patService.Authenticate(tokenString)
+			if err != nil {
+				if errors.Is(err, service.ErrInvalidPersonalAccessToken) {
+					c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid, expired or revoked personal access token"})
+				} else {
+					c.Error(err)
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate token"})
+				}
+				c.Abort()
+				return
+			}
+
+			scopes := pat.ScopeList()
+			if !slices.Contains(scopes, scope) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Token is missing required scope: " + scope})
+				c.Abort()
+				return
+			}
+
+			// Set user info in context
+			c.Set("userID", user.ID)
+			c.Set("email", user.Email)
+			c.Set("role", user.Role)
+			c.Set("scopes", scopes)
+
+			c.Next()
+		}
+	}
+}
+
+// bearerToken extracts the token from the Authorization header, aborting the request if it is missing
+func bearerToken(c *gin.Context) (string, bool) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+		c.Abort()
+		return "", false
+	}
+
+	// check bearer token format
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
+		c.Abort()
+		return "", false
+	}
+
+	return parts[1], true
+}
+
+// authenticateJWT validates an access token, records session activity and stores the claims in the context
+func authenticateJWT(
+	c *gin.Context,
+	tokenService service.TokenService,
+	sessionService service.SessionService,
+	tokenString string,
+) bool {
+	claims, err := tokenService.ValidateAccessToken(tokenString)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrTokenRevoked):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+		case errors.Is(err, service.ErrInvalidAccessToken):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		default:
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate token"})
+		}
+		c.Abort()
+		return false
+	}
+
+	if claims.SessionID != "" {
+		sessionService.Touch(claims.SessionID, c.ClientIP())
+	}
+
+	// Set user info in context
+	c.Set("userID", claims.UserID)
+	c.Set("email", claims.Email)
+	c.Set("role", claims.Role)
+	c.Set("claims", claims)
+
+	return true
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/service"
+	"github.com/lieucongduy182/go-gin-todo-api/utils"
+)
+
+// RequirePermission must run after AuthMiddleware; it checks the role claim against
+// the role's permission set. Impersonation tokens never pass, whatever the role.
+func RequirePermission(roleService service.RoleService, permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if rejectImpersonation(c) {
+			return
+		}
+
+		allowed, err := roleService.HasPermissions(c.GetString("role"), permissions...)
+		if err != nil {
+			c.Error(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			c.Abort()
+			return
+		}
+
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RejectImpersonation must run after AuthMiddleware; it keeps impersonation tokens away from
+// credentials and account security, so support staff can only act within the task APIs.
+func RejectImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if rejectImpersonation(c) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// rejectImpersonation aborts with 403 if the request carries an impersonation token
+func rejectImpersonation(c *gin.Context) bool {
+	claims, ok := c.Get("claims")
+	if !ok || claims.(*utils.Claims).ImpersonatorID == 0 {
+		return false
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed while impersonating a user"})
+	c.Abort()
+	return true
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Audit event names
+const (
+	AuditLoginSucceeded = "login.succeeded"
+	AuditLoginFailed    = "login.failed"
+	AuditLoginThrottled = "login.throttled"
+	AuditAccountLocked  = "account.locked"
+	AuditAccountUnlock  = "account.unlocked"
+
+	AuditUserDisabled         = "admin.user_disabled"
+	AuditUserEnabled          = "admin.user_enabled"
+	AuditRoleAssigned         = "admin.role_assigned"
+	AuditImpersonationStarted = "admin.impersonation_started"
+)
+
+// AuditEvent is an append-only record of a security relevant action
+type AuditEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Event     string    `gorm:"not null;index" json:"event"`
+	UserID    *uint     `gorm:"index" json:"user_id"`
+	ActorID   *uint     `gorm:"index" json:"actor_id"`
+	Email     string    `json:"email"`
+	IP        string    `json:"ip"`
+	Detail    string    `json:"detail"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
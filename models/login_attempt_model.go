@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// LoginAttempt counts recent failed logins for one throttle key, e.g. "email:alice@example.com" or "ip:10.0.0.1"
+type LoginAttempt struct {
+	ThrottleKey   string `gorm:"primaryKey;size:320"`
+	Failures      int    `gorm:"not null;default:0"`
+	LockedUntil   *time.Time
+	LastFailureAt time.Time `gorm:"not null;index"`
+}
+
+// IsLocked reports whether attempts for this key are currently refused
+func (a *LoginAttempt) IsLocked(now time.Time) bool {
+	return a.LockedUntil != nil && a.LockedUntil.After(now)
+}
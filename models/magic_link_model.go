@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// MagicLinkToken is a single-use passwordless login link. Only its hash is stored.
+type MagicLinkToken struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	TokenHash   string     `gorm:"not null;uniqueIndex" json:"-"`
+	RequestedIP string     `gorm:"index" json:"requested_ip"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt      *time.Time `json:"used_at"`
+	CreatedAt   time.Time  `gorm:"index" json:"created_at"`
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// MagicLinkLoginRequest exchanges a link for tokens, as JSON or from the confirmation form
+type MagicLinkLoginRequest struct {
+	Token string `json:"token" form:"token" binding:"required"`
+}
+
+// IsUsable reports whether the link can still be consumed
+func (m *MagicLinkToken) IsUsable(now time.Time) bool {
+	return m.UsedAt == nil && now.Before(m.ExpiresAt)
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// PATPrefix marks personal access tokens so they can be told apart from JWTs at a glance
+const PATPrefix = "tdp_"
+
+const (
+	ScopeTasksRead   = "tasks:read"
+	ScopeTasksWrite  = "tasks:write"
+	ScopeProfileRead = "profile:read"
+)
+
+// PersonalAccessToken is a long-lived, scoped token for scripts and integrations.
+// Only the hash of the token is stored; TokenPrefix is kept to help users recognise it.
+type PersonalAccessToken struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	Name        string     `gorm:"not null" json:"name"`
+	TokenHash   string     `gorm:"not null;uniqueIndex" json:"-"`
+	TokenPrefix string     `gorm:"not null" json:"token_prefix"`
+	Scopes      string     `gorm:"not null" json:"-"` // space separated
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type CreatePersonalAccessTokenRequest struct {
+	Name          string   `json:"name" binding:"required,min=1,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1,dive,oneof=tasks:read tasks:write profile:read"`
+	ExpiresInDays *int     `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+}
+
+type PersonalAccessTokenResponse struct {
+	ID          uint       `json:"id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"`
+	Scopes      []string   `json:"scopes"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// CreatedPersonalAccessTokenResponse carries the raw token, which is shown only once
+type CreatedPersonalAccessTokenResponse struct {
+	Token string                      `json:"token"`
+	PAT   PersonalAccessTokenResponse `json:"personal_access_token"`
+}
+
+func (p *PersonalAccessToken) ScopeList() []string {
+	return strings.Fields(p.Scopes)
+}
+
+func (p *PersonalAccessToken) IsActive(now time.Time) bool {
+	return p.RevokedAt == nil && (p.ExpiresAt == nil || now.Before(*p.ExpiresAt))
+}
+
+func (p *PersonalAccessToken) ToResponse() PersonalAccessTokenResponse {
+	return PersonalAccessTokenResponse{
+		ID:          p.ID,
+		Name:        p.Name,
+		TokenPrefix: p.TokenPrefix,
+		Scopes:      p.ScopeList(),
+		ExpiresAt:   p.ExpiresAt,
+		LastUsedAt:  p.LastUsedAt,
+		CreatedAt:   p.CreatedAt,
+	}
+}
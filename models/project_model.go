@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+// DefaultProjectColor is used when a project is created without a color
+const DefaultProjectColor = "#607d8b"
+
+// What happens to the tasks of a deleted project
+const (
+	ProjectDeleteMoveToInbox = "inbox"
+	ProjectDeleteCascade     = "cascade"
+)
+
+// Project groups a user's tasks. Tasks without a project are in the user's inbox.
+type Project struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	Name      string    `gorm:"not null" json:"name"`
+	Color     string    `gorm:"not null;default:'#607d8b'" json:"color"`
+	Archived  bool      `gorm:"not null;default:false" json:"archived"`
+	SortOrder int       `gorm:"not null;default:0" json:"sort_order"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ProjectWithCounts is a project together with the number of its live tasks
+type ProjectWithCounts struct {
+	Project
+	TaskCount      int64 `json:"task_count"`
+	CompletedCount int64 `json:"completed_count"`
+}
+
+type CreateProjectRequest struct {
+	Name      string `json:"name" binding:"required,min=1,max=100"`
+	Color     string `json:"color" binding:"omitempty,hexcolor"`
+	SortOrder *int   `json:"sort_order"`
+}
+
+type UpdateProjectRequest struct {
+	Name      string `json:"name" binding:"omitempty,min=1,max=100"`
+	Color     string `json:"color" binding:"omitempty,hexcolor"`
+	Archived  *bool  `json:"archived"`
+	SortOrder *int   `json:"sort_order"`
+}
+
+// MoveTaskRequest moves a task to another project; a null project_id moves it to the inbox
+type MoveTaskRequest struct {
+	ProjectID *uint `json:"project_id"`
+	Version   *uint `json:"-"` // from If-Match: only move this version
+}
+
+type ProjectResponse struct {
+	ID             uint      `json:"id"`
+	Name           string    `json:"name"`
+	Color          string    `json:"color"`
+	Archived       bool      `json:"archived"`
+	SortOrder      int       `json:"sort_order"`
+	TaskCount      *int64    `json:"task_count,omitempty"`
+	CompletedCount *int64    `json:"completed_count,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (p *Project) ToResponse() ProjectResponse {
+	return ProjectResponse{
+		ID:        p.ID,
+		Name:      p.Name,
+		Color:     p.Color,
+		Archived:  p.Archived,
+		SortOrder: p.SortOrder,
+		CreatedAt: p.CreatedAt,
+		UpdatedAt: p.UpdatedAt,
+	}
+}
+
+func (p *ProjectWithCounts) ToResponse() ProjectResponse {
+	response := p.Project.ToResponse()
+	response.TaskCount = &p.TaskCount
+	response.CompletedCount = &p.CompletedCount
+	return response
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// RefreshToken is a server-side record of an opaque refresh token.
+// Tokens issued from the same login share a FamilyID so that the whole chain
+// can be revoked when a rotated token is presented again.
+type RefreshToken struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	UserID       uint       `gorm:"not null;index" json:"user_id"`
+	TokenHash    string     `gorm:"not null;uniqueIndex" json:"-"`
+	FamilyID     string     `gorm:"not null;index" json:"family_id"`
+	ExpiresAt    time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt       *time.Time `json:"used_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	ReplacedByID *uint      `json:"replaced_by_id"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// IsActive reports whether the token can still be exchanged
+func (r *RefreshToken) IsActive(now time.Time) bool {
+	return r.UsedAt == nil && r.RevokedAt == nil && now.Before(r.ExpiresAt)
+}
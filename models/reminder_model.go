@@ -0,0 +1,98 @@
+package models
+
+import "time"
+
+// Reminder delivery channels
+const (
+	ReminderChannelEmail   = "email"
+	ReminderChannelWebhook = "webhook"
+	ReminderChannelInbox   = "inbox"
+)
+
+// Reminder states. A sending reminder is claimed by a scheduler until FireAt,
+// after which another replica may pick it up again.
+const (
+	ReminderStatusPending   = "pending"
+	ReminderStatusSending   = "sending"
+	ReminderStatusSent      = "sent"
+	ReminderStatusFailed    = "failed"
+	ReminderStatusCancelled = "cancelled"
+)
+
+// Reminder fires once, either at RemindAt or MinutesBefore the task's due date
+type Reminder struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	UserID        uint       `gorm:"not null;index" json:"user_id"`
+	TaskID        uint       `gorm:"not null;index" json:"task_id"`
+	Channel       string     `gorm:"not null" json:"channel"`
+	WebhookURL    string     `json:"-"`
+	RemindAt      *time.Time `json:"remind_at"`
+	MinutesBefore *int       `json:"minutes_before"`
+	FireAt        time.Time  `gorm:"not null;index:idx_reminders_due,priority:2" json:"fire_at"` // next attempt, or lease expiry while sending
+	Status        string     `gorm:"not null;default:'pending';index:idx_reminders_due,priority:1" json:"status"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	LastError     string     `json:"last_error"`
+	SentAt        *time.Time `json:"sent_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// ReminderDelivery records the outcome of one delivery attempt
+type ReminderDelivery struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ReminderID uint      `gorm:"not null;index" json:"reminder_id"`
+	Attempt    int       `gorm:"not null" json:"attempt"`
+	Channel    string    `gorm:"not null" json:"channel"`
+	Success    bool      `gorm:"not null" json:"success"`
+	Error      string    `json:"error"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Notification is an entry in a user's in-app inbox
+type Notification struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	TaskID    *uint      `json:"task_id"`
+	Title     string     `gorm:"not null" json:"title"`
+	Body      string     `json:"body"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// CreateReminderRequest needs exactly one of remind_at and minutes_before
+type CreateReminderRequest struct {
+	Channel       string     `json:"channel" binding:"required,oneof=email webhook inbox"`
+	RemindAt      *time.Time `json:"remind_at"`
+	MinutesBefore *int       `json:"minutes_before" binding:"omitempty,min=0,max=525600"`
+	WebhookURL    string     `json:"webhook_url" binding:"required_if=Channel webhook,omitempty,url,max=2048"`
+}
+
+type ReminderResponse struct {
+	ID            uint       `json:"id"`
+	TaskID        uint       `json:"task_id"`
+	Channel       string     `json:"channel"`
+	RemindAt      *time.Time `json:"remind_at"`
+	MinutesBefore *int       `json:"minutes_before"`
+	FireAt        time.Time  `json:"fire_at"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	SentAt        *time.Time `json:"sent_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func (r *Reminder) ToResponse() ReminderResponse {
+	return ReminderResponse{
+		ID:            r.ID,
+		TaskID:        r.TaskID,
+		Channel:       r.Channel,
+		RemindAt:      r.RemindAt,
+		MinutesBefore: r.MinutesBefore,
+		FireAt:        r.FireAt,
+		Status:        r.Status,
+		Attempts:      r.Attempts,
+		LastError:     r.LastError,
+		SentAt:        r.SentAt,
+		CreatedAt:     r.CreatedAt,
+	}
+}
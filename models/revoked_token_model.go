@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// RevokedToken records an access token (by its jti claim) that must no longer be accepted.
+// Rows can be dropped once ExpiresAt has passed since the token is rejected anyway.
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey" json:"jti"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Built-in roles. Their permissions are defined in code and cannot be edited.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+const (
+	PermissionUsersRead        = "users:read"
+	PermissionUsersManage      = "users:manage"
+	PermissionUsersImpersonate = "users:impersonate"
+	PermissionRolesManage      = "roles:manage"
+)
+
+// AllPermissions lists every permission understood by the API
+var AllPermissions = []string{
+	PermissionUsersRead,
+	PermissionUsersManage,
+	PermissionUsersImpersonate,
+	PermissionRolesManage,
+}
+
+// BuiltinRolePermissions maps the built-in roles to their fixed permission sets
+var BuiltinRolePermissions = map[string][]string{
+	RoleUser:  {},
+	RoleAdmin: AllPermissions,
+}
+
+// Role is a custom role with a configurable permission set
+type Role struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"not null;uniqueIndex" json:"name"`
+	Description string    `json:"description"`
+	Permissions string    `gorm:"not null;default:''" json:"-"` // space separated
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type CreateRoleRequest struct {
+	Name        string   `json:"name" binding:"required,min=2,max=50,lowercase,alphanum"`
+	Description string   `json:"description" binding:"max=255"`
+	Permissions []string `json:"permissions" binding:"dive,oneof=users:read users:manage users:impersonate roles:manage"`
+}
+
+type UpdateRoleRequest struct {
+	Description *string  `json:"description" binding:"omitempty,max=255"`
+	Permissions []string `json:"permissions" binding:"omitempty,dive,oneof=users:read users:manage users:impersonate roles:manage"`
+}
+
+type AssignRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type RoleResponse struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Permissions []string  `json:"permissions"`
+	Builtin     bool      `json:"builtin"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at,omitempty"`
+}
+
+func (r *Role) PermissionList() []string {
+	return strings.Fields(r.Permissions)
+}
+
+func (r *Role) SetPermissions(permissions []string) {
+	unique := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		if !slices.Contains(unique, permission) {
+			unique = append(unique, permission)
+		}
+	}
+
+	r.Permissions = strings.Join(unique, " ")
+}
+
+func (r *Role) ToResponse() RoleResponse {
+	return RoleResponse{
+		Name:        r.Name,
+		Description: r.Description,
+		Permissions: r.PermissionList(),
+		CreatedAt:   r.CreatedAt,
+		UpdatedAt:   r.UpdatedAt,
+	}
+}
+
+func IsBuiltinRole(name string) bool {
+	_, ok := BuiltinRolePermissions[name]
+	return ok
+}
+
+// ImpersonationResponse carries a short-lived access token for acting as another user.
+// No refresh token is issued, so impersonation ends when the token expires.
+type ImpersonationResponse struct {
+	AccessToken    string       `json:"access_token"`
+	User           UserResponse `json:"user"`
+	ImpersonatorID uint         `json:"impersonator_id"`
+	ExpiresIn      int          `json:"expires_in"`
+	TokenType      string       `json:"token_type"`
+}
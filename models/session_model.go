@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Session is one signed-in device. Its ID is the refresh token family ID,
+// so ending a session also ends its refresh token chain.
+type Session struct {
+	ID         string     `gorm:"primaryKey;size:64" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	DeviceName string     `json:"device_name"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	LastSeenAt time.Time  `gorm:"not null" json:"last_seen_at"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ClientInfo describes the device a login or refresh comes from
+type ClientInfo struct {
+	IP         string
+	UserAgent  string
+	DeviceName string
+}
+
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	DeviceName string    `json:"device_name"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	Current    bool      `json:"current"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// IsActive reports whether the session can still be used
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+func (s *Session) ToResponse(currentID string) SessionResponse {
+	return SessionResponse{
+		ID:         s.ID,
+		DeviceName: s.DeviceName,
+		UserAgent:  s.UserAgent,
+		IP:         s.IP,
+		Current:    s.ID == currentID,
+		LastSeenAt: s.LastSeenAt,
+		ExpiresAt:  s.ExpiresAt,
+		CreatedAt:  s.CreatedAt,
+	}
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// DefaultTagColor is used when a tag is created without a color, e.g. implicitly through a task
+const DefaultTagColor = "#9e9e9e"
+
+// Tag is a per-user label that can be attached to any number of tasks.
+// Names are stored lower case and are unique per user.
+type Tag struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_tags_user_name" json:"user_id"`
+	Name      string    `gorm:"not null;uniqueIndex:idx_tags_user_name" json:"name"`
+	Color     string    `gorm:"not null;default:'#9e9e9e'" json:"color"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TagWithCount is a tag together with the number of live tasks carrying it
+type TagWithCount struct {
+	Tag
+	TaskCount int64 `json:"task_count"`
+}
+
+type CreateTagRequest struct {
+	Name  string `json:"name" binding:"required,min=1,max=50,excludesall=0x2C"`
+	Color string `json:"color" binding:"omitempty,hexcolor"`
+}
+
+type UpdateTagRequest struct {
+	Name  string `json:"name" binding:"omitempty,min=1,max=50,excludesall=0x2C"`
+	Color string `json:"color" binding:"omitempty,hexcolor"`
+}
+
+type TagResponse struct {
+	ID        uint   `json:"id"`
+	Name      string `json:"name"`
+	Color     string `json:"color"`
+	TaskCount *int64 `json:"task_count,omitempty"`
+}
+
+func (t *Tag) ToResponse() TagResponse {
+	return TagResponse{
+		ID:    t.ID,
+		Name:  t.Name,
+		Color: t.Color,
+	}
+}
+
+func (t *TagWithCount) ToResponse() TagResponse {
+	response := t.Tag.ToResponse()
+	response.TaskCount = &t.TaskCount
+	return response
+}
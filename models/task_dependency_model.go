@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// TaskDependency records that TaskID cannot be completed before BlockedByID is.
+// Both tasks always belong to the same user.
+type TaskDependency struct {
+	TaskID      uint      `gorm:"primaryKey;autoIncrement:false" json:"task_id"`
+	BlockedByID uint      `gorm:"primaryKey;autoIncrement:false;index" json:"blocked_by_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type AddDependencyRequest struct {
+	BlockedByID uint `json:"blocked_by_id" binding:"required"`
+}
+
+type TaskDependenciesResponse struct {
+	BlockedBy []TaskResponse `json:"blocked_by"` // tasks that must be completed first
+	Blocking  []TaskResponse `json:"blocking"`   // tasks waiting for this one
+}
@@ -7,45 +7,118 @@ import (
 )
 
 type Task struct {
-	ID          uint           `gorm:"primaryKey" json:"id"`
-	UserID      uint           `gorm:"not null;index" json:"user_id"`
-	Title       string         `gorm:"not null" json:"title"`
-	Description string         `json:"description"`
-	Completed   bool           `gorm:"default:false; index" json:"completed"`
-	Priority    string         `gorm:"default:'medium'" json:"priority"`
-	DueDate     *time.Time     `json:"due_date"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
-
-	User User `gorm:"foreignKey:UserID" json:"-"`
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	UserID       uint           `gorm:"not null;index" json:"user_id"`
+	ProjectID    *uint          `gorm:"index" json:"project_id"`                     // nil means the task is in the inbox
+	ParentID     *uint          `gorm:"index" json:"parent_id"`                      // set for subtasks
+	AutoComplete bool           `gorm:"not null;default:false" json:"auto_complete"` // complete the task once all its subtasks are done
+	Title        string         `gorm:"not null" json:"title"`
+	Description  string         `json:"description"`
+	Completed    bool           `gorm:"default:false; index" json:"completed"`
+	Priority     string         `gorm:"default:'medium'" json:"priority"`
+	DueDate      *time.Time     `json:"due_date"`
+	Recurrence   string         `gorm:"not null;default:''" json:"recurrence"` // RRULE, empty for one-off tasks
+	RecurrenceTZ string         `gorm:"not null;default:''" json:"recurrence_tz"`
+	SeriesStart  *time.Time     `json:"series_start"`                      // DTSTART of the rule: the due date of the first occurrence
+	Version      uint           `gorm:"not null;default:1" json:"version"` // bumped by every update, sent as the ETag
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+
+	User User  `gorm:"foreignKey:UserID" json:"-"`
+	Tags []Tag `gorm:"many2many:task_tags" json:"tags"`
+}
+
+// Tag filter modes for task listings
+const (
+	TagModeAny = "any"
+	TagModeAll = "all"
+)
+
+// TaskFilter narrows down a task listing; zero values do not filter
+type TaskFilter struct {
+	Tags      []string
+	TagMode   string // TagModeAny (default) or TagModeAll
+	ProjectID *uint
+	InboxOnly bool // only tasks without a project
 }
 
 type CreateTaskRequest struct {
-	Title       string     `json:"title" binding:"required,min=1,max=255"`
-	Description string     `json:"description"`
-	Priority    string     `json:"priority"`
-	DueDate     *time.Time `json:"due_date"`
+	Title        string     `json:"title" binding:"required,min=1,max=255"`
+	Description  string     `json:"description"`
+	Priority     string     `json:"priority"`
+	DueDate      *time.Time `json:"due_date"`
+	ProjectID    *uint      `json:"project_id"` // subtasks default to their parent's project
+	ParentID     *uint      `json:"parent_id"`
+	AutoComplete bool       `json:"auto_complete"`
+	Recurrence   string     `json:"recurrence" binding:"omitempty,max=255"` // needs a due date
+	Timezone     string     `json:"timezone" binding:"omitempty,timezone"`  // recurrence wall clock, UTC by default
+	Tags         []string   `json:"tags" binding:"omitempty,max=20,dive,min=1,max=50,excludesall=0x2C"`
 }
 
 type UpdateTaskRequest struct {
-	Title       string     `json:"title" binding:"omitempty,min=1,max=255"`
-	Description string     `json:"description" binding:"omitempty,max=1000"`
-	Completed   *bool      `json:"completed" binding:"omitempty"`
-	Priority    string     `json:"priority" binding:"omitempty,oneof=low medium high"`
-	DueDate     *time.Time `json:"due_date"`
+	Title        string     `json:"title" binding:"omitempty,min=1,max=255"`
+	Description  string     `json:"description" binding:"omitempty,max=1000"`
+	Completed    *bool      `json:"completed" binding:"omitempty"`
+	Priority     string     `json:"priority" binding:"omitempty,oneof=low medium high"`
+	DueDate      *time.Time `json:"due_date"`
+	AutoComplete *bool      `json:"auto_complete"`
+	Recurrence   *string    `json:"recurrence" binding:"omitempty,max=255"` // restarts the series at the due date, "" ends it
+	Timezone     string     `json:"timezone" binding:"omitempty,timezone"`
+	Tags         *[]string  `json:"tags" binding:"omitempty,max=20,dive,min=1,max=50,excludesall=0x2C"` // replaces all tags, [] clears them
+	Version      *uint      `json:"-"`                                                                  // from If-Match: only update this version
 }
 
 type TaskResponse struct {
-	ID          uint       `json:"id"`
-	UserID      uint       `json:"user_id"`
-	Title       string     `json:"title"`
-	Description string     `json:"description"`
-	Completed   bool       `json:"completed"`
-	Priority    string     `json:"priority"`
-	DueDate     *time.Time `json:"due_date"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	ID           uint             `json:"id"`
+	UserID       uint             `json:"user_id"`
+	ProjectID    *uint            `json:"project_id"`
+	ParentID     *uint            `json:"parent_id"`
+	AutoComplete bool             `json:"auto_complete"`
+	Title        string           `json:"title"`
+	Description  string           `json:"description"`
+	Completed    bool             `json:"completed"`
+	Priority     string           `json:"priority"`
+	DueDate      *time.Time       `json:"due_date"`
+	Recurrence   string           `json:"recurrence,omitempty"`
+	RecurrenceTZ string           `json:"recurrence_tz,omitempty"`
+	SeriesStart  *time.Time       `json:"series_start,omitempty"`
+	Version      uint             `json:"version"`
+	Tags         []TagResponse    `json:"tags"`
+	Progress     *SubtaskProgress `json:"progress,omitempty"` // nil for tasks without subtasks
+	Blocked      bool             `json:"blocked"`            // an open task still blocks this one
+	Next         *TaskResponse    `json:"next,omitempty"`     // the occurrence created by completing a recurring task
+	CreatedAt    time.Time        `json:"created_at"`
+	UpdatedAt    time.Time        `json:"updated_at"`
+}
+
+// SubtaskProgress rolls up the completion of a task's direct subtasks
+type SubtaskProgress struct {
+	Total     int64 `json:"total"`
+	Completed int64 `json:"completed"`
+	Percent   int   `json:"percent"`
+}
+
+func NewSubtaskProgress(total, completed int64) *SubtaskProgress {
+	progress := &SubtaskProgress{Total: total, Completed: completed}
+	if total > 0 {
+		progress.Percent = int(completed * 100 / total)
+	}
+	return progress
+}
+
+// TaskTreeNode is a task with its subtasks nested below it
+type TaskTreeNode struct {
+	TaskResponse
+	Depth    int             `json:"depth"` // 0 for the task the tree was requested for
+	Subtasks []*TaskTreeNode `json:"subtasks"`
+}
+
+// TrashedTaskResponse is a task in the trash
+type TrashedTaskResponse struct {
+	TaskResponse
+	DeletedAt time.Time  `json:"deleted_at"`
+	PurgeAt   *time.Time `json:"purge_at"` // nil when trashed tasks are kept until purged by hand
 }
 
 type PaginationResponse struct {
@@ -57,15 +130,28 @@ type PaginationResponse struct {
 }
 
 func (t *Task) ToResponse() TaskResponse {
+	tags := make([]TagResponse, 0, len(t.Tags))
+	for _, tag := range t.Tags {
+		tags = append(tags, tag.ToResponse())
+	}
+
 	return TaskResponse{
-		ID:          t.ID,
-		UserID:      t.UserID,
-		Title:       t.Title,
-		Description: t.Description,
-		Completed:   t.Completed,
-		Priority:    t.Priority,
-		DueDate:     t.DueDate,
-		CreatedAt:   t.CreatedAt,
-		UpdatedAt:   t.UpdatedAt,
+		ID:           t.ID,
+		UserID:       t.UserID,
+		ProjectID:    t.ProjectID,
+		ParentID:     t.ParentID,
+		AutoComplete: t.AutoComplete,
+		Title:        t.Title,
+		Description:  t.Description,
+		Completed:    t.Completed,
+		Priority:     t.Priority,
+		DueDate:      t.DueDate,
+		Recurrence:   t.Recurrence,
+		RecurrenceTZ: t.RecurrenceTZ,
+		SeriesStart:  t.SeriesStart,
+		Version:      t.Version,
+		Tags:         tags,
+		CreatedAt:    t.CreatedAt,
+		UpdatedAt:    t.UpdatedAt,
 	}
 }
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Where a task change came from
+const (
+	RevisionSourceAPI        = "api"
+	RevisionSourceAutomation = "automation" // auto-complete and recurring tasks
+)
+
+// Revision actions
+const (
+	RevisionActionCreate = "create"
+	RevisionActionUpdate = "update"
+	RevisionActionRevert = "revert"
+)
+
+// FieldChange holds the JSON encoded old and new value of one task field
+type FieldChange struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from"`
+	To    json.RawMessage `json:"to"`
+}
+
+// TaskRevision is an append-only record of one change to a task
+type TaskRevision struct {
+	ID         uint          `gorm:"primaryKey" json:"id"`
+	TaskID     uint          `gorm:"not null;index" json:"task_id"`
+	UserID     uint          `gorm:"not null;index" json:"-"` // owner of the task
+	ActorID    *uint         `json:"actor_id"`                // nil for automation
+	Source     string        `gorm:"not null" json:"source"`
+	Action     string        `gorm:"not null" json:"action"`
+	RevertedTo *uint         `json:"reverted_to,omitempty"` // the revision a revert restored
+	Changes    []FieldChange `gorm:"type:jsonb;serializer:json;not null" json:"changes"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
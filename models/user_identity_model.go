@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// UserIdentity links an account at an external OpenID Connect provider to a local user
+type UserIdentity struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	Provider    string     `gorm:"not null;uniqueIndex:idx_identity_provider_subject" json:"provider"`
+	Subject     string     `gorm:"not null;uniqueIndex:idx_identity_provider_subject" json:"-"`
+	Email       string     `json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
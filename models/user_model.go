@@ -1,20 +1,29 @@
 package models
 
 import (
+	"strconv"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"github.com/lieucongduy182/go-gin-todo-api/utils"
 	"gorm.io/gorm"
 )
 
 type User struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	Username  string         `gorm:"unique;not null" json:"username"`
-	Email     string         `gorm:"unique;not null" json:"email"`
-	Password  string         `gorm:"not null" json:"-"` // "-" means don't include in JSON
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID               uint           `gorm:"primaryKey" json:"id"`
+	Username         string         `gorm:"unique;not null" json:"username"`
+	Email            string         `gorm:"unique;not null" json:"email"`
+	Password         string         `gorm:"not null" json:"-"` // "-" means don't include in JSON
+	Role             string         `gorm:"not null;default:'user';index" json:"role"`
+	DisabledAt       *time.Time     `json:"disabled_at"`
+	EmailVerifiedAt  *time.Time     `json:"email_verified_at"`
+	TOTPSecret       string         `json:"-"` // sealed with utils.EncryptString under TOTPSecretContext
+	TOTPEnabledAt    *time.Time     `json:"-"`
+	TOTPLastStep     int64          `gorm:"default:0" json:"-"`   // last accepted time step, prevents code replay
+	TokensValidAfter *time.Time     `json:"-"`                    // access tokens issued at or before this are rejected
+	WebAuthnHandle   []byte         `gorm:"uniqueIndex" json:"-"` // random user handle given to authenticators, set on first passkey
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
 
 	Tasks []Task `gorm:"foreignKey:UserID" json:"tasks,omitempty"`
 }
@@ -22,7 +31,7 @@ type User struct {
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=50"`
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required,max=128"`
 }
 
 type LoginRequest struct {
@@ -30,46 +39,98 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type UpdateProfileRequest struct {
+	Username string `json:"username" binding:"required,min=3,max=50"`
+}
+
 type ChangePasswordRequest struct {
 	OldPassword string `json:"old_password" binding:"required"`
-	NewPassword string `json:"new_password" binding:"required,min=6,max=50"`
+	NewPassword string `json:"new_password" binding:"required,max=128"`
+}
+
+// DeleteAccountRequest confirms the deletion; accounts without a password leave it empty
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
 }
 
 type UserResponse struct {
-	ID        uint      `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              uint       `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email"`
+	Role            string     `json:"role"`
+	Disabled        bool       `json:"disabled"`
+	DisabledAt      *time.Time `json:"disabled_at"`
+	EmailVerified   bool       `json:"email_verified"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	TOTPEnabled     bool       `json:"totp_enabled"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
 type AuthResponse struct {
-	Token     string       `json:"token"`
-	User      UserResponse `json:"user"`
-	ExpiresIn int          `json:"expires_in"`
-	TokenType string       `json:"token_type"`
+	AccessToken      string       `json:"access_token"`
+	RefreshToken     string       `json:"refresh_token"`
+	SessionID        string       `json:"session_id"`
+	User             UserResponse `json:"user"`
+	ExpiresIn        int          `json:"expires_in"`
+	RefreshExpiresIn int          `json:"refresh_expires_in"`
+	TokenType        string       `json:"token_type"`
 }
 
 func (u *User) HashPassword(password string) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := utils.HashPassword(password)
 	if err != nil {
 		return err
 	}
 
-	u.Password = string(hashedPassword)
+	u.Password = hashedPassword
 	return nil
 }
 
 func (u *User) CheckPassword(password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
+	return utils.CheckPassword(u.Password, password)
+}
+
+// PasswordNeedsRehash reports whether the stored hash predates the current hashing settings
+func (u *User) PasswordNeedsRehash() bool {
+	return utils.PasswordNeedsRehash(u.Password)
+}
+
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
+}
+
+func (u *User) IsTOTPEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
+
+// HasPassword reports whether the user can sign in with a password.
+// Accounts created through an identity provider have none.
+func (u *User) HasPassword() bool {
+	return u.Password != ""
+}
+
+// TOTPSecretContext binds the encrypted TOTP secret to this user
+func (u *User) TOTPSecretContext() string {
+	return "totp-secret:" + strconv.FormatUint(uint64(u.ID), 10)
 }
 
 func (u *User) ToResponse() UserResponse {
 	return UserResponse{
-		ID:        u.ID,
-		Username:  u.Username,
-		Email:     u.Email,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		ID:              u.ID,
+		Username:        u.Username,
+		Email:           u.Email,
+		Role:            u.Role,
+		Disabled:        u.IsDisabled(),
+		DisabledAt:      u.DisabledAt,
+		EmailVerified:   u.IsEmailVerified(),
+		EmailVerifiedAt: u.EmailVerifiedAt,
+		TOTPEnabled:     u.IsTOTPEnabled(),
+		CreatedAt:       u.CreatedAt,
+		UpdatedAt:       u.UpdatedAt,
 	}
 }
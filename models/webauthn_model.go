@@ -0,0 +1,116 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// WebAuthn ceremony kinds, stored with the challenge so a login challenge cannot finish a registration
+const (
+	WebAuthnCeremonyRegistration = "registration"
+	WebAuthnCeremonyLogin        = "login"
+	WebAuthnCeremonyMFA          = "mfa"
+)
+
+// WebAuthnCredential is a passkey registered by a user.
+// The public key verifies later assertions; the sign counter helps spot cloned authenticators.
+type WebAuthnCredential struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	UserID          uint       `gorm:"not null;index" json:"user_id"`
+	Name            string     `gorm:"not null" json:"name"`
+	CredentialID    []byte     `gorm:"not null;uniqueIndex" json:"-"`
+	PublicKey       []byte     `gorm:"not null" json:"-"`
+	AttestationType string     `json:"-"`
+	AAGUID          []byte     `json:"-"`
+	Transports      string     `json:"-"` // comma separated
+	SignCount       uint32     `gorm:"not null;default:0" json:"-"`
+	BackupEligible  bool       `gorm:"not null;default:false" json:"backup_eligible"`
+	BackupState     bool       `gorm:"not null;default:false" json:"backup_state"`
+	CloneWarning    bool       `gorm:"not null;default:false" json:"clone_warning"` // counter went backwards, the passkey is refused
+	LastUsedAt      *time.Time `json:"last_used_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// WebAuthnCeremony keeps the server side state of a ceremony between its begin and finish calls.
+// Only the hash of the ceremony token handed to the client is stored, and each one is used once.
+type WebAuthnCeremony struct {
+	TokenHash   string    `gorm:"primaryKey"`
+	Kind        string    `gorm:"not null"`
+	UserID      *uint     `gorm:"index"` // nil for discoverable logins, where the user is not known yet
+	SessionData []byte    `gorm:"not null"`
+	ExpiresAt   time.Time `gorm:"not null;index"`
+	CreatedAt   time.Time
+}
+
+type WebAuthnRegistrationFinishRequest struct {
+	CeremonyToken string          `json:"ceremony_token" binding:"required"`
+	Name          string          `json:"name" binding:"omitempty,max=100"`
+	Credential    json.RawMessage `json:"credential" binding:"required"`
+}
+
+// WebAuthnLoginBeginRequest starts a passkey login. Without an email any discoverable passkey is accepted.
+type WebAuthnLoginBeginRequest struct {
+	Email string `json:"email" binding:"omitempty,email"`
+}
+
+type WebAuthnLoginFinishRequest struct {
+	CeremonyToken string          `json:"ceremony_token" binding:"required"`
+	Credential    json.RawMessage `json:"credential" binding:"required"`
+}
+
+type WebAuthnMFABeginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+type WebAuthnMFAFinishRequest struct {
+	MFAToken      string          `json:"mfa_token" binding:"required"`
+	CeremonyToken string          `json:"ceremony_token" binding:"required"`
+	Credential    json.RawMessage `json:"credential" binding:"required"`
+}
+
+// WebAuthnCeremonyResponse carries the options for navigator.credentials.create() or .get()
+// and the token that has to be sent back with the authenticator's answer
+type WebAuthnCeremonyResponse struct {
+	CeremonyToken string `json:"ceremony_token"`
+	Options       any    `json:"options"`
+	ExpiresIn     int    `json:"expires_in"`
+}
+
+type WebAuthnCredentialResponse struct {
+	ID             uint       `json:"id"`
+	Name           string     `json:"name"`
+	Transports     []string   `json:"transports"`
+	BackupEligible bool       `json:"backup_eligible"`
+	BackupState    bool       `json:"backup_state"`
+	CloneWarning   bool       `json:"clone_warning"`
+	LastUsedAt     *time.Time `json:"last_used_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// WebAuthnRegistrationResponse includes recovery codes when the first passkey turned on two-factor login
+type WebAuthnRegistrationResponse struct {
+	Credential    WebAuthnCredentialResponse `json:"credential"`
+	RecoveryCodes []string                   `json:"recovery_codes,omitempty"`
+}
+
+func (w *WebAuthnCredential) TransportList() []string {
+	if w.Transports == "" {
+		return []string{}
+	}
+
+	return strings.Split(w.Transports, ",")
+}
+
+func (w *WebAuthnCredential) ToResponse() WebAuthnCredentialResponse {
+	return WebAuthnCredentialResponse{
+		ID:             w.ID,
+		Name:           w.Name,
+		Transports:     w.TransportList(),
+		BackupEligible: w.BackupEligible,
+		BackupState:    w.BackupState,
+		CloneWarning:   w.CloneWarning,
+		LastUsedAt:     w.LastUsedAt,
+		CreatedAt:      w.CreatedAt,
+	}
+}
@@ -0,0 +1,28 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lieucongduy182/go-gin-todo-api/mailer"
+)
+
+// EmailNotifier sends reminders by email
+type EmailNotifier struct {
+	mail    mailer.Mailer
+	baseURL string
+}
+
+func NewEmailNotifier(mail mailer.Mailer, baseURL string) *EmailNotifier {
+	return &EmailNotifier{mail: mail, baseURL: baseURL}
+}
+
+// Notify implements Notifier.
+func (e *EmailNotifier) Notify(_ context.Context, n *Notification) error {
+	return e.mail.Send(&mailer.Message{
+		To:      n.Email,
+		Subject: subject(n),
+		Body: fmt.Sprintf("Hi,\n\nthis is your reminder for \"%s\".\n\nView the task: %s/api/v1/tasks/%d\n",
+			n.TaskTitle, e.baseURL, n.TaskID),
+	})
+}
@@ -0,0 +1,31 @@
+package notifier
+
+import (
+	"context"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+)
+
+// InboxStore persists in-app notifications
+type InboxStore interface {
+	Create(notification *models.Notification) error
+}
+
+// InboxNotifier puts reminders into the user's in-app inbox
+type InboxNotifier struct {
+	store InboxStore
+}
+
+func NewInboxNotifier(store InboxStore) *InboxNotifier {
+	return &InboxNotifier{store: store}
+}
+
+// Notify implements Notifier.
+func (i *InboxNotifier) Notify(_ context.Context, n *Notification) error {
+	taskID := n.TaskID
+	return i.store.Create(&models.Notification{
+		UserID: n.UserID,
+		TaskID: &taskID,
+		Title:  subject(n),
+	})
+}
@@ -0,0 +1,32 @@
+package notifier
+
+import (
+	"context"
+	"time"
+)
+
+// Notification is what a reminder tells the user
+type Notification struct {
+	ReminderID uint
+	UserID     uint
+	Email      string
+	TaskID     uint
+	TaskTitle  string
+	DueDate    *time.Time
+	WebhookURL string
+}
+
+// Notifier delivers notifications over one channel.
+// An error means the delivery should be retried later.
+type Notifier interface {
+	Notify(ctx context.Context, n *Notification) error
+}
+
+// subject is the one-line summary shared by all channels
+func subject(n *Notification) string {
+	if n.DueDate == nil {
+		return "Reminder: " + n.TaskTitle
+	}
+
+	return "Reminder: " + n.TaskTitle + " is due " + n.DueDate.UTC().Format("Mon, 02 Jan 2006 15:04 MST")
+}
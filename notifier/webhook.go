@@ -0,0 +1,117 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+var errPrivateAddress = errors.New("webhook address is not publicly routable")
+
+// WebhookNotifier POSTs a JSON payload to the URL configured on the reminder.
+// With a secret, the body is signed so receivers can verify it came from us.
+type WebhookNotifier struct {
+	client *http.Client
+	secret []byte
+}
+
+// NewWebhookNotifier returns a notifier that refuses to connect to loopback, private and
+// link-local addresses unless allowPrivate is set, so user supplied URLs cannot reach internal services.
+func NewWebhookNotifier(secret string, timeout time.Duration, allowPrivate bool) *WebhookNotifier {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		// checked on the resolved address, so DNS names pointing inside are caught too
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+				ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+
+	return &WebhookNotifier{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			// a redirect could point the request somewhere the user did not configure
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		secret: []byte(secret),
+	}
+}
+
+type webhookPayload struct {
+	Event      string      `json:"event"`
+	ReminderID uint        `json:"reminder_id"`
+	Task       webhookTask `json:"task"`
+	Message    string      `json:"message"`
+	SentAt     time.Time   `json:"sent_at"`
+}
+
+type webhookTask struct {
+	ID      uint       `json:"id"`
+	Title   string     `json:"title"`
+	DueDate *time.Time `json:"due_date"`
+}
+
+// Notify implements Notifier.
+// Any status outside 2xx counts as a failed delivery.
+func (w *WebhookNotifier) Notify(ctx context.Context, n *Notification) error {
+	body, err := json.Marshal(webhookPayload{
+		Event:      "task.reminder",
+		ReminderID: n.ReminderID,
+		Task:       webhookTask{ID: n.TaskID, Title: n.TaskTitle, DueDate: n.DueDate},
+		Message:    subject(n),
+		SentAt:     time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "todo-api-webhooks")
+
+	if len(w.secret) > 0 {
+		mac := hmac.New(sha256.New, w.secret)
+		mac.Write(body)
+		req.Header.Set("X-Webhook-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
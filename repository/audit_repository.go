@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"gorm.io/gorm"
+)
+
+type AuditRepository interface {
+	Create(event *models.AuditEvent) error
+}
+
+// auditRepository implement AuditRepository interface
+type auditRepository struct {
+	db *gorm.DB
+}
+
+// Create implements AuditRepository.
+func (a *auditRepository) Create(event *models.AuditEvent) error {
+	return a.db.Create(event).Error
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
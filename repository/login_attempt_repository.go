@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"gorm.io/gorm"
+)
+
+// LoginAttemptStore keeps failed login counters. The in-memory store suits a single
+// instance; the Postgres store shares counters between replicas.
+type LoginAttemptStore interface {
+	// Get returns the attempt record for key, or a zero record if there is none
+	Get(key string) (*models.LoginAttempt, error)
+	// RecordFailure increments the counter for key, starting over if the previous
+	// failure happened before resetBefore, and returns the updated record
+	RecordFailure(key string, now, resetBefore time.Time) (*models.LoginAttempt, error)
+	Lock(key string, until time.Time) error
+	Reset(key string) error
+	DeleteStale(before time.Time) error
+}
+
+// loginAttemptRepository is the Postgres LoginAttemptStore
+type loginAttemptRepository struct {
+	db *gorm.DB
+}
+
+// Get implements LoginAttemptStore.
+func (l *loginAttemptRepository) Get(key string) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+
+	if err := l.db.Where("throttle_key = ?", key).First(&attempt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &models.LoginAttempt{ThrottleKey: key}, nil
+		}
+		return nil, err
+	}
+
+	return &attempt, nil
+}
+
+// RecordFailure implements LoginAttemptStore.
+// The upsert is a single statement so concurrent failures are all counted.
+func (l *loginAttemptRepository) RecordFailure(key string, now, resetBefore time.Time) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+
+	err := l.db.Raw(`
+		INSERT INTO login_attempts (throttle_key, failures, last_failure_at)
+		VALUES (?, 1, ?)
+		ON CONFLICT (throttle_key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING throttle_key, failures, locked_until, last_failure_at`,
+		key, now, resetBefore,
+	).Scan(&attempt).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &attempt, nil
+}
+
+// Lock implements LoginAttemptStore.
+func (l *loginAttemptRepository) Lock(key string, until time.Time) error {
+	return l.db.Model(&models.LoginAttempt{}).
+		Where("throttle_key = ?", key).
+		Update("locked_until", until).Error
+}
+
+// Reset implements LoginAttemptStore.
+func (l *loginAttemptRepository) Reset(key string) error {
+	return l.db.Where("throttle_key = ?", key).Delete(&models.LoginAttempt{}).Error
+}
+
+// DeleteStale implements LoginAttemptStore.
+func (l *loginAttemptRepository) DeleteStale(before time.Time) error {
+	return l.db.
+		Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", before, time.Now()).
+		Delete(&models.LoginAttempt{}).Error
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) LoginAttemptStore {
+	return &loginAttemptRepository{db: db}
+}
+
+// memoryLoginAttemptStore is the in-process LoginAttemptStore
+type memoryLoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]models.LoginAttempt
+}
+
+// Get implements LoginAttemptStore.
+func (m *memoryLoginAttemptStore) Get(key string) (*models.LoginAttempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempt, ok := m.attempts[key]
+	if !ok {
+		attempt = models.LoginAttempt{ThrottleKey: key}
+	}
+
+	return &attempt, nil
+}
+
+// RecordFailure implements LoginAttemptStore.
+func (m *memoryLoginAttemptStore) RecordFailure(key string, now, resetBefore time.Time) (*models.LoginAttempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempt, ok := m.attempts[key]
+	if !ok || attempt.LastFailureAt.Before(resetBefore) {
+		attempt = models.LoginAttempt{ThrottleKey: key, LockedUntil: attempt.LockedUntil}
+	}
+
+	attempt.Failures++
+	attempt.LastFailureAt = now
+	m.attempts[key] = attempt
+
+	return &attempt, nil
+}
+
+// Lock implements LoginAttemptStore.
+func (m *memoryLoginAttemptStore) Lock(key string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if attempt, ok := m.attempts[key]; ok {
+		attempt.LockedUntil = &until
+		m.attempts[key] = attempt
+	}
+
+	return nil
+}
+
+// Reset implements LoginAttemptStore.
+func (m *memoryLoginAttemptStore) Reset(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.attempts, key)
+	return nil
+}
+
+// DeleteStale implements LoginAttemptStore.
+func (m *memoryLoginAttemptStore) DeleteStale(before time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for key, attempt := range m.attempts {
+		if attempt.LastFailureAt.Before(before) && !attempt.IsLocked(now) {
+			delete(m.attempts, key)
+		}
+	}
+
+	return nil
+}
+
+func NewMemoryLoginAttemptStore() LoginAttemptStore {
+	return &memoryLoginAttemptStore{attempts: make(map[string]models.LoginAttempt)}
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"gorm.io/gorm"
+)
+
+var ErrMagicLinkNotFound = errors.New("magic link not found")
+
+type MagicLinkRepository interface {
+	Create(token *models.MagicLinkToken) error
+	GetByHash(tokenHash string) (*models.MagicLinkToken, error)
+	Consume(id uint) error
+	InvalidateForUser(userID uint) error
+	CountForUserSince(userID uint, since time.Time) (int64, error)
+	// RecentByIP returns how many links were requested from ip since the given time and when the oldest was
+	RecentByIP(ip string, since time.Time) (int64, *time.Time, error)
+}
+
+// magicLinkRepository implement MagicLinkRepository interface
+type magicLinkRepository struct {
+	db *gorm.DB
+}
+
+// Create implements MagicLinkRepository.
+func (m *magicLinkRepository) Create(token *models.MagicLinkToken) error {
+	return m.db.Create(token).Error
+}
+
+// GetByHash implements MagicLinkRepository.
+func (m *magicLinkRepository) GetByHash(tokenHash string) (*models.MagicLinkToken, error) {
+	var token models.MagicLinkToken
+
+	if err := m.db.Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMagicLinkNotFound
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// Consume implements MagicLinkRepository.
+// Like password reset tokens, only the first caller succeeds.
+func (m *magicLinkRepository) Consume(id uint) error {
+	result := m.db.Model(&models.MagicLinkToken{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", id, time.Now()).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrMagicLinkNotFound
+	}
+
+	return nil
+}
+
+// InvalidateForUser implements MagicLinkRepository.
+func (m *magicLinkRepository) InvalidateForUser(userID uint) error {
+	return m.db.Model(&models.MagicLinkToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error
+}
+
+// CountForUserSince implements MagicLinkRepository.
+func (m *magicLinkRepository) CountForUserSince(userID uint, since time.Time) (int64, error) {
+	var count int64
+	err := m.db.Model(&models.MagicLinkToken{}).
+		Where("user_id = ? AND created_at > ?", userID, since).
+		Count(&count).Error
+
+	return count, err
+}
+
+// RecentByIP implements MagicLinkRepository.
+func (m *magicLinkRepository) RecentByIP(ip string, since time.Time) (int64, *time.Time, error) {
+	var result struct {
+		Count  int64
+		Oldest *time.Time
+	}
+
+	err := m.db.Model(&models.MagicLinkToken{}).
+		Select("COUNT(*) AS count, MIN(created_at) AS oldest").
+		Where("requested_ip = ? AND created_at > ?", ip, since).
+		Scan(&result).Error
+
+	return result.Count, result.Oldest, err
+}
+
+func NewMagicLinkRepository(db *gorm.DB) MagicLinkRepository {
+	return &magicLinkRepository{db: db}
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"gorm.io/gorm"
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+type NotificationRepository interface {
+	Create(notification *models.Notification) error
+	ListByUser(userID uint, unreadOnly bool, limit int) ([]models.Notification, error)
+	CountUnread(userID uint) (int64, error)
+	MarkRead(id, userID uint) error
+	MarkAllRead(userID uint) error
+}
+
+// notificationRepository implement NotificationRepository interface
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+// Create implements NotificationRepository.
+func (n *notificationRepository) Create(notification *models.Notification) error {
+	return n.db.Create(notification).Error
+}
+
+// ListByUser implements NotificationRepository.
+func (n *notificationRepository) ListByUser(userID uint, unreadOnly bool, limit int) ([]models.Notification, error) {
+	var notifications []models.Notification
+
+	query := n.db.Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	if err := query.Order("created_at desc").Limit(limit).Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+// CountUnread implements NotificationRepository.
+func (n *notificationRepository) CountUnread(userID uint) (int64, error) {
+	var count int64
+	if err := n.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// MarkRead implements NotificationRepository.
+// Marking an already read notification again is not an error.
+func (n *notificationRepository) MarkRead(id uint, userID uint) error {
+	var notification models.Notification
+	if err := n.db.Where("id = ? AND user_id = ?", id, userID).First(&notification).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotificationNotFound
+		}
+		return err
+	}
+
+	if notification.ReadAt != nil {
+		return nil
+	}
+
+	return n.db.Model(&notification).Update("read_at", time.Now()).Error
+}
+
+// MarkAllRead implements NotificationRepository.
+func (n *notificationRepository) MarkAllRead(userID uint) error {
+	return n.db.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now()).Error
+}
+
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepository{db: db}
+}
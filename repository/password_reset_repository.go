@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"gorm.io/gorm"
+)
+
+var ErrResetTokenNotFound = errors.New("password reset token not found")
+
+type PasswordResetRepository interface {
+	Create(token *models.PasswordResetToken) error
+	GetByHash(tokenHash string) (*models.PasswordResetToken, error)
+	Consume(id uint) error
+	InvalidateForUser(userID uint) error
+}
+
+// passwordResetRepository implement PasswordResetRepository interface
+type passwordResetRepository struct {
+	db *gorm.DB
+}
+
+// Create implements PasswordResetRepository.
+func (p *passwordResetRepository) Create(token *models.PasswordResetToken) error {
+	return p.db.Create(token).Error
+}
+
+// GetByHash implements PasswordResetRepository.
+func (p *passwordResetRepository) GetByHash(tokenHash string) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+
+	if err := p.db.Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrResetTokenNotFound
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// Consume implements PasswordResetRepository.
+// It only succeeds for the first caller, which makes the token single-use even under concurrency.
+func (p *passwordResetRepository) Consume(id uint) error {
+	result := p.db.Model(&models.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", id, time.Now()).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrResetTokenNotFound
+	}
+
+	return nil
+}
+
+// InvalidateForUser implements PasswordResetRepository.
+func (p *passwordResetRepository) InvalidateForUser(userID uint) error {
+	return p.db.Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error
+}
+
+func NewPasswordResetRepository(db *gorm.DB) PasswordResetRepository {
+	return &passwordResetRepository{db: db}
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"gorm.io/gorm"
+)
+
+var ErrPersonalAccessTokenNotFound = errors.New("personal access token not found")
+
+type PersonalAccessTokenRepository interface {
+	Create(token *models.PersonalAccessToken) error
+	GetByHash(tokenHash string) (*models.PersonalAccessToken, error)
+	ListByUser(userID uint) ([]models.PersonalAccessToken, error)
+	Revoke(id, userID uint) error
+	TouchLastUsed(id uint, usedAt time.Time) error
+}
+
+// personalAccessTokenRepository implement PersonalAccessTokenRepository interface
+type personalAccessTokenRepository struct {
+	db *gorm.DB
+}
+
+// Create implements PersonalAccessTokenRepository.
+func (p *personalAccessTokenRepository) Create(token *models.PersonalAccessToken) error {
+	return p.db.Create(token).Error
+}
+
+// GetByHash implements PersonalAccessTokenRepository.
+func (p *personalAccessTokenRepository) GetByHash(tokenHash string) (*models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+
+	if err := p.db.Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPersonalAccessTokenNotFound
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// ListByUser implements PersonalAccessTokenRepository.
+// Revoked tokens are left out.
+func (p *personalAccessTokenRepository) ListByUser(userID uint) ([]models.PersonalAccessToken, error) {
+	var tokens []models.PersonalAccessToken
+
+	if err := p.db.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at desc").
+		Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// Revoke implements PersonalAccessTokenRepository.
+func (p *personalAccessTokenRepository) Revoke(id uint, userID uint) error {
+	result := p.db.Model(&models.PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrPersonalAccessTokenNotFound
+	}
+
+	return nil
+}
+
+// TouchLastUsed implements PersonalAccessTokenRepository.
+func (p *personalAccessTokenRepository) TouchLastUsed(id uint, usedAt time.Time) error {
+	return p.db.Model(&models.PersonalAccessToken{}).
+		Where("id = ?", id).
+		Update("last_used_at", usedAt).Error
+}
+
+func NewPersonalAccessTokenRepository(db *gorm.DB) PersonalAccessTokenRepository {
+	return &personalAccessTokenRepository{db: db}
+}
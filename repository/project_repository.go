@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"gorm.io/gorm"
+)
+
+var ErrProjectNotFound = errors.New("project not found")
+
+type ProjectRepository interface {
+	Create(project *models.Project) error
+	GetByID(id, userID uint) (*models.Project, error)
+	ListWithCounts(userID uint, includeArchived bool) ([]models.ProjectWithCounts, error)
+	NextSortOrder(userID uint) (int, error)
+	Update(project *models.Project) error
+	Delete(id, userID uint, mode string) error
+}
+
+// projectRepository implement ProjectRepository interface
+type projectRepository struct {
+	db *gorm.DB
+}
+
+// Create implements ProjectRepository.
+func (p *projectRepository) Create(project *models.Project) error {
+	return p.db.Create(project).Error
+}
+
+// GetByID implements ProjectRepository.
+func (p *projectRepository) GetByID(id uint, userID uint) (*models.Project, error) {
+	var project models.Project
+
+	if err := p.db.Where("id = ? AND user_id = ?", id, userID).First(&project).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, err
+	}
+
+	return &project, nil
+}
+
+// ListWithCounts implements ProjectRepository.
+// Soft-deleted tasks are not counted.
+func (p *projectRepository) ListWithCounts(userID uint, includeArchived bool) ([]models.ProjectWithCounts, error) {
+	var projects []models.ProjectWithCounts
+
+	query := p.db.Model(&models.Project{}).
+		Select("projects.*, COUNT(tasks.id) AS task_count, COUNT(tasks.id) FILTER (WHERE tasks.completed) AS completed_count").
+		Joins("LEFT JOIN tasks ON tasks.project_id = projects.id AND tasks.deleted_at IS NULL").
+		Where("projects.user_id = ?", userID)
+
+	if !includeArchived {
+		query = query.Where("projects.archived = ?", false)
+	}
+
+	if err := query.Group("projects.id").
+		Order("projects.sort_order asc, projects.name asc").
+		Scan(&projects).Error; err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+// NextSortOrder implements ProjectRepository.
+func (p *projectRepository) NextSortOrder(userID uint) (int, error) {
+	var next int
+
+	if err := p.db.Model(&models.Project{}).
+		Select("COALESCE(MAX(sort_order), -1) + 1").
+		Where("user_id = ?", userID).
+		Scan(&next).Error; err != nil {
+		return 0, err
+	}
+
+	return next, nil
+}
+
+// Update implements ProjectRepository.
+func (p *projectRepository) Update(project *models.Project) error {
+	return p.db.Save(project).Error
+}
+
+// Delete implements ProjectRepository.
+// With ProjectDeleteCascade the project's tasks are deleted along with it,
+// otherwise they move to the inbox. Tasks deleted earlier are detached either way.
+func (p *projectRepository) Delete(id uint, userID uint, mode string) error {
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		project := models.Project{ID: id}
+		if err := tx.Where("user_id = ?", userID).First(&project).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrProjectNotFound
+			}
+			return err
+		}
+
+		if mode == models.ProjectDeleteCascade {
+			if err := tx.Where("project_id = ?", project.ID).Delete(&models.Task{}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Unscoped().Model(&models.Task{}).
+			Where("project_id = ?", project.ID).
+			Updates(map[string]interface{}{
+				"project_id": nil,
+				"version":    gorm.Expr("version + 1"),
+			}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&project).Error
+	})
+}
+
+func NewProjectRepository(db *gorm.DB) ProjectRepository {
+	return &projectRepository{db: db}
+}
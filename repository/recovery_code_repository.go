@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"gorm.io/gorm"
+)
+
+var ErrRecoveryCodeNotFound = errors.New("recovery code not found")
+
+type RecoveryCodeRepository interface {
+	ReplaceForUser(userID uint, codeHashes []string) error
+	IsUnused(userID uint, codeHash string) (bool, error)
+	Consume(userID uint, codeHash string) error
+	DeleteForUser(userID uint) error
+}
+
+// recoveryCodeRepository implement RecoveryCodeRepository interface
+type recoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+// ReplaceForUser implements RecoveryCodeRepository.
+// Old codes are dropped and the new set is stored in one transaction.
+func (r *recoveryCodeRepository) ReplaceForUser(userID uint, codeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		codes := make([]models.RecoveryCode, 0, len(codeHashes))
+		for _, hash := range codeHashes {
+			codes = append(codes, models.RecoveryCode{UserID: userID, CodeHash: hash})
+		}
+
+		return tx.Create(&codes).Error
+	})
+}
+
+// IsUnused implements RecoveryCodeRepository.
+func (r *recoveryCodeRepository) IsUnused(userID uint, codeHash string) (bool, error) {
+	var count int64
+	if err := r.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Consume implements RecoveryCodeRepository.
+func (r *recoveryCodeRepository) Consume(userID uint, codeHash string) error {
+	result := r.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrRecoveryCodeNotFound
+	}
+
+	return nil
+}
+
+// DeleteForUser implements RecoveryCodeRepository.
+func (r *recoveryCodeRepository) DeleteForUser(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+}
+
+func NewRecoveryCodeRepository(db *gorm.DB) RecoveryCodeRepository {
+	return &recoveryCodeRepository{db: db}
+}
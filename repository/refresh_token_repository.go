@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenConsumed = errors.New("refresh token already used or revoked")
+)
+
+type RefreshTokenRepository interface {
+	Create(token *models.RefreshToken) error
+	GetByHash(tokenHash string) (*models.RefreshToken, error)
+	Rotate(current *models.RefreshToken, next *models.RefreshToken) error
+	RevokeFamily(familyID string) error
+	RevokeAllForUser(userID uint) error
+}
+
+// refreshTokenRepository implement RefreshTokenRepository interface
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+// Create implements RefreshTokenRepository.
+func (r *refreshTokenRepository) Create(token *models.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+// GetByHash implements RefreshTokenRepository.
+func (r *refreshTokenRepository) GetByHash(tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+
+	if err := r.db.Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// Rotate implements RefreshTokenRepository.
+// The current token is consumed with a conditional update so that two concurrent
+// refreshes with the same token cannot both succeed.
+func (r *refreshTokenRepository) Rotate(current *models.RefreshToken, next *models.RefreshToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", current.ID).
+			Updates(map[string]interface{}{
+				"used_at":        time.Now(),
+				"replaced_by_id": next.ID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenConsumed
+		}
+
+		return nil
+	})
+}
+
+// RevokeFamily implements RefreshTokenRepository.
+func (r *refreshTokenRepository) RevokeFamily(familyID string) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeAllForUser implements RefreshTokenRepository.
+func (r *refreshTokenRepository) RevokeAllForUser(userID uint) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{db: db}
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrReminderNotFound = errors.New("reminder not found")
+
+type ReminderRepository interface {
+	Create(reminder *models.Reminder) error
+	ListByTask(taskID, userID uint) ([]models.Reminder, error)
+	Delete(id, taskID, userID uint) error
+	ClaimDue(now time.Time, lease time.Duration, limit int) ([]models.Reminder, error)
+	Finish(reminder *models.Reminder, delivery *models.ReminderDelivery) error
+	Release(reminder *models.Reminder) error
+	RescheduleForTask(taskID uint, due time.Time) error
+	CarryOver(fromTaskID uint, to *models.Task) error
+}
+
+// reminderRepository implement ReminderRepository interface
+type reminderRepository struct {
+	db *gorm.DB
+}
+
+// Create implements ReminderRepository.
+func (r *reminderRepository) Create(reminder *models.Reminder) error {
+	return r.db.Create(reminder).Error
+}
+
+// ListByTask implements ReminderRepository.
+func (r *reminderRepository) ListByTask(taskID uint, userID uint) ([]models.Reminder, error) {
+	var reminders []models.Reminder
+
+	if err := r.db.Where("task_id = ? AND user_id = ?", taskID, userID).
+		Order("fire_at asc").
+		Find(&reminders).Error; err != nil {
+		return nil, err
+	}
+
+	return reminders, nil
+}
+
+// Delete implements ReminderRepository.
+func (r *reminderRepository) Delete(id uint, taskID uint, userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND task_id = ? AND user_id = ?", id, taskID, userID).Delete(&models.Reminder{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return ErrReminderNotFound
+		}
+
+		return tx.Where("reminder_id = ?", id).Delete(&models.ReminderDelivery{}).Error
+	})
+}
+
+// ClaimDue implements ReminderRepository.
+// Due reminders are locked with SKIP LOCKED, so concurrent schedulers never claim the same rows,
+// and marked as sending until now+lease. Claims of a scheduler that died expire with the lease.
+func (r *reminderRepository) ClaimDue(now time.Time, lease time.Duration, limit int) ([]models.Reminder, error) {
+	var reminders []models.Reminder
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ? AND fire_at <= ?", []string{models.ReminderStatusPending, models.ReminderStatusSending}, now).
+			Order("fire_at asc").
+			Limit(limit).
+			Find(&reminders).Error; err != nil {
+			return err
+		}
+
+		if len(reminders) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, 0, len(reminders))
+		for i := range reminders {
+			ids = append(ids, reminders[i].ID)
+			reminders[i].Status = models.ReminderStatusSending
+			reminders[i].FireAt = now.Add(lease)
+			reminders[i].Attempts++
+		}
+
+		return tx.Model(&models.Reminder{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"status":   models.ReminderStatusSending,
+			"fire_at":  now.Add(lease),
+			"attempts": gorm.Expr("attempts + 1"),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reminders, nil
+}
+
+// Finish implements ReminderRepository.
+// Stores the outcome of a claimed reminder together with its delivery record, if any.
+func (r *reminderRepository) Finish(reminder *models.Reminder, delivery *models.ReminderDelivery) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(reminder).
+			Select("status", "fire_at", "last_error", "sent_at").
+			Updates(reminder).Error; err != nil {
+			return err
+		}
+
+		if delivery == nil {
+			return nil
+		}
+
+		return tx.Create(delivery).Error
+	})
+}
+
+// Release implements ReminderRepository.
+// Hands a claimed but undelivered reminder back without counting the attempt.
+func (r *reminderRepository) Release(reminder *models.Reminder) error {
+	return r.db.Model(&models.Reminder{}).
+		Where("id = ? AND status = ?", reminder.ID, models.ReminderStatusSending).
+		Updates(map[string]interface{}{
+			"status":   models.ReminderStatusPending,
+			"fire_at":  time.Now(),
+			"attempts": gorm.Expr("GREATEST(attempts - 1, 0)"),
+		}).Error
+}
+
+// RescheduleForTask implements ReminderRepository.
+// Reminders relative to the due date are re-armed for the new date; reminders being sent are left alone.
+func (r *reminderRepository) RescheduleForTask(taskID uint, due time.Time) error {
+	return r.db.Model(&models.Reminder{}).
+		Where("task_id = ? AND minutes_before IS NOT NULL AND status <> ?", taskID, models.ReminderStatusSending).
+		Updates(map[string]interface{}{
+			"fire_at":    gorm.Expr("?::timestamptz - make_interval(mins => minutes_before)", due),
+			"status":     models.ReminderStatusPending,
+			"attempts":   0,
+			"last_error": "",
+			"sent_at":    nil,
+		}).Error
+}
+
+// CarryOver implements ReminderRepository.
+// Copies the reminders relative to the due date onto the next occurrence of a recurring task.
+func (r *reminderRepository) CarryOver(fromTaskID uint, to *models.Task) error {
+	if to.DueDate == nil {
+		return nil
+	}
+
+	return r.db.Exec(`INSERT INTO reminders (user_id, task_id, channel, webhook_url, minutes_before, fire_at, status, attempts, created_at, updated_at)
+SELECT user_id, ?, channel, webhook_url, minutes_before, ?::timestamptz - make_interval(mins => minutes_before), ?, 0, NOW(), NOW()
+FROM reminders WHERE task_id = ? AND minutes_before IS NOT NULL`,
+		to.ID, *to.DueDate, models.ReminderStatusPending, fromTaskID).Error
+}
+
+func NewReminderRepository(db *gorm.DB) ReminderRepository {
+	return &reminderRepository{db: db}
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RevokedTokenRepository interface {
+	Create(token *models.RevokedToken) error
+	IsRevoked(jti string) (bool, error)
+	Consume(token *models.RevokedToken) (bool, error)
+	DeleteExpired() error
+}
+
+// revokedTokenRepository implement RevokedTokenRepository interface
+type revokedTokenRepository struct {
+	db *gorm.DB
+}
+
+// Create implements RevokedTokenRepository.
+// Revoking an already revoked token is a no-op.
+func (r *revokedTokenRepository) Create(token *models.RevokedToken) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error
+}
+
+// IsRevoked implements RevokedTokenRepository.
+func (r *revokedTokenRepository) IsRevoked(jti string) (bool, error) {
+	var count int64
+	if err := r.db.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Consume implements RevokedTokenRepository.
+// It revokes a single-use token and reports whether this call was the one that used it.
+func (r *revokedTokenRepository) Consume(token *models.RevokedToken) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(token)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// DeleteExpired implements RevokedTokenRepository.
+func (r *revokedTokenRepository) DeleteExpired() error {
+	return r.db.Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{}).Error
+}
+
+func NewRevokedTokenRepository(db *gorm.DB) RevokedTokenRepository {
+	return &revokedTokenRepository{db: db}
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"gorm.io/gorm"
+)
+
+var ErrRoleNotFound = errors.New("role not found")
+
+type RoleRepository interface {
+	Create(role *models.Role) error
+	GetByName(name string) (*models.Role, error)
+	List() ([]models.Role, error)
+	Update(role *models.Role) error
+	Delete(name string) error
+}
+
+// roleRepository implement RoleRepository interface
+type roleRepository struct {
+	db *gorm.DB
+}
+
+// Create implements RoleRepository.
+func (r *roleRepository) Create(role *models.Role) error {
+	return r.db.Create(role).Error
+}
+
+// GetByName implements RoleRepository.
+func (r *roleRepository) GetByName(name string) (*models.Role, error) {
+	var role models.Role
+
+	if err := r.db.Where("name = ?", name).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+// List implements RoleRepository.
+func (r *roleRepository) List() ([]models.Role, error) {
+	var roles []models.Role
+
+	if err := r.db.Order("name asc").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// Update implements RoleRepository.
+func (r *roleRepository) Update(role *models.Role) error {
+	return r.db.Save(role).Error
+}
+
+// Delete implements RoleRepository.
+func (r *roleRepository) Delete(name string) error {
+	result := r.db.Where("name = ?", name).Delete(&models.Role{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrRoleNotFound
+	}
+
+	return nil
+}
+
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return &roleRepository{db: db}
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"gorm.io/gorm"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+type SessionRepository interface {
+	Create(session *models.Session) error
+	GetByID(id string) (*models.Session, error)
+	ListActiveByUser(userID uint) ([]models.Session, error)
+	Touch(id, ip string, seenAt time.Time) error
+	Extend(id, ip string, seenAt, expiresAt time.Time) error
+	Revoke(id string) error
+	RevokeAllForUser(userID uint) error
+	DeleteExpiredForUser(userID uint) error
+}
+
+// sessionRepository implement SessionRepository interface
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+// Create implements SessionRepository.
+func (s *sessionRepository) Create(session *models.Session) error {
+	return s.db.Create(session).Error
+}
+
+// GetByID implements SessionRepository.
+func (s *sessionRepository) GetByID(id string) (*models.Session, error) {
+	var session models.Session
+
+	if err := s.db.Where("id = ?", id).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// ListActiveByUser implements SessionRepository.
+func (s *sessionRepository) ListActiveByUser(userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+
+	err := s.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+
+	return sessions, err
+}
+
+// Touch implements SessionRepository.
+func (s *sessionRepository) Touch(id, ip string, seenAt time.Time) error {
+	return s.db.Model(&models.Session{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"last_seen_at": seenAt, "ip": ip}).Error
+}
+
+// Extend implements SessionRepository.
+func (s *sessionRepository) Extend(id, ip string, seenAt, expiresAt time.Time) error {
+	result := s.db.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{"last_seen_at": seenAt, "ip": ip, "expires_at": expiresAt})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// Revoke implements SessionRepository.
+func (s *sessionRepository) Revoke(id string) error {
+	return s.db.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeAllForUser implements SessionRepository.
+func (s *sessionRepository) RevokeAllForUser(userID uint) error {
+	return s.db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// DeleteExpiredForUser implements SessionRepository.
+func (s *sessionRepository) DeleteExpiredForUser(userID uint) error {
+	return s.db.Where("user_id = ? AND (expires_at < ? OR revoked_at IS NOT NULL)", userID, time.Now()).
+		Delete(&models.Session{}).Error
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrTagNotFound = errors.New("tag not found")
+
+type TagRepository interface {
+	Create(tag *models.Tag) error
+	GetByID(id, userID uint) (*models.Tag, error)
+	GetByName(userID uint, name string) (*models.Tag, error)
+	ListWithCounts(userID uint) ([]models.TagWithCount, error)
+	FindOrCreate(userID uint, names []string) ([]models.Tag, error)
+	Update(tag *models.Tag) error
+	Delete(id, userID uint) error
+}
+
+// tagRepository implement TagRepository interface
+type tagRepository struct {
+	db *gorm.DB
+}
+
+// Create implements TagRepository.
+func (t *tagRepository) Create(tag *models.Tag) error {
+	return t.db.Create(tag).Error
+}
+
+// GetByID implements TagRepository.
+func (t *tagRepository) GetByID(id uint, userID uint) (*models.Tag, error) {
+	var tag models.Tag
+
+	if err := t.db.Where("id = ? AND user_id = ?", id, userID).First(&tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTagNotFound
+		}
+		return nil, err
+	}
+
+	return &tag, nil
+}
+
+// GetByName implements TagRepository.
+func (t *tagRepository) GetByName(userID uint, name string) (*models.Tag, error) {
+	var tag models.Tag
+
+	if err := t.db.Where("user_id = ? AND name = ?", userID, name).First(&tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTagNotFound
+		}
+		return nil, err
+	}
+
+	return &tag, nil
+}
+
+// ListWithCounts implements TagRepository.
+// Soft-deleted tasks are not counted.
+func (t *tagRepository) ListWithCounts(userID uint) ([]models.TagWithCount, error) {
+	var tags []models.TagWithCount
+
+	if err := t.db.Model(&models.Tag{}).
+		Select("tags.*, COUNT(tasks.id) AS task_count").
+		Joins("LEFT JOIN task_tags ON task_tags.tag_id = tags.id").
+		Joins("LEFT JOIN tasks ON tasks.id = task_tags.task_id AND tasks.deleted_at IS NULL").
+		Where("tags.user_id = ?", userID).
+		Group("tags.id").
+		Order("tags.name asc").
+		Scan(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// FindOrCreate implements TagRepository.
+// Missing tags are created with the default color; concurrent creation of the same name is harmless.
+func (t *tagRepository) FindOrCreate(userID uint, names []string) ([]models.Tag, error) {
+	if len(names) == 0 {
+		return []models.Tag{}, nil
+	}
+
+	var tags []models.Tag
+
+	err := t.db.Transaction(func(tx *gorm.DB) error {
+		missing := make([]models.Tag, 0, len(names))
+		for _, name := range names {
+			missing = append(missing, models.Tag{UserID: userID, Name: name, Color: models.DefaultTagColor})
+		}
+
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&missing).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("user_id = ? AND name IN ?", userID, names).
+			Order("name asc").
+			Find(&tags).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// Update implements TagRepository.
+func (t *tagRepository) Update(tag *models.Tag) error {
+	return t.db.Save(tag).Error
+}
+
+// Delete implements TagRepository.
+// The tag is detached from all tasks first.
+func (t *tagRepository) Delete(id uint, userID uint) error {
+	return t.db.Transaction(func(tx *gorm.DB) error {
+		tag := models.Tag{ID: id}
+		if err := tx.Where("user_id = ?", userID).First(&tag).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTagNotFound
+			}
+			return err
+		}
+
+		if err := tx.Exec("DELETE FROM task_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&tag).Error
+	})
+}
+
+func NewTagRepository(db *gorm.DB) TagRepository {
+	return &tagRepository{db: db}
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrDependencyNotFound = errors.New("dependency not found")
+	ErrDependencyCycle    = errors.New("dependency would create a cycle")
+)
+
+type TaskDependencyRepository interface {
+	Add(userID uint, dependency *models.TaskDependency) error
+	Remove(taskID, blockedByID uint) error
+	ListBlockers(taskID uint) ([]*models.Task, error)
+	ListBlocking(taskID uint) ([]*models.Task, error)
+	BlockedTaskIDs(taskIDs []uint) (map[uint]bool, error)
+	OpenEdges(userID uint) ([]models.TaskDependency, error)
+}
+
+// taskDependencyRepository implement TaskDependencyRepository interface
+type taskDependencyRepository struct {
+	db *gorm.DB
+}
+
+// openTask joins the tasks table as alias so that only live, uncompleted tasks match
+func openTask(alias, on string) string {
+	return "JOIN tasks " + alias + " ON " + alias + ".id = " + on +
+		" AND " + alias + ".completed = false AND " + alias + ".deleted_at IS NULL"
+}
+
+// Add implements TaskDependencyRepository.
+// The user's row is locked while checking for cycles so that two concurrent edges cannot close one.
+// Adding an existing edge is a no-op.
+func (d *taskDependencyRepository) Add(userID uint, dependency *models.TaskDependency) error {
+	if dependency.TaskID == dependency.BlockedByID {
+		return ErrDependencyCycle
+	}
+
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").First(&models.User{}, userID).Error; err != nil {
+			return err
+		}
+
+		// follow blocked_by edges from the new blocker; reaching the task means a cycle
+		var cycle bool
+		if err := tx.Raw(`WITH RECURSIVE chain AS (
+	SELECT blocked_by_id AS id FROM task_dependencies WHERE task_id = ?
+	UNION
+	SELECT task_dependencies.blocked_by_id FROM task_dependencies JOIN chain ON task_dependencies.task_id = chain.id
+) SELECT EXISTS (SELECT 1 FROM chain WHERE id = ?)`, dependency.BlockedByID, dependency.TaskID).
+			Scan(&cycle).Error; err != nil {
+			return err
+		}
+
+		if cycle {
+			return ErrDependencyCycle
+		}
+
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(dependency).Error
+	})
+}
+
+// Remove implements TaskDependencyRepository.
+func (d *taskDependencyRepository) Remove(taskID uint, blockedByID uint) error {
+	result := d.db.Where("task_id = ? AND blocked_by_id = ?", taskID, blockedByID).Delete(&models.TaskDependency{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrDependencyNotFound
+	}
+
+	return nil
+}
+
+// ListBlockers implements TaskDependencyRepository.
+// Completed blockers are included; deleted ones are not.
+func (d *taskDependencyRepository) ListBlockers(taskID uint) ([]*models.Task, error) {
+	var tasks []*models.Task
+
+	if err := d.db.Preload("Tags").
+		Joins("JOIN task_dependencies ON task_dependencies.blocked_by_id = tasks.id").
+		Where("task_dependencies.task_id = ?", taskID).
+		Order("tasks.created_at asc").
+		Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// ListBlocking implements TaskDependencyRepository.
+func (d *taskDependencyRepository) ListBlocking(taskID uint) ([]*models.Task, error) {
+	var tasks []*models.Task
+
+	if err := d.db.Preload("Tags").
+		Joins("JOIN task_dependencies ON task_dependencies.task_id = tasks.id").
+		Where("task_dependencies.blocked_by_id = ?", taskID).
+		Order("tasks.created_at asc").
+		Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// BlockedTaskIDs implements TaskDependencyRepository.
+// A task is blocked while any of its blockers is open.
+func (d *taskDependencyRepository) BlockedTaskIDs(taskIDs []uint) (map[uint]bool, error) {
+	blocked := make(map[uint]bool)
+	if len(taskIDs) == 0 {
+		return blocked, nil
+	}
+
+	var ids []uint
+	if err := d.db.Model(&models.TaskDependency{}).
+		Distinct("task_dependencies.task_id").
+		Joins(openTask("blocker", "task_dependencies.blocked_by_id")).
+		Where("task_dependencies.task_id IN ?", taskIDs).
+		Pluck("task_dependencies.task_id", &ids).Error; err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		blocked[id] = true
+	}
+
+	return blocked, nil
+}
+
+// OpenEdges implements TaskDependencyRepository.
+// Only edges between two open tasks of the user are returned.
+func (d *taskDependencyRepository) OpenEdges(userID uint) ([]models.TaskDependency, error) {
+	var edges []models.TaskDependency
+
+	if err := d.db.Model(&models.TaskDependency{}).
+		Select("task_dependencies.*").
+		Joins(openTask("task", "task_dependencies.task_id")).
+		Joins(openTask("blocker", "task_dependencies.blocked_by_id")).
+		Where("task.user_id = ?", userID).
+		Find(&edges).Error; err != nil {
+		return nil, err
+	}
+
+	return edges, nil
+}
+
+func NewTaskDependencyRepository(db *gorm.DB) TaskDependencyRepository {
+	return &taskDependencyRepository{db: db}
+}
@@ -2,24 +2,42 @@ package repository
 
 import (
 	"errors"
+	"time"
 
 	"github.com/lieucongduy182/go-gin-todo-api/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrTaskNotFound        = errors.New("task not found")
+	ErrTaskVersionConflict = errors.New("task was changed by another request")
 )
 
 type TaskRepository interface {
 	Create(task *models.Task) error
 	GetById(id, userID uint) (*models.Task, error)
 	GetByUserId(userID uint) (*[]models.Task, error)
-	GetByUserIdPaginated(userID uint, page, pageSize int) ([]*models.Task, int64, error)
+	GetByUserIdPaginated(userID uint, filter *models.TaskFilter, page, pageSize int) ([]*models.Task, int64, error)
 	Search(userID uint, keyword string) ([]*models.Task, error)
 	GetByStatus(userID uint, status bool) ([]*models.Task, error)
 	GetByPriority(userID uint, priority string) ([]*models.Task, error)
 	Update(task *models.Task) error
-	Delete(id, userID uint) error
+	ReplaceTags(task *models.Task, tags []models.Tag) error
+	Depth(id uint) (int, error)
+	GetChildren(parentID, userID uint) ([]*models.Task, error)
+	GetSubtree(id, userID uint) ([]*models.Task, error)
+	SubtaskProgress(parentIDs []uint) (map[uint]*models.SubtaskProgress, error)
+	Delete(task *models.Task) error
+	ListTrash(userID uint, page, pageSize int) ([]*models.Task, int64, error)
+	GetTrashed(id, userID uint) (*models.Task, error)
+	Restore(task *models.Task) error
+	Purge(id, userID uint) error
+	EmptyTrash(userID uint) (int64, error)
+	PurgeTrashedBefore(cutoff time.Time, limit int) (int64, error)
 	DeleteByUserId(userID uint) error
 	Count(userID uint) (int64, error)
-	GetStats(userID uint) (map[string]interface{}, error)
+	GetStats(userID uint, filter *models.TaskFilter) (map[string]interface{}, error)
 }
 
 // taskRepository implement The TaskRepository interface
@@ -38,22 +56,225 @@ func (t *taskRepository) Count(userID uint) (int64, error) {
 }
 
 // Create implements TaskRepository.
+// Tags must already exist; only the task_tags links are written for them.
 func (t *taskRepository) Create(task *models.Task) error {
-	if err := t.db.Save(task).Error; err != nil {
+	task.Version = 1
+	if err := t.db.Omit("Tags.*").Save(task).Error; err != nil {
 		return err
 	}
 	return nil
 }
 
+// subtreeCTE selects the ids of a live task and all of its live descendants
+const subtreeCTE = `WITH RECURSIVE subtree AS (
+	SELECT id FROM tasks WHERE id = ? AND user_id = ? AND deleted_at IS NULL
+	UNION ALL
+	SELECT tasks.id FROM tasks JOIN subtree ON tasks.parent_id = subtree.id WHERE tasks.deleted_at IS NULL
+) SELECT id FROM subtree`
+
 // Delete implements TaskRepository.
-func (t *taskRepository) Delete(id uint, userID uint) error {
-	if err := t.db.Where("id = ? and user_id = ?", id, userID).Delete(&models.Task{}).Error; err != nil {
-		return err
+// Subtasks are deleted along with their parent. Like Update, it only applies to the
+// version of task that was loaded.
+func (t *taskRepository) Delete(task *models.Task) error {
+	result := t.db.Where("id IN (?)", t.db.Raw(subtreeCTE, task.ID, task.UserID)).
+		Where("EXISTS (SELECT 1 FROM tasks root WHERE root.id = ? AND root.version = ?)", task.ID, task.Version).
+		Delete(&models.Task{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrTaskVersionConflict
+	}
+
+	return nil
+}
+
+// trashedRoots limits a query to trashed tasks that were not deleted as part of a trashed parent
+const trashedRoots = `tasks.deleted_at IS NOT NULL AND NOT EXISTS (
+	SELECT 1 FROM tasks parent WHERE parent.id = tasks.parent_id AND parent.deleted_at IS NOT NULL
+)`
+
+// ListTrash implements TaskRepository.
+// Subtasks deleted with their parent are only listed through the parent, most recently deleted first.
+func (t *taskRepository) ListTrash(userID uint, page int, pageSize int) ([]*models.Task, int64, error) {
+	var tasks []*models.Task
+	var total int64
+
+	query := t.db.Unscoped().Where("tasks.user_id = ?", userID).Where(trashedRoots)
+
+	if err := query.Session(&gorm.Session{}).Model(&models.Task{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Preload("Tags").
+		Order("tasks.deleted_at desc").
+		Offset(offset).
+		Limit(pageSize).
+		Find(&tasks).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return tasks, total, nil
+}
+
+// GetTrashed implements TaskRepository.
+func (t *taskRepository) GetTrashed(id uint, userID uint) (*models.Task, error) {
+	var task models.Task
+
+	if err := t.db.Unscoped().Preload("Tags").
+		Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).
+		First(&task).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+
+	return &task, nil
+}
+
+// Restore implements TaskRepository.
+// Subtasks come back with the task only if they were deleted together with it, and a
+// subtask only comes back while its parent is live.
+// Only the loaded version of task is restored, and every restored task gets a new
+// version, so writes pinned to a version from before the deletion fail.
+func (t *taskRepository) Restore(task *models.Task) error {
+	result := t.db.Unscoped().Model(&models.Task{}).
+		Where("id IN (?)", t.db.Raw(`WITH RECURSIVE subtree AS (
+	SELECT id, deleted_at FROM tasks WHERE id = ? AND user_id = ? AND version = ? AND deleted_at IS NOT NULL
+	AND (parent_id IS NULL OR EXISTS (SELECT 1 FROM tasks parent WHERE parent.id = tasks.parent_id AND parent.deleted_at IS NULL))
+	UNION ALL
+	SELECT tasks.id, tasks.deleted_at FROM tasks JOIN subtree ON tasks.parent_id = subtree.id
+	WHERE tasks.deleted_at = subtree.deleted_at
+) SELECT id FROM subtree`, task.ID, task.UserID, task.Version)).
+		Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"version":    gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrTaskVersionConflict
 	}
 
+	task.Version++
+	task.DeletedAt = gorm.DeletedAt{}
 	return nil
 }
 
+// Purge implements TaskRepository.
+// Only trashed tasks can be purged; their subtasks are purged with them.
+func (t *taskRepository) Purge(id uint, userID uint) error {
+	return t.db.Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		if err := tx.Unscoped().Model(&models.Task{}).
+			Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+
+		if len(ids) == 0 {
+			return ErrTaskNotFound
+		}
+
+		_, err := purgeTasks(tx, ids)
+		return err
+	})
+}
+
+// EmptyTrash implements TaskRepository.
+func (t *taskRepository) EmptyTrash(userID uint) (int64, error) {
+	var purged int64
+
+	err := t.db.Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		if err := tx.Unscoped().Model(&models.Task{}).
+			Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+
+		var err error
+		purged, err = purgeTasks(tx, ids)
+		return err
+	})
+
+	return purged, err
+}
+
+// PurgeTrashedBefore implements TaskRepository.
+// Purges up to limit trashed tasks, with their subtasks, of all users deleted before cutoff.
+func (t *taskRepository) PurgeTrashedBefore(cutoff time.Time, limit int) (int64, error) {
+	var purged int64
+
+	err := t.db.Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		if err := tx.Unscoped().Model(&models.Task{}).
+			Where("deleted_at < ?", cutoff).
+			Order("deleted_at asc").
+			Limit(limit).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+
+		var err error
+		purged, err = purgeTasks(tx, ids)
+		return err
+	})
+
+	return purged, err
+}
+
+// purgeTasks permanently deletes the given tasks, their trashed subtasks and everything attached to them.
+// A live subtask, restored or moved under a trashed task, is left alone.
+func purgeTasks(tx *gorm.DB, rootIDs []uint) (int64, error) {
+	if len(rootIDs) == 0 {
+		return 0, nil
+	}
+
+	var ids []uint
+	if err := tx.Raw(`WITH RECURSIVE subtree AS (
+	SELECT id FROM tasks WHERE id IN ?
+	UNION
+	SELECT tasks.id FROM tasks JOIN subtree ON tasks.parent_id = subtree.id WHERE tasks.deleted_at IS NOT NULL
+) SELECT id FROM subtree`, rootIDs).Scan(&ids).Error; err != nil {
+		return 0, err
+	}
+
+	if err := tx.Exec("DELETE FROM task_tags WHERE task_id IN ?", ids).Error; err != nil {
+		return 0, err
+	}
+
+	if err := tx.Where("task_id IN ? OR blocked_by_id IN ?", ids, ids).Delete(&models.TaskDependency{}).Error; err != nil {
+		return 0, err
+	}
+
+	if err := tx.Where("reminder_id IN (?)", tx.Model(&models.Reminder{}).Select("id").Where("task_id IN ?", ids)).
+		Delete(&models.ReminderDelivery{}).Error; err != nil {
+		return 0, err
+	}
+
+	if err := tx.Where("task_id IN ?", ids).Delete(&models.Reminder{}).Error; err != nil {
+		return 0, err
+	}
+
+	if err := tx.Where("task_id IN ?", ids).Delete(&models.TaskRevision{}).Error; err != nil {
+		return 0, err
+	}
+
+	// the inbox keeps its history, only the link to the task goes
+	if err := tx.Model(&models.Notification{}).Where("task_id IN ?", ids).Update("task_id", nil).Error; err != nil {
+		return 0, err
+	}
+
+	result := tx.Unscoped().Where("id IN ?", ids).Delete(&models.Task{})
+	return result.RowsAffected, result.Error
+}
+
 // DeleteByUserId implements TaskRepository.
 func (t *taskRepository) DeleteByUserId(userID uint) error {
 	if err := t.db.Where("user_id = ?", userID).Delete(&models.Task{}).Error; err != nil {
@@ -66,9 +287,9 @@ func (t *taskRepository) DeleteByUserId(userID uint) error {
 // GetById implements TaskRepository.
 func (t *taskRepository) GetById(id uint, userID uint) (*models.Task, error) {
 	var task *models.Task
-	if err := t.db.Where("id = ? and userID = ?", id, userID).First(&task).Error; err != nil {
+	if err := t.db.Preload("Tags").Where("id = ? and user_id = ?", id, userID).First(&task).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("Task not Found")
+			return nil, ErrTaskNotFound
 		}
 
 		return nil, err
@@ -81,9 +302,9 @@ func (t *taskRepository) GetById(id uint, userID uint) (*models.Task, error) {
 func (t *taskRepository) GetByPriority(userID uint, priority string) ([]*models.Task, error) {
 	var task []*models.Task
 
-	if err := t.db.Where("user_id = ? AND priority = ?", userID, priority).
+	if err := t.db.Preload("Tags").Where("user_id = ? AND priority = ?", userID, priority).
 		Order("created_at desc").
-		First(&task).Error; err != nil {
+		Find(&task).Error; err != nil {
 		return nil, err
 	}
 
@@ -94,9 +315,9 @@ func (t *taskRepository) GetByPriority(userID uint, priority string) ([]*models.
 func (t *taskRepository) GetByStatus(userID uint, status bool) ([]*models.Task, error) {
 	var task []*models.Task
 
-	if err := t.db.Where("user_id = ? AND completed = ?", userID, status).
+	if err := t.db.Preload("Tags").Where("user_id = ? AND completed = ?", userID, status).
 		Order("created_at desc").
-		First(&task).Error; err != nil {
+		Find(&task).Error; err != nil {
 		return nil, err
 	}
 
@@ -106,13 +327,9 @@ func (t *taskRepository) GetByStatus(userID uint, status bool) ([]*models.Task,
 // GetByUserId implements TaskRepository.
 func (t *taskRepository) GetByUserId(userID uint) (*[]models.Task, error) {
 	var task *[]models.Task
-	if err := t.db.Where("userID = ?", userID).
+	if err := t.db.Preload("Tags").Where("user_id = ?", userID).
 		Order("created_at desc").
 		Find(&task).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("Task not Found")
-		}
-
 		return nil, err
 	}
 
@@ -120,16 +337,17 @@ func (t *taskRepository) GetByUserId(userID uint) (*[]models.Task, error) {
 }
 
 // GetByUserIdPaginated implements TaskRepository.
-func (t *taskRepository) GetByUserIdPaginated(userID uint, page int, pageSize int) ([]*models.Task, int64, error) {
+func (t *taskRepository) GetByUserIdPaginated(userID uint, filter *models.TaskFilter, page int, pageSize int) ([]*models.Task, int64, error) {
 	var task []*models.Task
 	var total int64
 
-	if err := t.db.Model(&models.Task{}).Where("user_id = ?", userID).
+	if err := t.filtered(userID, filter).Model(&models.Task{}).
 		Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 	offset := (page - 1) * pageSize
-	if err := t.db.Where("user_id = ?", userID).
+	if err := t.filtered(userID, filter).Preload("Tags").
+		Order("created_at desc").
 		Offset(offset).
 		Limit(pageSize).
 		Find(&task).Error; err != nil {
@@ -139,17 +357,82 @@ func (t *taskRepository) GetByUserIdPaginated(userID uint, page int, pageSize in
 	return task, total, nil
 }
 
+// filtered starts a task query limited to the user's tasks matching filter
+func (t *taskRepository) filtered(userID uint, filter *models.TaskFilter) *gorm.DB {
+	query := t.db.Where("tasks.user_id = ?", userID)
+
+	switch {
+	case filter.InboxOnly:
+		query = query.Where("tasks.project_id IS NULL")
+	case filter.ProjectID != nil:
+		query = query.Where("tasks.project_id = ?", *filter.ProjectID)
+	}
+
+	if len(filter.Tags) > 0 {
+		tagged := t.db.Table("task_tags").
+			Select("task_tags.task_id").
+			Joins("JOIN tags ON tags.id = task_tags.tag_id").
+			Where("tags.user_id = ? AND tags.name IN ?", userID, filter.Tags)
+
+		// every requested tag must be present, so count the matches per task
+		if filter.TagMode == models.TagModeAll {
+			tagged = tagged.Group("task_tags.task_id").Having("COUNT(DISTINCT tags.id) = ?", len(filter.Tags))
+		}
+
+		query = query.Where("tasks.id IN (?)", tagged)
+	}
+
+	return query
+}
+
 // GetStats implements TaskRepository.
-func (t *taskRepository) GetStats(userID uint) (map[string]interface{}, error) {
+func (t *taskRepository) GetStats(userID uint, filter *models.TaskFilter) (map[string]interface{}, error) {
 	var total, completed, pending, high, medium, low int64
 
-	t.db.Model(&models.Task{}).Where("user_id = ?", userID).Count(&total)
-	t.db.Model(&models.Task{}).Where("user_id = ? AND completed = ?", userID, true).Count(&completed)
-	t.db.Model(&models.Task{}).Where("user_id = ? AND completed = ?", userID, false).Count(&pending)
+	counts := []struct {
+		dest  *int64
+		query string
+		arg   interface{}
+	}{
+		{dest: &total},
+		{dest: &completed, query: "completed = ?", arg: true},
+		{dest: &pending, query: "completed = ?", arg: false},
+		{dest: &high, query: "priority = ?", arg: "high"},
+		{dest: &medium, query: "priority = ?", arg: "medium"},
+		{dest: &low, query: "priority = ?", arg: "low"},
+	}
+
+	for _, count := range counts {
+		query := t.filtered(userID, filter).Model(&models.Task{})
+		if count.query != "" {
+			query = query.Where(count.query, count.arg)
+		}
+
+		if err := query.Count(count.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var tagCounts []struct {
+		Name  string
+		Count int64
+	}
+	err := t.db.Table("tags").
+		Select("tags.name, COUNT(tasks.id) AS count").
+		Joins("JOIN task_tags ON task_tags.tag_id = tags.id").
+		Joins("JOIN tasks ON tasks.id = task_tags.task_id AND tasks.deleted_at IS NULL").
+		Where("tags.user_id = ?", userID).
+		Where("tasks.id IN (?)", t.filtered(userID, filter).Model(&models.Task{}).Select("tasks.id")).
+		Group("tags.name").
+		Scan(&tagCounts).Error
+	if err != nil {
+		return nil, err
+	}
 
-	t.db.Model(&models.Task{}).Where("user_id = ? AND priority = ?", userID, "high").Count(&high)
-	t.db.Model(&models.Task{}).Where("user_id = ? AND priority = ?", userID, "medium").Count(&medium)
-	t.db.Model(&models.Task{}).Where("user_id = ? AND priority = ?", userID, "low").Count(&low)
+	tags := make(map[string]int64, len(tagCounts))
+	for _, tag := range tagCounts {
+		tags[tag.Name] = tag.Count
+	}
 
 	stats := map[string]interface{}{
 		"total": total,
@@ -162,6 +445,7 @@ func (t *taskRepository) GetStats(userID uint) (map[string]interface{}, error) {
 			"medium": medium,
 			"low":    low,
 		},
+		"tags": tags,
 	}
 	return stats, nil
 }
@@ -171,7 +455,7 @@ func (t *taskRepository) Search(userID uint, keyword string) ([]*models.Task, er
 	var task []*models.Task
 	searchPattern := "%" + keyword + "%"
 
-	if err := t.db.Where("user_id = ? AND (title ILIKE ? OR description ILIKE ?)",
+	if err := t.db.Preload("Tags").Where("user_id = ? AND (title ILIKE ? OR description ILIKE ?)",
 		userID, searchPattern, searchPattern).
 		Order("created_at desc").
 		Find(&task).Error; err != nil {
@@ -182,14 +466,107 @@ func (t *taskRepository) Search(userID uint, keyword string) ([]*models.Task, er
 }
 
 // Update implements TaskRepository.
+// Associations are left alone; tags change through ReplaceTags.
+// The row is only written while it still has the version the task was loaded with, and the version
+// is bumped in the same statement, so concurrent writers cannot overwrite each other.
 func (t *taskRepository) Update(task *models.Task) error {
-	if err := t.db.Save(task).Error; err != nil {
-		return err
+	loaded := task.Version
+	task.Version++
+
+	result := t.db.Model(task).
+		Where("version = ?", loaded).
+		Select("*").
+		Omit(clause.Associations, "created_at").
+		Updates(task)
+	if result.Error != nil {
+		task.Version = loaded
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		task.Version = loaded
+		return ErrTaskVersionConflict
 	}
 
 	return nil
 }
 
+// ReplaceTags implements TaskRepository.
+func (t *taskRepository) ReplaceTags(task *models.Task, tags []models.Tag) error {
+	return t.db.Model(task).Omit("Tags.*").Association("Tags").Replace(tags)
+}
+
+// Depth implements TaskRepository.
+// A top-level task has depth 1.
+func (t *taskRepository) Depth(id uint) (int, error) {
+	var depth int
+
+	if err := t.db.Raw(`WITH RECURSIVE ancestors AS (
+	SELECT id, parent_id, 1 AS depth FROM tasks WHERE id = ?
+	UNION ALL
+	SELECT tasks.id, tasks.parent_id, ancestors.depth + 1 FROM tasks JOIN ancestors ON tasks.id = ancestors.parent_id
+) SELECT COALESCE(MAX(depth), 0) FROM ancestors`, id).Scan(&depth).Error; err != nil {
+		return 0, err
+	}
+
+	return depth, nil
+}
+
+// GetChildren implements TaskRepository.
+func (t *taskRepository) GetChildren(parentID uint, userID uint) ([]*models.Task, error) {
+	var tasks []*models.Task
+
+	if err := t.db.Preload("Tags").Where("parent_id = ? AND user_id = ?", parentID, userID).
+		Order("created_at asc").
+		Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// GetSubtree implements TaskRepository.
+// The task itself is included; an empty result means it does not exist.
+func (t *taskRepository) GetSubtree(id uint, userID uint) ([]*models.Task, error) {
+	var tasks []*models.Task
+
+	if err := t.db.Preload("Tags").Where("id IN (?)", t.db.Raw(subtreeCTE, id, userID)).
+		Order("created_at asc").
+		Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// SubtaskProgress implements TaskRepository.
+// Tasks without subtasks are missing from the result.
+func (t *taskRepository) SubtaskProgress(parentIDs []uint) (map[uint]*models.SubtaskProgress, error) {
+	progress := make(map[uint]*models.SubtaskProgress, len(parentIDs))
+	if len(parentIDs) == 0 {
+		return progress, nil
+	}
+
+	var counts []struct {
+		ParentID  uint
+		Total     int64
+		Completed int64
+	}
+	if err := t.db.Model(&models.Task{}).
+		Select("parent_id, COUNT(*) AS total, COUNT(*) FILTER (WHERE completed) AS completed").
+		Where("parent_id IN ?", parentIDs).
+		Group("parent_id").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+
+	for _, count := range counts {
+		progress[count.ParentID] = models.NewSubtaskProgress(count.Total, count.Completed)
+	}
+
+	return progress, nil
+}
+
 func NewTaskRepository(db *gorm.DB) TaskRepository {
 	return &taskRepository{db: db}
 }
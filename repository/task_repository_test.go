@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB opens the database in TEST_DATABASE_DSN, e.g. the one from docker-compose:
+// "host=localhost port=55432 user=postgres password=password dbname=todo_db sslmode=disable".
+// Each test runs in a transaction that is rolled back afterwards.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := db.Begin()
+	t.Cleanup(func() { tx.Rollback() })
+
+	if err := tx.AutoMigrate(
+		&models.User{},
+		&models.Task{},
+		&models.Tag{},
+		&models.Project{},
+		&models.TaskDependency{},
+		&models.TaskRevision{},
+		&models.Reminder{},
+		&models.ReminderDelivery{},
+		&models.Notification{},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	return tx
+}
+
+// taskTree stores a parent with two subtasks for a new user
+func taskTree(t *testing.T, db *gorm.DB) (parent, first, second *models.Task) {
+	t.Helper()
+
+	user := &models.User{Email: "trash-test@example.com", Username: "trash-test"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	parent = &models.Task{UserID: user.ID, Title: "parent"}
+	if err := db.Create(parent).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	first = &models.Task{UserID: user.ID, ParentID: &parent.ID, Title: "first"}
+	second = &models.Task{UserID: user.ID, ParentID: &parent.ID, Title: "second"}
+	if err := db.Create([]*models.Task{first, second}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return parent, first, second
+}
+
+func exists(t *testing.T, db *gorm.DB, id uint) bool {
+	t.Helper()
+
+	var count int64
+	if err := db.Unscoped().Model(&models.Task{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	return count > 0
+}
+
+func TestPurgeKeepsLiveSubtasks(t *testing.T) {
+	db := testDB(t)
+	repo := NewTaskRepository(db)
+	parent, trashed, live := taskTree(t, db)
+
+	if err := repo.Delete(parent); err != nil {
+		t.Fatal(err)
+	}
+
+	// a subtask that is live again under the trashed parent, as before restores checked the parent
+	if err := db.Unscoped().Model(live).Update("deleted_at", nil).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.Purge(parent.ID, parent.UserID); err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+
+	if exists(t, db, parent.ID) || exists(t, db, trashed.ID) {
+		t.Errorf("the trashed tasks were not purged")
+	}
+	if !exists(t, db, live.ID) {
+		t.Errorf("the live subtask was purged")
+	}
+}
+
+func TestRestoreNeedsLiveParent(t *testing.T) {
+	db := testDB(t)
+	repo := NewTaskRepository(db)
+	parent, subtask, _ := taskTree(t, db)
+
+	if err := repo.Delete(subtask); err != nil {
+		t.Fatal(err)
+	}
+	parent, _ = repo.GetById(parent.ID, parent.UserID)
+	if err := repo.Delete(parent); err != nil {
+		t.Fatal(err)
+	}
+
+	subtask, err := repo.GetTrashed(subtask.ID, subtask.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Restore(subtask); !errors.Is(err, ErrTaskVersionConflict) {
+		t.Errorf("restoring under a trashed parent: err = %v, want ErrTaskVersionConflict", err)
+	}
+	if _, err := repo.GetById(subtask.ID, subtask.UserID); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("the subtask was restored under a trashed parent")
+	}
+}
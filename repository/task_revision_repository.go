@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"gorm.io/gorm"
+)
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+type TaskRevisionRepository interface {
+	Create(revision *models.TaskRevision) error
+	ListByTask(taskID, userID uint, page, pageSize int) ([]models.TaskRevision, int64, error)
+	GetByID(id, taskID, userID uint) (*models.TaskRevision, error)
+	ListAfter(taskID, revisionID uint) ([]models.TaskRevision, error)
+}
+
+// taskRevisionRepository implement TaskRevisionRepository interface
+type taskRevisionRepository struct {
+	db *gorm.DB
+}
+
+// Create implements TaskRevisionRepository.
+func (r *taskRevisionRepository) Create(revision *models.TaskRevision) error {
+	return r.db.Create(revision).Error
+}
+
+// ListByTask implements TaskRevisionRepository.
+// Newest revisions come first.
+func (r *taskRevisionRepository) ListByTask(taskID uint, userID uint, page int, pageSize int) ([]models.TaskRevision, int64, error) {
+	var revisions []models.TaskRevision
+	var total int64
+
+	query := r.db.Model(&models.TaskRevision{}).Where("task_id = ? AND user_id = ?", taskID, userID)
+
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("id desc").Offset(offset).Limit(pageSize).Find(&revisions).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return revisions, total, nil
+}
+
+// GetByID implements TaskRevisionRepository.
+func (r *taskRevisionRepository) GetByID(id uint, taskID uint, userID uint) (*models.TaskRevision, error) {
+	var revision models.TaskRevision
+
+	if err := r.db.Where("id = ? AND task_id = ? AND user_id = ?", id, taskID, userID).
+		First(&revision).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, err
+	}
+
+	return &revision, nil
+}
+
+// ListAfter implements TaskRevisionRepository.
+// Returns the revisions of the task newer than revisionID, newest first.
+func (r *taskRevisionRepository) ListAfter(taskID uint, revisionID uint) ([]models.TaskRevision, error) {
+	var revisions []models.TaskRevision
+
+	if err := r.db.Where("task_id = ? AND id > ?", taskID, revisionID).
+		Order("id desc").
+		Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func NewTaskRevisionRepository(db *gorm.DB) TaskRevisionRepository {
+	return &taskRevisionRepository{db: db}
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"gorm.io/gorm"
+)
+
+var ErrUserIdentityNotFound = errors.New("user identity not found")
+
+type UserIdentityRepository interface {
+	Create(identity *models.UserIdentity) error
+	GetByProviderSubject(provider, subject string) (*models.UserIdentity, error)
+	TouchLastLogin(id uint, at time.Time) error
+}
+
+// userIdentityRepository implement UserIdentityRepository interface
+type userIdentityRepository struct {
+	db *gorm.DB
+}
+
+// Create implements UserIdentityRepository.
+func (u *userIdentityRepository) Create(identity *models.UserIdentity) error {
+	return u.db.Create(identity).Error
+}
+
+// GetByProviderSubject implements UserIdentityRepository.
+func (u *userIdentityRepository) GetByProviderSubject(provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+
+	if err := u.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserIdentityNotFound
+		}
+		return nil, err
+	}
+
+	return &identity, nil
+}
+
+// TouchLastLogin implements UserIdentityRepository.
+func (u *userIdentityRepository) TouchLastLogin(id uint, at time.Time) error {
+	return u.db.Model(&models.UserIdentity{}).Where("id = ?", id).Update("last_login_at", at).Error
+}
+
+func NewUserIdentityRepository(db *gorm.DB) UserIdentityRepository {
+	return &userIdentityRepository{db: db}
+}
@@ -7,16 +7,23 @@ import (
 	"gorm.io/gorm"
 )
 
+var ErrUserNotFound = errors.New("user not found")
+
 // User Repository defines the interface
 type UserRepository interface {
 	Create(user *models.User) error
 	GetByID(id uint) (*models.User, error)
 	GetByEmail(email string) (*models.User, error)
 	GetByUsername(username string) (*models.User, error)
+	GetByWebAuthnHandle(handle []byte) (*models.User, error)
 	Update(user *models.User) error
 	Delete(id uint) error
 	UserExists(email string) (bool, error)
 	GetAll(page, pageSize int) ([]models.User, int64, error)
+	AdvanceTOTPStep(id uint, step int64) (bool, error)
+	ReplacePasswordHash(id uint, oldHash, newHash string) error
+	CountByRole(role string) (int64, error)
+	CountActiveByRole(role string) (int64, error)
 }
 
 // userRepository implement UserRepository interface
@@ -34,12 +41,96 @@ func (u *userRepository) Create(user *models.User) error {
 }
 
 // Delete implements UserRepository.
+// The user and all of their tasks are removed permanently in a single transaction.
 func (u *userRepository) Delete(id uint) error {
-	if err := u.db.Delete(id).Error; err != nil {
-		return err
-	}
+	return u.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", id).Delete(&models.RefreshToken{}).Error; err != nil {
+			return err
+		}
 
-	return nil
+		if err := tx.Where("user_id = ?", id).Delete(&models.RevokedToken{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", id).Delete(&models.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", id).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", id).Delete(&models.PersonalAccessToken{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", id).Delete(&models.UserIdentity{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", id).Delete(&models.Session{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", id).Delete(&models.MagicLinkToken{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", id).Delete(&models.WebAuthnCredential{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", id).Delete(&models.WebAuthnCeremony{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Exec("DELETE FROM task_tags WHERE tag_id IN (SELECT id FROM tags WHERE user_id = ?)", id).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", id).Delete(&models.Tag{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Exec("DELETE FROM reminder_deliveries WHERE reminder_id IN (SELECT id FROM reminders WHERE user_id = ?)", id).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", id).Delete(&models.Reminder{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", id).Delete(&models.Notification{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", id).Delete(&models.TaskRevision{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Exec("DELETE FROM task_dependencies WHERE task_id IN (SELECT id FROM tasks WHERE user_id = ?)", id).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Where("user_id = ?", id).Delete(&models.Task{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", id).Delete(&models.Project{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().Delete(&models.User{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return ErrUserNotFound
+		}
+
+		return nil
+	})
 }
 
 // GetAll implements UserRepository.
@@ -53,7 +144,7 @@ func (u *userRepository) GetAll(page int, pageSize int) ([]models.User, int64, e
 
 	offset := (page - 1) * pageSize
 
-	if err := u.db.Offset(offset).Limit(pageSize).Find(&users).Error; err != nil {
+	if err := u.db.Order("id asc").Offset(offset).Limit(pageSize).Find(&users).Error; err != nil {
 		return nil, 0, err
 	}
 
@@ -66,7 +157,7 @@ func (u *userRepository) GetByEmail(email string) (*models.User, error) {
 
 	if err := u.db.Where("email = ?", email).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -80,7 +171,7 @@ func (u *userRepository) GetByID(id uint) (*models.User, error) {
 
 	if err := u.db.Where("id = ?", id).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -94,7 +185,21 @@ func (u *userRepository) GetByUsername(username string) (*models.User, error) {
 
 	if err := u.db.Where("username = ?", username).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// GetByWebAuthnHandle implements UserRepository.
+func (u *userRepository) GetByWebAuthnHandle(handle []byte) (*models.User, error) {
+	var user models.User
+
+	if err := u.db.Where("web_authn_handle = ?", handle).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -114,13 +219,55 @@ func (u *userRepository) Update(user *models.User) error {
 // UserExists implements UserRepository.
 func (u *userRepository) UserExists(email string) (bool, error) {
 	var count int64
-	if err := u.db.Where("email = ?", email).Count(&count).Error; err != nil {
+	if err := u.db.Model(&models.User{}).Where("email = ?", email).Count(&count).Error; err != nil {
 		return false, err
 	}
 
 	return count > 0, nil
 }
 
+// AdvanceTOTPStep implements UserRepository.
+// It records step as the last used TOTP step only if it is newer than the stored one,
+// so the same code cannot be accepted twice even by concurrent requests.
+func (u *userRepository) AdvanceTOTPStep(id uint, step int64) (bool, error) {
+	result := u.db.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// ReplacePasswordHash implements UserRepository.
+// The swap only happens while oldHash is still stored, so it never undoes a concurrent password change.
+func (u *userRepository) ReplacePasswordHash(id uint, oldHash, newHash string) error {
+	return u.db.Model(&models.User{}).
+		Where("id = ? AND password = ?", id, oldHash).
+		Update("password", newHash).Error
+}
+
+// CountByRole implements UserRepository.
+func (u *userRepository) CountByRole(role string) (int64, error) {
+	var count int64
+	if err := u.db.Model(&models.User{}).Where("role = ?", role).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// CountActiveByRole implements UserRepository.
+func (u *userRepository) CountActiveByRole(role string) (int64, error) {
+	var count int64
+	if err := u.db.Model(&models.User{}).Where("role = ? AND disabled_at IS NULL", role).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
 func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db: db}
 }
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrWebAuthnCeremonyNotFound = errors.New("webauthn ceremony not found")
+
+type WebAuthnCeremonyRepository interface {
+	Create(ceremony *models.WebAuthnCeremony) error
+	Consume(tokenHash, kind string) (*models.WebAuthnCeremony, error)
+	DeleteExpired() error
+}
+
+// webAuthnCeremonyRepository implement WebAuthnCeremonyRepository interface
+type webAuthnCeremonyRepository struct {
+	db *gorm.DB
+}
+
+// Create implements WebAuthnCeremonyRepository.
+func (w *webAuthnCeremonyRepository) Create(ceremony *models.WebAuthnCeremony) error {
+	return w.db.Create(ceremony).Error
+}
+
+// Consume implements WebAuthnCeremonyRepository.
+// The row is deleted and returned in one statement, so a challenge can only be answered once.
+func (w *webAuthnCeremonyRepository) Consume(tokenHash, kind string) (*models.WebAuthnCeremony, error) {
+	var ceremonies []models.WebAuthnCeremony
+
+	result := w.db.Clauses(clause.Returning{}).
+		Where("token_hash = ? AND kind = ? AND expires_at > ?", tokenHash, kind, time.Now()).
+		Delete(&ceremonies)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if len(ceremonies) == 0 {
+		return nil, ErrWebAuthnCeremonyNotFound
+	}
+
+	return &ceremonies[0], nil
+}
+
+// DeleteExpired implements WebAuthnCeremonyRepository.
+func (w *webAuthnCeremonyRepository) DeleteExpired() error {
+	return w.db.Where("expires_at <= ?", time.Now()).Delete(&models.WebAuthnCeremony{}).Error
+}
+
+func NewWebAuthnCeremonyRepository(db *gorm.DB) WebAuthnCeremonyRepository {
+	return &webAuthnCeremonyRepository{db: db}
+}
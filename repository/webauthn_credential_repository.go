@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"gorm.io/gorm"
+)
+
+var ErrWebAuthnCredentialNotFound = errors.New("passkey not found")
+
+type WebAuthnCredentialRepository interface {
+	Create(credential *models.WebAuthnCredential) error
+	ListByUser(userID uint) ([]models.WebAuthnCredential, error)
+	CountByUser(userID uint) (int64, error)
+	UpdateAfterLogin(credential *models.WebAuthnCredential) error
+	Delete(id, userID uint) error
+}
+
+// webAuthnCredentialRepository implement WebAuthnCredentialRepository interface
+type webAuthnCredentialRepository struct {
+	db *gorm.DB
+}
+
+// Create implements WebAuthnCredentialRepository.
+func (w *webAuthnCredentialRepository) Create(credential *models.WebAuthnCredential) error {
+	return w.db.Create(credential).Error
+}
+
+// ListByUser implements WebAuthnCredentialRepository.
+func (w *webAuthnCredentialRepository) ListByUser(userID uint) ([]models.WebAuthnCredential, error) {
+	var credentials []models.WebAuthnCredential
+
+	if err := w.db.Where("user_id = ?", userID).
+		Order("created_at asc").
+		Find(&credentials).Error; err != nil {
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
+// CountByUser implements WebAuthnCredentialRepository.
+func (w *webAuthnCredentialRepository) CountByUser(userID uint) (int64, error) {
+	var count int64
+	if err := w.db.Model(&models.WebAuthnCredential{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// UpdateAfterLogin implements WebAuthnCredentialRepository.
+// Only the fields an assertion can change are written.
+func (w *webAuthnCredentialRepository) UpdateAfterLogin(credential *models.WebAuthnCredential) error {
+	return w.db.Model(&models.WebAuthnCredential{}).
+		Where("id = ?", credential.ID).
+		Updates(map[string]any{
+			"sign_count":    credential.SignCount,
+			"backup_state":  credential.BackupState,
+			"clone_warning": credential.CloneWarning,
+			"last_used_at":  credential.LastUsedAt,
+		}).Error
+}
+
+// Delete implements WebAuthnCredentialRepository.
+func (w *webAuthnCredentialRepository) Delete(id uint, userID uint) error {
+	result := w.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.WebAuthnCredential{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrWebAuthnCredentialNotFound
+	}
+
+	return nil
+}
+
+func NewWebAuthnCredentialRepository(db *gorm.DB) WebAuthnCredentialRepository {
+	return &webAuthnCredentialRepository{db: db}
+}
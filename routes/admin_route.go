@@ -0,0 +1,38 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/handlers"
+	"github.com/lieucongduy182/go-gin-todo-api/middleware"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/service"
+)
+
+func SetupAdminRoutes(
+	r *gin.Engine,
+	authMiddleware gin.HandlerFunc,
+	roleService service.RoleService,
+	adminHandler *handlers.AdminHandler,
+) {
+	v1 := r.Group("/api/v1")
+
+	can := func(permission string) gin.HandlerFunc {
+		return middleware.RequirePermission(roleService, permission)
+	}
+
+	admin := v1.Group("/admin")
+	admin.Use(authMiddleware)
+	{
+		admin.GET("/users", can(models.PermissionUsersRead), adminHandler.ListUsers)
+		admin.GET("/users/:id", can(models.PermissionUsersRead), adminHandler.GetUser)
+		admin.POST("/users/:id/disable", can(models.PermissionUsersManage), adminHandler.DisableUser)
+		admin.POST("/users/:id/enable", can(models.PermissionUsersManage), adminHandler.EnableUser)
+		admin.PUT("/users/:id/role", can(models.PermissionRolesManage), adminHandler.AssignRole)
+		admin.POST("/users/:id/impersonate", can(models.PermissionUsersImpersonate), adminHandler.ImpersonateUser)
+
+		admin.GET("/roles", can(models.PermissionRolesManage), adminHandler.ListRoles)
+		admin.POST("/roles", can(models.PermissionRolesManage), adminHandler.CreateRole)
+		admin.PATCH("/roles/:name", can(models.PermissionRolesManage), adminHandler.UpdateRole)
+		admin.DELETE("/roles/:name", can(models.PermissionRolesManage), adminHandler.DeleteRole)
+	}
+}
@@ -4,20 +4,47 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/lieucongduy182/go-gin-todo-api/handlers"
 	"github.com/lieucongduy182/go-gin-todo-api/middleware"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
 )
 
-func SetupAuthRoutes(r *gin.Engine) {
+func SetupAuthRoutes(
+	r *gin.Engine,
+	authMiddleware gin.HandlerFunc,
+	requireScope func(scope string) gin.HandlerFunc,
+	authHandler *handlers.AuthHandler,
+	userHandler *handlers.UserHandler,
+	passwordHandler *handlers.PasswordHandler,
+	verificationHandler *handlers.VerificationHandler,
+	magicLinkHandler *handlers.MagicLinkHandler,
+) {
 	v1 := r.Group("/api/v1")
+	rejectImpersonation := middleware.RejectImpersonation()
 
 	auth := v1.Group("/auth")
 	{
-		auth.POST("/login", handlers.Login)
-		auth.POST("/register", handlers.Register)
+		auth.POST("/login", authHandler.Login)
+		auth.POST("/register", authHandler.Register)
+		auth.POST("/refresh", authHandler.Refresh)
+		auth.POST("/logout", authMiddleware, authHandler.Logout)
+		auth.POST("/logout-all", authMiddleware, rejectImpersonation, authHandler.LogoutAll)
+		auth.POST("/password/forgot", passwordHandler.ForgotPassword)
+		auth.POST("/password/reset", passwordHandler.ResetPassword)
+		auth.GET("/verify", verificationHandler.VerifyEmail)
+		auth.POST("/verify/resend", authMiddleware, rejectImpersonation, verificationHandler.ResendVerification)
+		auth.GET("/unlock", authHandler.UnlockAccount)
+		auth.POST("/magic-link", magicLinkHandler.RequestLink)
+		auth.GET("/magic-link/callback", magicLinkHandler.ConfirmLink)
+		auth.POST("/magic-link/callback", magicLinkHandler.Callback)
 	}
 
+	v1.GET("/profile", requireScope(models.ScopeProfileRead), userHandler.GetProfile)
+
 	protected := v1.Group("/")
-	protected.Use(middleware.AuthMiddleware())
+	// impersonation only reaches the read-only profile above, never changes to the account
+	protected.Use(authMiddleware, rejectImpersonation)
 	{
-		protected.GET("/profile", handlers.GetProfile)
+		protected.PATCH("/profile", userHandler.UpdateProfile)
+		protected.POST("/profile/password", userHandler.ChangePassword)
+		protected.DELETE("/profile", userHandler.DeleteAccount)
 	}
 }
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/handlers"
+	"github.com/lieucongduy182/go-gin-todo-api/middleware"
+)
+
+func SetupMFARoutes(r *gin.Engine, authMiddleware gin.HandlerFunc, mfaHandler *handlers.MFAHandler) {
+	v1 := r.Group("/api/v1")
+
+	// second login step, authenticated by the mfa_token from /auth/login
+	v1.POST("/auth/login/mfa", mfaHandler.CompleteLogin)
+
+	mfa := v1.Group("/mfa")
+	mfa.Use(authMiddleware, middleware.RejectImpersonation())
+	{
+		mfa.POST("/totp/enroll", mfaHandler.EnrollTOTP)
+		mfa.POST("/totp/confirm", mfaHandler.ConfirmTOTP)
+		mfa.POST("/totp/disable", mfaHandler.DisableTOTP)
+		mfa.POST("/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+	}
+}
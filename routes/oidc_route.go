@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/handlers"
+)
+
+func SetupOIDCRoutes(r *gin.Engine, oidcHandler *handlers.OIDCHandler) {
+	oidc := r.Group("/api/v1/auth/oidc")
+	{
+		oidc.GET("/providers", oidcHandler.ListProviders)
+		oidc.GET("/:provider/login", oidcHandler.Login)
+		oidc.GET("/:provider/callback", oidcHandler.Callback)
+	}
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/handlers"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+)
+
+func SetupProjectRoutes(r *gin.Engine, requireScope func(scope string) gin.HandlerFunc, projectHandler *handlers.ProjectHandler) {
+	v1 := r.Group("/api/v1")
+
+	// projects belong to the task domain and share its token scopes
+	read := requireScope(models.ScopeTasksRead)
+	write := requireScope(models.ScopeTasksWrite)
+
+	projects := v1.Group("/projects")
+	{
+		projects.GET("", read, projectHandler.ListProjects)
+		projects.POST("", write, projectHandler.CreateProject)
+		projects.GET("/:id", read, projectHandler.GetProject)
+		projects.GET("/:id/tasks", read, projectHandler.GetProjectTasks)
+		projects.GET("/:id/stats", read, projectHandler.GetProjectStats)
+		projects.PATCH("/:id", write, projectHandler.UpdateProject)
+		projects.DELETE("/:id", write, projectHandler.DeleteProject)
+	}
+}
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/handlers"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+)
+
+func SetupReminderRoutes(r *gin.Engine, requireScope func(scope string) gin.HandlerFunc, reminderHandler *handlers.ReminderHandler) {
+	v1 := r.Group("/api/v1")
+
+	// reminders and the in-app inbox belong to the task domain and share its token scopes
+	read := requireScope(models.ScopeTasksRead)
+	write := requireScope(models.ScopeTasksWrite)
+
+	reminders := v1.Group("/tasks/:id/reminders")
+	{
+		reminders.GET("", read, reminderHandler.ListReminders)
+		reminders.POST("", write, reminderHandler.CreateReminder)
+		reminders.DELETE("/:reminderId", write, reminderHandler.DeleteReminder)
+	}
+
+	notifications := v1.Group("/notifications")
+	{
+		notifications.GET("", read, reminderHandler.ListNotifications)
+		notifications.POST("/read-all", write, reminderHandler.MarkAllNotificationsRead)
+		notifications.POST("/:id/read", write, reminderHandler.MarkNotificationRead)
+	}
+}
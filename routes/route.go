@@ -1,8 +1,154 @@
 package routes
 
-import "github.com/gin-gonic/gin"
+import (
+	"log"
+	"time"
 
-func SetupRoutes(r *gin.Engine) {
-	SetupAuthRoutes(r)
-	SetupTaskRoutes(r)
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/config"
+	"github.com/lieucongduy182/go-gin-todo-api/database"
+	"github.com/lieucongduy182/go-gin-todo-api/handlers"
+	"github.com/lieucongduy182/go-gin-todo-api/mailer"
+	"github.com/lieucongduy182/go-gin-todo-api/middleware"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/notifier"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/service"
+	"gorm.io/gorm"
+)
+
+// SetupRoutes registers all routes and returns the background jobs the server should run
+func SetupRoutes(r *gin.Engine) []service.Job {
+	db := database.GetDB()
+
+	// repositories
+	userRepo := repository.NewUserRepository(db)
+	taskRepo := repository.NewTaskRepository(db)
+	tagRepo := repository.NewTagRepository(db)
+	projectRepo := repository.NewProjectRepository(db)
+	taskDependencyRepo := repository.NewTaskDependencyRepository(db)
+	reminderRepo := repository.NewReminderRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	taskRevisionRepo := repository.NewTaskRevisionRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	revokedTokenRepo := repository.NewRevokedTokenRepository(db)
+	passwordResetRepo := repository.NewPasswordResetRepository(db)
+	recoveryCodeRepo := repository.NewRecoveryCodeRepository(db)
+	patRepo := repository.NewPersonalAccessTokenRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	identityRepo := repository.NewUserIdentityRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	magicLinkRepo := repository.NewMagicLinkRepository(db)
+	passkeyRepo := repository.NewWebAuthnCredentialRepository(db)
+	webAuthnCeremonyRepo := repository.NewWebAuthnCeremonyRepository(db)
+
+	// infrastructure
+	mail := mailer.New(config.AppConfig)
+	loginAttempts := newLoginAttemptStore(config.AppConfig.LoginThrottleStore, db)
+
+	// services
+	auditService := service.NewAuditService(auditRepo)
+	loginThrottle := service.NewLoginThrottleService(loginAttempts, auditService, mail)
+	sessionService := service.NewSessionService(sessionRepo, refreshTokenRepo)
+	tokenService := service.NewTokenService(userRepo, refreshTokenRepo, revokedTokenRepo, sessionService)
+	verificationService := service.NewEmailVerificationService(userRepo, mail)
+	mfaService := service.NewMFAService(userRepo, recoveryCodeRepo, passkeyRepo, tokenService, loginThrottle)
+	userService := service.NewUserService(userRepo, taskRepo, tokenService, verificationService, mfaService, loginThrottle, sessionService)
+	magicLinkService := service.NewMagicLinkService(userRepo, magicLinkRepo, tokenService, mfaService, loginThrottle, mail)
+	webAuthnService := service.NewWebAuthnService(userRepo, passkeyRepo, webAuthnCeremonyRepo, recoveryCodeRepo, tokenService, loginThrottle)
+	oidcService := service.NewOIDCService(userRepo, identityRepo, tokenService, mfaService, auditService)
+	taskService := service.NewTaskService(db, userRepo, taskRepo, tagRepo, projectRepo, taskDependencyRepo, reminderRepo, taskRevisionRepo)
+	tagService := service.NewTagService(tagRepo)
+	projectService := service.NewProjectService(projectRepo)
+	reminderService := service.NewReminderService(taskRepo, reminderRepo)
+	notificationService := service.NewNotificationService(notificationRepo)
+	trashRetention := time.Duration(config.AppConfig.TrashRetentionDays) * 24 * time.Hour
+	trashService := service.NewTrashService(taskRepo, trashRetention)
+	passwordResetService := service.NewPasswordResetService(userRepo, passwordResetRepo, tokenService, mail)
+	patService := service.NewPersonalAccessTokenService(userRepo, patRepo)
+	roleService := service.NewRoleService(roleRepo, userRepo)
+	adminService := service.NewAdminService(userRepo, roleService, tokenService, auditService)
+
+	// handlers
+	authHandler := handlers.NewAuthHandler(userService, tokenService, loginThrottle)
+	userHandler := handlers.NewUserHandler(userService)
+	passwordHandler := handlers.NewPasswordHandler(passwordResetService)
+	verificationHandler := handlers.NewVerificationHandler(verificationService)
+	mfaHandler := handlers.NewMFAHandler(mfaService)
+	oidcHandler := handlers.NewOIDCHandler(oidcService)
+	sessionHandler := handlers.NewSessionHandler(sessionService)
+	magicLinkHandler := handlers.NewMagicLinkHandler(magicLinkService)
+	webAuthnHandler := handlers.NewWebAuthnHandler(webAuthnService)
+	patHandler := handlers.NewPersonalAccessTokenHandler(patService)
+	adminHandler := handlers.NewAdminHandler(userService, adminService, roleService)
+	taskHandler := handlers.NewTaskHandler(taskService)
+	tagHandler := handlers.NewTagHandler(tagService)
+	projectHandler := handlers.NewProjectHandler(projectService, taskService)
+	reminderHandler := handlers.NewReminderHandler(reminderService, notificationService)
+	trashHandler := handlers.NewTrashHandler(trashService, taskService)
+
+	// middleware
+	authMiddleware := middleware.AuthMiddleware(tokenService, sessionService)
+	requireScope := middleware.ScopedAuthMiddleware(tokenService, sessionService, patService)
+
+	SetupWellKnownRoutes(r)
+	SetupAuthRoutes(r, authMiddleware, requireScope, authHandler, userHandler, passwordHandler, verificationHandler, magicLinkHandler)
+	SetupMFARoutes(r, authMiddleware, mfaHandler)
+	SetupOIDCRoutes(r, oidcHandler)
+	SetupWebAuthnRoutes(r, authMiddleware, webAuthnHandler)
+	SetupTokenRoutes(r, authMiddleware, patHandler)
+	SetupSessionRoutes(r, authMiddleware, sessionHandler)
+	SetupTaskRoutes(r, requireScope, taskHandler)
+	SetupTagRoutes(r, requireScope, tagHandler)
+	SetupProjectRoutes(r, requireScope, projectHandler)
+	SetupReminderRoutes(r, requireScope, reminderHandler)
+	SetupTrashRoutes(r, requireScope, trashHandler)
+	SetupAdminRoutes(r, authMiddleware, roleService, adminHandler)
+
+	// background jobs
+	var jobs []service.Job
+	if config.AppConfig.RemindersEnabled {
+		webhookTimeout := time.Duration(config.AppConfig.WebhookTimeoutSeconds) * time.Second
+		notifiers := map[string]notifier.Notifier{
+			models.ReminderChannelEmail:   notifier.NewEmailNotifier(mail, config.AppConfig.AppBaseURL),
+			models.ReminderChannelWebhook: notifier.NewWebhookNotifier(config.AppConfig.WebhookSecret, webhookTimeout, config.AppConfig.WebhookAllowPrivate),
+			models.ReminderChannelInbox:   notifier.NewInboxNotifier(notificationRepo),
+		}
+
+		jobs = append(jobs, service.NewReminderScheduler(
+			reminderRepo,
+			taskRepo,
+			userRepo,
+			notifiers,
+			time.Duration(config.AppConfig.ReminderPollSeconds)*time.Second,
+			config.AppConfig.ReminderBatchSize,
+			config.AppConfig.ReminderMaxAttempts,
+			webhookTimeout,
+		))
+	}
+
+	if trashRetention > 0 {
+		jobs = append(jobs, service.NewTrashPurger(
+			taskRepo,
+			trashRetention,
+			time.Duration(config.AppConfig.TrashPurgeIntervalMinutes)*time.Minute,
+		))
+	}
+
+	return jobs
+}
+
+// newLoginAttemptStore picks the backend named by LOGIN_THROTTLE_STORE.
+// The in-memory store only works when a single instance serves logins.
+func newLoginAttemptStore(driver string, db *gorm.DB) repository.LoginAttemptStore {
+	switch driver {
+	case "memory":
+		return repository.NewMemoryLoginAttemptStore()
+	case "postgres", "":
+		return repository.NewLoginAttemptRepository(db)
+	default:
+		log.Printf("Unknown login throttle store %q, falling back to postgres", driver)
+		return repository.NewLoginAttemptRepository(db)
+	}
 }
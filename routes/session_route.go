@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/handlers"
+	"github.com/lieucongduy182/go-gin-todo-api/middleware"
+)
+
+func SetupSessionRoutes(r *gin.Engine, authMiddleware gin.HandlerFunc, sessionHandler *handlers.SessionHandler) {
+	v1 := r.Group("/api/v1")
+
+	sessions := v1.Group("/sessions")
+	sessions.Use(authMiddleware, middleware.RejectImpersonation())
+	{
+		sessions.GET("", sessionHandler.ListSessions)
+		sessions.DELETE("/:id", sessionHandler.RevokeSession)
+	}
+}
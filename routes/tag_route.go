@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/handlers"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+)
+
+func SetupTagRoutes(r *gin.Engine, requireScope func(scope string) gin.HandlerFunc, tagHandler *handlers.TagHandler) {
+	v1 := r.Group("/api/v1")
+
+	// tags belong to the task domain and share its token scopes
+	read := requireScope(models.ScopeTasksRead)
+	write := requireScope(models.ScopeTasksWrite)
+
+	tags := v1.Group("/tags")
+	{
+		tags.GET("", read, tagHandler.ListTags)
+		tags.POST("", write, tagHandler.CreateTag)
+		tags.PATCH("/:id", write, tagHandler.UpdateTag)
+		tags.DELETE("/:id", write, tagHandler.DeleteTag)
+	}
+}
@@ -3,19 +3,36 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/lieucongduy182/go-gin-todo-api/handlers"
-	"github.com/lieucongduy182/go-gin-todo-api/middleware"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
 )
 
-func SetupTaskRoutes(r *gin.Engine) {
+func SetupTaskRoutes(r *gin.Engine, requireScope func(scope string) gin.HandlerFunc, taskHandler *handlers.TaskHandler) {
 	v1 := r.Group("/api/v1")
 
+	read := requireScope(models.ScopeTasksRead)
+	write := requireScope(models.ScopeTasksWrite)
+
 	protected := v1.Group("/tasks")
-	protected.Use(middleware.AuthMiddleware())
 	{
-		protected.GET("/", handlers.GetTasks)
-		protected.GET("/:id", handlers.GetTask)
-		protected.POST("/", handlers.CreateTask)
-		protected.PATCH("/:id", handlers.UpdateTask)
-		protected.DELETE("/:id", handlers.DeleteTask)
+		protected.GET("/", read, taskHandler.GetTasks)
+		protected.GET("/search", read, taskHandler.SearchTasks)
+		protected.GET("/stats", read, taskHandler.GetStats)
+		protected.GET("/next", read, taskHandler.GetNextTasks)
+		protected.GET("/priority/:priority", read, taskHandler.GetTasksByPriority)
+		protected.GET("/status/:status", read, taskHandler.GetTasksByStatus)
+		protected.GET("/:id", read, taskHandler.GetTask)
+		protected.GET("/:id/subtasks", read, taskHandler.GetSubtasks)
+		protected.GET("/:id/tree", read, taskHandler.GetTaskTree)
+		protected.GET("/:id/dependencies", read, taskHandler.GetDependencies)
+		protected.GET("/:id/history", read, taskHandler.GetTaskHistory)
+		protected.POST("/", write, taskHandler.CreateTask)
+		protected.PATCH("/:id", write, taskHandler.UpdateTask)
+		protected.POST("/:id/move", write, taskHandler.MoveTask)
+		protected.POST("/:id/revert/:revision", write, taskHandler.RevertTask)
+		protected.POST("/:id/recurrence/skip", write, taskHandler.SkipOccurrence)
+		protected.POST("/:id/recurrence/end", write, taskHandler.EndRecurrence)
+		protected.POST("/:id/dependencies", write, taskHandler.AddDependency)
+		protected.DELETE("/:id/dependencies/:blockedById", write, taskHandler.RemoveDependency)
+		protected.DELETE("/:id", write, taskHandler.DeleteTask)
 	}
 }
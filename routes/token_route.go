@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/handlers"
+	"github.com/lieucongduy182/go-gin-todo-api/middleware"
+)
+
+func SetupTokenRoutes(r *gin.Engine, authMiddleware gin.HandlerFunc, patHandler *handlers.PersonalAccessTokenHandler) {
+	v1 := r.Group("/api/v1")
+
+	// managing tokens always requires an interactive login, never a token itself
+	tokens := v1.Group("/tokens")
+	tokens.Use(authMiddleware, middleware.RejectImpersonation())
+	{
+		tokens.GET("/", patHandler.ListTokens)
+		tokens.POST("/", patHandler.CreateToken)
+		tokens.DELETE("/:id", patHandler.RevokeToken)
+	}
+}
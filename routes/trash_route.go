@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/handlers"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+)
+
+func SetupTrashRoutes(r *gin.Engine, requireScope func(scope string) gin.HandlerFunc, trashHandler *handlers.TrashHandler) {
+	v1 := r.Group("/api/v1")
+
+	read := requireScope(models.ScopeTasksRead)
+	write := requireScope(models.ScopeTasksWrite)
+
+	tasks := v1.Group("/tasks")
+	{
+		tasks.GET("/trash", read, trashHandler.ListTrash)
+		tasks.DELETE("/trash", write, trashHandler.EmptyTrash)
+		tasks.POST("/:id/restore", write, trashHandler.RestoreTask)
+		tasks.DELETE("/:id/permanent", write, trashHandler.PurgeTask)
+	}
+}
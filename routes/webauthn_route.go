@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/lieucongduy182/go-gin-todo-api/handlers"
+	"github.com/lieucongduy182/go-gin-todo-api/middleware"
+)
+
+func SetupWebAuthnRoutes(r *gin.Engine, authMiddleware gin.HandlerFunc, webAuthnHandler *handlers.WebAuthnHandler) {
+	v1 := r.Group("/api/v1")
+
+	// passwordless login with a passkey
+	v1.POST("/auth/webauthn/login/begin", webAuthnHandler.BeginLogin)
+	v1.POST("/auth/webauthn/login/finish", webAuthnHandler.FinishLogin)
+
+	// passkey as the second login step, authenticated by the mfa_token from /auth/login
+	v1.POST("/auth/login/mfa/webauthn/begin", webAuthnHandler.BeginSecondFactor)
+	v1.POST("/auth/login/mfa/webauthn/finish", webAuthnHandler.FinishSecondFactor)
+
+	passkeys := v1.Group("/webauthn")
+	passkeys.Use(authMiddleware, middleware.RejectImpersonation())
+	{
+		passkeys.POST("/registration/begin", webAuthnHandler.BeginRegistration)
+		passkeys.POST("/registration/finish", webAuthnHandler.FinishRegistration)
+		passkeys.GET("/credentials", webAuthnHandler.ListCredentials)
+		passkeys.DELETE("/credentials/:id", webAuthnHandler.DeleteCredential)
+	}
+}
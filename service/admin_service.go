@@ -0,0 +1,252 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/utils"
+)
+
+var (
+	ErrCannotModifySelf  = errors.New("administrators cannot perform this action on their own account")
+	ErrCannotImpersonate = errors.New("this user cannot be impersonated")
+	ErrCannotDisable     = errors.New("privileged users cannot be disabled")
+	ErrLastAdmin         = errors.New("the last active administrator cannot be disabled or demoted")
+)
+
+// AdminService implements the support operations exposed under /api/v1/admin
+type AdminService interface {
+	GetUser(userID uint) (*models.UserResponse, error)
+	DisableUser(actorID, userID uint) (*models.UserResponse, error)
+	EnableUser(actorID, userID uint) (*models.UserResponse, error)
+	AssignRole(actorID, userID uint, role string) (*models.UserResponse, error)
+	Impersonate(actorID, userID uint) (*models.ImpersonationResponse, error)
+}
+
+type adminService struct {
+	userRepo     repository.UserRepository
+	roleService  RoleService
+	tokenService TokenService
+	auditService AuditService
+}
+
+// GetUser implements AdminService.
+func (a *adminService) GetUser(userID uint) (*models.UserResponse, error) {
+	user, err := a.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := user.ToResponse()
+	return &response, nil
+}
+
+// DisableUser implements AdminService.
+// Disabling also signs the user out everywhere. Like impersonation, it only applies to unprivileged users,
+// and the last active administrator is never disabled.
+func (a *adminService) DisableUser(actorID uint, userID uint) (*models.UserResponse, error) {
+	if actorID == userID {
+		return nil, ErrCannotModifySelf
+	}
+
+	user, err := a.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsDisabled() {
+		if err := a.checkNotLastAdmin(user); err != nil {
+			return nil, err
+		}
+
+		privileged, err := a.isPrivileged(user.Role)
+		if err != nil {
+			return nil, err
+		}
+
+		if privileged {
+			return nil, ErrCannotDisable
+		}
+
+		now := time.Now()
+		user.DisabledAt = &now
+		if err := a.userRepo.Update(user); err != nil {
+			return nil, err
+		}
+
+		if err := a.tokenService.LogoutAll(user.ID); err != nil {
+			return nil, err
+		}
+
+		a.record(models.AuditUserDisabled, actorID, user, "")
+	}
+
+	response := user.ToResponse()
+	return &response, nil
+}
+
+// EnableUser implements AdminService.
+func (a *adminService) EnableUser(actorID uint, userID uint) (*models.UserResponse, error) {
+	user, err := a.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsDisabled() {
+		user.DisabledAt = nil
+		if err := a.userRepo.Update(user); err != nil {
+			return nil, err
+		}
+
+		a.record(models.AuditUserEnabled, actorID, user, "")
+	}
+
+	response := user.ToResponse()
+	return &response, nil
+}
+
+// AssignRole implements AdminService.
+// Existing sessions are ended so that the new role claim takes effect immediately.
+func (a *adminService) AssignRole(actorID uint, userID uint, role string) (*models.UserResponse, error) {
+	if actorID == userID {
+		return nil, ErrCannotModifySelf
+	}
+
+	exists, err := a.roleService.RoleExists(role)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, repository.ErrRoleNotFound
+	}
+
+	user, err := a.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Role != role {
+		if err := a.checkNotLastAdmin(user); err != nil {
+			return nil, err
+		}
+
+		previous := user.Role
+		user.Role = role
+		if err := a.userRepo.Update(user); err != nil {
+			return nil, err
+		}
+
+		if err := a.tokenService.LogoutAll(user.ID); err != nil {
+			return nil, err
+		}
+
+		a.record(models.AuditRoleAssigned, actorID, user, fmt.Sprintf("role changed from %s to %s", previous, role))
+	}
+
+	response := user.ToResponse()
+	return &response, nil
+}
+
+// Impersonate implements AdminService.
+// Only unprivileged, active users can be impersonated.
+func (a *adminService) Impersonate(actorID uint, userID uint) (*models.ImpersonationResponse, error) {
+	if actorID == userID {
+		return nil, ErrCannotImpersonate
+	}
+
+	user, err := a.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsDisabled() {
+		return nil, ErrCannotImpersonate
+	}
+
+	privileged, err := a.isPrivileged(user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	if privileged {
+		return nil, ErrCannotImpersonate
+	}
+
+	token, err := utils.GenerateImpersonationToken(user.ID, user.Email, user.Role, actorID)
+	if err != nil {
+		return nil, errors.New("Failed to generate token")
+	}
+
+	a.record(models.AuditImpersonationStarted, actorID, user, "")
+
+	return &models.ImpersonationResponse{
+		AccessToken:    token,
+		User:           user.ToResponse(),
+		ImpersonatorID: actorID,
+		ExpiresIn:      int(utils.AccessTokenTTL().Seconds()),
+		TokenType:      "Bearer",
+	}, nil
+}
+
+// isPrivileged reports whether role grants any permission at all
+func (a *adminService) isPrivileged(role string) (bool, error) {
+	for _, permission := range models.AllPermissions {
+		granted, err := a.roleService.HasPermissions(role, permission)
+		if err != nil {
+			return false, err
+		}
+
+		if granted {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// checkNotLastAdmin refuses to take the admin role away from its only active holder
+func (a *adminService) checkNotLastAdmin(user *models.User) error {
+	if user.Role != models.RoleAdmin || user.IsDisabled() {
+		return nil
+	}
+
+	active, err := a.userRepo.CountActiveByRole(models.RoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	if active <= 1 {
+		return ErrLastAdmin
+	}
+
+	return nil
+}
+
+// record writes an audit event for an action actorID took on user
+func (a *adminService) record(event string, actorID uint, user *models.User, detail string) {
+	a.auditService.Record(&models.AuditEvent{
+		Event:   event,
+		UserID:  &user.ID,
+		ActorID: &actorID,
+		Email:   user.Email,
+		Detail:  detail,
+	})
+}
+
+func NewAdminService(
+	userRepo repository.UserRepository,
+	roleService RoleService,
+	tokenService TokenService,
+	auditService AuditService,
+) AdminService {
+	return &adminService{
+		userRepo:     userRepo,
+		roleService:  roleService,
+		tokenService: tokenService,
+		auditService: auditService,
+	}
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+)
+
+// fakeRoleService knows the built-in roles only
+type fakeRoleService struct {
+	RoleService
+}
+
+func (f *fakeRoleService) RoleExists(name string) (bool, error) {
+	_, ok := models.BuiltinRolePermissions[name]
+	return ok, nil
+}
+
+func (f *fakeRoleService) HasPermissions(role string, permissions ...string) (bool, error) {
+	for _, permission := range permissions {
+		granted := false
+		for _, p := range models.BuiltinRolePermissions[role] {
+			granted = granted || p == permission
+		}
+		if !granted {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+type adminFixture struct {
+	*authFixture
+	service AdminService
+}
+
+func newAdminFixture(t *testing.T) *adminFixture {
+	t.Helper()
+
+	f := &adminFixture{authFixture: newAuthFixture(t)}
+	f.service = NewAdminService(f.users, &fakeRoleService{}, f.tokens, f.audit)
+	return f
+}
+
+func TestAdminDisableUser(t *testing.T) {
+	f := newAdminFixture(t)
+	admin := f.createUser(t, "admin@example.com", "", models.RoleAdmin)
+	member := f.createUser(t, "member@example.com", "", models.RoleUser)
+
+	if _, err := f.service.DisableUser(admin.ID, member.ID); err != nil {
+		t.Fatalf("disabling a member: %v", err)
+	}
+	if stored, _ := f.users.GetByID(member.ID); !stored.IsDisabled() {
+		t.Errorf("member is still active")
+	}
+
+	if _, err := f.service.EnableUser(admin.ID, member.ID); err != nil {
+		t.Fatalf("enabling a member: %v", err)
+	}
+
+	var events []string
+	for _, event := range f.audit.events {
+		if event.ActorID == nil || *event.ActorID != admin.ID || event.UserID == nil || *event.UserID != member.ID {
+			t.Errorf("event %s has actor %v and user %v", event.Event, event.ActorID, event.UserID)
+		}
+		events = append(events, event.Event)
+	}
+	if len(events) != 2 || events[0] != models.AuditUserDisabled || events[1] != models.AuditUserEnabled {
+		t.Errorf("audit events = %v", events)
+	}
+}
+
+func TestAdminDisableUserRefusesAdministrators(t *testing.T) {
+	f := newAdminFixture(t)
+	admin := f.createUser(t, "admin@example.com", "", models.RoleAdmin)
+	other := f.createUser(t, "other@example.com", "", models.RoleAdmin)
+
+	if _, err := f.service.DisableUser(admin.ID, other.ID); !errors.Is(err, ErrCannotDisable) {
+		t.Fatalf("err = %v, want ErrCannotDisable", err)
+	}
+
+	// with the acting admin gone, the other one is the last active administrator
+	disabled, _ := f.users.GetByID(admin.ID)
+	disabled.DisabledAt = &disabled.CreatedAt
+	if err := f.users.Update(disabled); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.service.DisableUser(admin.ID, other.ID); !errors.Is(err, ErrLastAdmin) {
+		t.Fatalf("err = %v, want ErrLastAdmin", err)
+	}
+	if _, err := f.service.AssignRole(admin.ID, other.ID, models.RoleUser); !errors.Is(err, ErrLastAdmin) {
+		t.Fatalf("demoting: err = %v, want ErrLastAdmin", err)
+	}
+
+	if len(f.audit.events) != 0 {
+		t.Errorf("refused actions were audited: %v", f.audit.events)
+	}
+}
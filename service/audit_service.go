@@ -0,0 +1,28 @@
+package service
+
+import (
+	"log"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+)
+
+// AuditService records security events. Recording never fails the request that triggered it.
+type AuditService interface {
+	Record(event *models.AuditEvent)
+}
+
+type auditService struct {
+	auditRepo repository.AuditRepository
+}
+
+// Record implements AuditService.
+func (a *auditService) Record(event *models.AuditEvent) {
+	if err := a.auditRepo.Create(event); err != nil {
+		log.Printf("Failed to record audit event %s for %s: %v", event.Event, event.Email, err)
+	}
+}
+
+func NewAuditService(auditRepo repository.AuditRepository) AuditService {
+	return &auditService{auditRepo: auditRepo}
+}
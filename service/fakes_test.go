@@ -0,0 +1,502 @@
+package service
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/config"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/utils"
+)
+
+// testConfig installs a minimal configuration for the duration of a test
+func testConfig(t interface{ Cleanup(func()) }) *config.Config {
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{
+		JWTSecret:          "test-secret",
+		JWTIssuer:          "https://todo.example.com",
+		JWTAudience:        "https://todo.example.com",
+		AccessTokenMinutes: 15,
+		RefreshTokenDays:   7,
+		AppName:            "Todo",
+		AppBaseURL:         "https://todo.example.com",
+		WebAuthnRPID:       "todo.example.com",
+		WebAuthnRPName:     "Todo",
+		WebAuthnOrigins:    []string{"https://todo.example.com"},
+		// the cheapest Argon2 settings, tests only need the format
+		Argon2MemoryKB:    64,
+		Argon2Time:        1,
+		Argon2Parallelism: 1,
+	}
+	t.Cleanup(func() { config.AppConfig = previous })
+
+	return config.AppConfig
+}
+
+// authFixture holds the fakes the authentication services are built from
+type authFixture struct {
+	users         *fakeUserRepository
+	recoveryCodes *fakeRecoveryCodeRepository
+	tokens        *fakeTokenService
+	throttle      *fakeLoginThrottle
+	audit         *fakeAuditService
+	client        *models.ClientInfo
+}
+
+func newAuthFixture(t *testing.T) *authFixture {
+	t.Helper()
+	testConfig(t)
+
+	return &authFixture{
+		users:         newFakeUserRepository(),
+		recoveryCodes: newFakeRecoveryCodeRepository(),
+		tokens:        &fakeTokenService{},
+		throttle:      &fakeLoginThrottle{},
+		audit:         &fakeAuditService{},
+		client:        &models.ClientInfo{IP: "203.0.113.7", UserAgent: "test"},
+	}
+}
+
+// createUser stores a user named after the local part of email with role; an empty
+// password leaves the account without one, like after a social login
+func (f *authFixture) createUser(t *testing.T, email, password, role string) *models.User {
+	t.Helper()
+
+	username, _, _ := strings.Cut(email, "@")
+	user := &models.User{Email: email, Username: username, Role: role}
+	if password != "" {
+		if err := user.HashPassword(password); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.users.Create(user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// fakeUserRepository keeps users in memory; callers get copies like rows from a database
+type fakeUserRepository struct {
+	mu     sync.Mutex
+	users  map[uint]*models.User
+	nextID uint
+}
+
+func newFakeUserRepository() *fakeUserRepository {
+	return &fakeUserRepository{users: make(map[uint]*models.User)}
+}
+
+func (f *fakeUserRepository) Create(user *models.User) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.nextID++
+	user.ID = f.nextID
+	user.CreatedAt = time.Now()
+	stored := *user
+	f.users[user.ID] = &stored
+	return nil
+}
+
+func (f *fakeUserRepository) find(match func(*models.User) bool) (*models.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, user := range f.users {
+		if match(user) {
+			found := *user
+			return &found, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (f *fakeUserRepository) GetByID(id uint) (*models.User, error) {
+	return f.find(func(u *models.User) bool { return u.ID == id })
+}
+
+func (f *fakeUserRepository) GetByEmail(email string) (*models.User, error) {
+	return f.find(func(u *models.User) bool { return u.Email == email })
+}
+
+func (f *fakeUserRepository) GetByUsername(username string) (*models.User, error) {
+	return f.find(func(u *models.User) bool { return u.Username == username })
+}
+
+func (f *fakeUserRepository) GetByWebAuthnHandle(handle []byte) (*models.User, error) {
+	return f.find(func(u *models.User) bool { return len(u.WebAuthnHandle) > 0 && bytes.Equal(u.WebAuthnHandle, handle) })
+}
+
+func (f *fakeUserRepository) Update(user *models.User) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.users[user.ID]; !ok {
+		return repository.ErrUserNotFound
+	}
+	stored := *user
+	f.users[user.ID] = &stored
+	return nil
+}
+
+func (f *fakeUserRepository) Delete(id uint) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.users, id)
+	return nil
+}
+
+func (f *fakeUserRepository) UserExists(email string) (bool, error) {
+	_, err := f.GetByEmail(email)
+	return err == nil, nil
+}
+
+func (f *fakeUserRepository) GetAll(page, pageSize int) ([]models.User, int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	users := make([]models.User, 0, len(f.users))
+	for _, user := range f.users {
+		users = append(users, *user)
+	}
+	return users, int64(len(users)), nil
+}
+
+func (f *fakeUserRepository) AdvanceTOTPStep(id uint, step int64) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	user, ok := f.users[id]
+	if !ok {
+		return false, repository.ErrUserNotFound
+	}
+	if user.TOTPLastStep >= step {
+		return false, nil
+	}
+	user.TOTPLastStep = step
+	return true, nil
+}
+
+func (f *fakeUserRepository) ReplacePasswordHash(id uint, oldHash, newHash string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	user, ok := f.users[id]
+	if !ok || user.Password != oldHash {
+		return repository.ErrUserNotFound
+	}
+	user.Password = newHash
+	return nil
+}
+
+func (f *fakeUserRepository) CountByRole(role string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var count int64
+	for _, user := range f.users {
+		if user.Role == role {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *fakeUserRepository) CountActiveByRole(role string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var count int64
+	for _, user := range f.users {
+		if user.Role == role && !user.IsDisabled() {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// fakeTokenService hands out opaque tokens and remembers who got them
+type fakeTokenService struct {
+	issued    []uint
+	loggedOut []uint
+	spent     map[string]bool
+}
+
+func (f *fakeTokenService) IssueTokens(user *models.User, client *models.ClientInfo) (*models.AuthResponse, error) {
+	f.issued = append(f.issued, user.ID)
+
+	token, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuthResponse{AccessToken: token, User: user.ToResponse(), TokenType: "Bearer"}, nil
+}
+
+func (f *fakeTokenService) Refresh(refreshToken string, client *models.ClientInfo) (*models.AuthResponse, error) {
+	return nil, ErrInvalidRefreshToken
+}
+
+func (f *fakeTokenService) ValidateAccessToken(tokenString string) (*utils.Claims, error) {
+	return utils.ValidateToken(tokenString)
+}
+
+func (f *fakeTokenService) Logout(claims *utils.Claims, refreshToken string) error {
+	return nil
+}
+
+func (f *fakeTokenService) LogoutAll(userID uint) error {
+	f.loggedOut = append(f.loggedOut, userID)
+	return nil
+}
+
+func (f *fakeTokenService) ValidateMFAChallenge(tokenString string) (*utils.Claims, error) {
+	claims, err := utils.ValidateMFAChallengeToken(tokenString)
+	if err != nil || f.spent[claims.ID] {
+		return nil, ErrInvalidMFAToken
+	}
+	return claims, nil
+}
+
+func (f *fakeTokenService) ConsumeMFAChallenge(claims *utils.Claims) error {
+	if f.spent[claims.ID] {
+		return ErrInvalidMFAToken
+	}
+	if f.spent == nil {
+		f.spent = make(map[string]bool)
+	}
+	f.spent[claims.ID] = true
+	return nil
+}
+
+// fakeLoginThrottle counts the outcomes and only locks out when told to
+type fakeLoginThrottle struct {
+	failures  int
+	successes int
+	locked    bool
+}
+
+func (f *fakeLoginThrottle) Check(email, clientIP string) error {
+	if f.locked {
+		return &LoginThrottledError{RetryAfter: time.Minute}
+	}
+	return nil
+}
+
+func (f *fakeLoginThrottle) RecordFailure(email, clientIP string, user *models.User) error {
+	f.failures++
+	return nil
+}
+
+func (f *fakeLoginThrottle) RecordSuccess(email, clientIP string, user *models.User) error {
+	f.successes++
+	return nil
+}
+
+func (f *fakeLoginThrottle) Unlock(token string) error {
+	return nil
+}
+
+// fakeRecoveryCodeRepository stores the code hashes per user
+type fakeRecoveryCodeRepository struct {
+	hashes map[uint][]string
+}
+
+func newFakeRecoveryCodeRepository() *fakeRecoveryCodeRepository {
+	return &fakeRecoveryCodeRepository{hashes: make(map[uint][]string)}
+}
+
+func (f *fakeRecoveryCodeRepository) ReplaceForUser(userID uint, codeHashes []string) error {
+	f.hashes[userID] = append([]string(nil), codeHashes...)
+	return nil
+}
+
+func (f *fakeRecoveryCodeRepository) IsUnused(userID uint, codeHash string) (bool, error) {
+	for _, hash := range f.hashes[userID] {
+		if hash == codeHash {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeRecoveryCodeRepository) Consume(userID uint, codeHash string) error {
+	for i, hash := range f.hashes[userID] {
+		if hash == codeHash {
+			f.hashes[userID] = append(f.hashes[userID][:i], f.hashes[userID][i+1:]...)
+			return nil
+		}
+	}
+	return repository.ErrRecoveryCodeNotFound
+}
+
+func (f *fakeRecoveryCodeRepository) DeleteForUser(userID uint) error {
+	delete(f.hashes, userID)
+	return nil
+}
+
+// fakeAuditService keeps the recorded events
+type fakeAuditService struct {
+	events []models.AuditEvent
+}
+
+func (f *fakeAuditService) Record(event *models.AuditEvent) {
+	f.events = append(f.events, *event)
+}
+
+// fakeSessionRepository keeps sessions in memory
+type fakeSessionRepository struct {
+	sessions map[string]*models.Session
+}
+
+func (f *fakeSessionRepository) Create(session *models.Session) error {
+	stored := *session
+	stored.CreatedAt = time.Now()
+	f.sessions[session.ID] = &stored
+	return nil
+}
+
+func (f *fakeSessionRepository) GetByID(id string) (*models.Session, error) {
+	session, ok := f.sessions[id]
+	if !ok {
+		return nil, repository.ErrSessionNotFound
+	}
+	found := *session
+	return &found, nil
+}
+
+func (f *fakeSessionRepository) ListActiveByUser(userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	for _, session := range f.sessions {
+		if session.UserID == userID && session.IsActive(time.Now()) {
+			sessions = append(sessions, *session)
+		}
+	}
+	return sessions, nil
+}
+
+func (f *fakeSessionRepository) Touch(id, ip string, seenAt time.Time) error {
+	return nil
+}
+
+func (f *fakeSessionRepository) Extend(id, ip string, seenAt, expiresAt time.Time) error {
+	session, ok := f.sessions[id]
+	if !ok || session.RevokedAt != nil {
+		return repository.ErrSessionNotFound
+	}
+	session.ExpiresAt = expiresAt
+	return nil
+}
+
+func (f *fakeSessionRepository) Revoke(id string) error {
+	if session, ok := f.sessions[id]; ok && session.RevokedAt == nil {
+		now := time.Now()
+		session.RevokedAt = &now
+	}
+	return nil
+}
+
+func (f *fakeSessionRepository) RevokeAllForUser(userID uint) error {
+	for id, session := range f.sessions {
+		if session.UserID == userID {
+			_ = f.Revoke(id)
+		}
+	}
+	return nil
+}
+
+func (f *fakeSessionRepository) DeleteExpiredForUser(userID uint) error {
+	return nil
+}
+
+// fakeRefreshTokenRepository keeps refresh tokens in memory
+type fakeRefreshTokenRepository struct {
+	tokens []*models.RefreshToken
+}
+
+func (f *fakeRefreshTokenRepository) Create(token *models.RefreshToken) error {
+	token.ID = uint(len(f.tokens) + 1)
+	stored := *token
+	f.tokens = append(f.tokens, &stored)
+	return nil
+}
+
+func (f *fakeRefreshTokenRepository) GetByHash(tokenHash string) (*models.RefreshToken, error) {
+	for _, token := range f.tokens {
+		if token.TokenHash == tokenHash {
+			found := *token
+			return &found, nil
+		}
+	}
+	return nil, repository.ErrRefreshTokenNotFound
+}
+
+func (f *fakeRefreshTokenRepository) Rotate(current *models.RefreshToken, next *models.RefreshToken) error {
+	stored := f.tokens[current.ID-1]
+	if stored.UsedAt != nil || stored.RevokedAt != nil {
+		return repository.ErrRefreshTokenConsumed
+	}
+	if err := f.Create(next); err != nil {
+		return err
+	}
+	now := time.Now()
+	stored.UsedAt = &now
+	stored.ReplacedByID = &next.ID
+	return nil
+}
+
+func (f *fakeRefreshTokenRepository) RevokeFamily(familyID string) error {
+	return f.revokeWhere(func(token *models.RefreshToken) bool { return token.FamilyID == familyID })
+}
+
+func (f *fakeRefreshTokenRepository) RevokeAllForUser(userID uint) error {
+	return f.revokeWhere(func(token *models.RefreshToken) bool { return token.UserID == userID })
+}
+
+func (f *fakeRefreshTokenRepository) revokeWhere(match func(*models.RefreshToken) bool) error {
+	now := time.Now()
+	for _, token := range f.tokens {
+		if match(token) && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+// fakeRevokedTokenRepository keeps the revoked token IDs
+type fakeRevokedTokenRepository struct {
+	revoked map[string]bool
+}
+
+func (f *fakeRevokedTokenRepository) Create(token *models.RevokedToken) error {
+	f.revoked[token.JTI] = true
+	return nil
+}
+
+func (f *fakeRevokedTokenRepository) IsRevoked(jti string) (bool, error) {
+	return f.revoked[jti], nil
+}
+
+func (f *fakeRevokedTokenRepository) Consume(token *models.RevokedToken) (bool, error) {
+	if f.revoked[token.JTI] {
+		return false, nil
+	}
+	f.revoked[token.JTI] = true
+	return true, nil
+}
+
+func (f *fakeRevokedTokenRepository) DeleteExpired() error {
+	return nil
+}
+
+// newTestTokenService builds the real token and session services on in-memory repositories
+func newTestTokenService(users repository.UserRepository) TokenService {
+	refreshTokens := &fakeRefreshTokenRepository{}
+	sessions := NewSessionService(&fakeSessionRepository{sessions: make(map[string]*models.Session)}, refreshTokens)
+	return NewTokenService(users, refreshTokens, &fakeRevokedTokenRepository{revoked: make(map[string]bool)}, sessions)
+}
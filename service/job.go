@@ -0,0 +1,8 @@
+package service
+
+import "context"
+
+// Job is background work that runs until its context is cancelled
+type Job interface {
+	Run(ctx context.Context)
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/config"
+	"github.com/lieucongduy182/go-gin-todo-api/mailer"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/utils"
+)
+
+const (
+	accountUnlockPurpose = "account-unlock"
+
+	// failures older than this are forgotten
+	loginFailureWindow = time.Hour
+
+	// per-email backoff starts after this many consecutive failures and doubles from loginBackoffBase
+	loginBackoffFreeAttempts = 3
+	loginBackoffBase         = time.Second
+)
+
+var (
+	ErrTooManyLoginAttempts = errors.New("too many failed login attempts")
+	ErrInvalidUnlockToken   = errors.New("invalid or expired unlock link")
+)
+
+// LoginThrottledError is returned while an email or client IP must wait before trying again
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return ErrTooManyLoginAttempts.Error()
+}
+
+func (e *LoginThrottledError) Unwrap() error {
+	return ErrTooManyLoginAttempts
+}
+
+// LoginThrottleService tracks failed logins per email and per client IP.
+// Emails get exponential backoff and then a lockout with an unlock email;
+// client IPs are only locked out once they reach a much higher limit.
+type LoginThrottleService interface {
+	Check(email, clientIP string) error
+	// RecordFailure counts a failed attempt; user is nil when the email is unknown
+	RecordFailure(email, clientIP string, user *models.User) error
+	RecordSuccess(email, clientIP string, user *models.User) error
+	Unlock(token string) error
+}
+
+type loginThrottleService struct {
+	store        repository.LoginAttemptStore
+	auditService AuditService
+	mailer       mailer.Mailer
+
+	sweepMu   sync.Mutex
+	lastSweep time.Time
+}
+
+func emailThrottleKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(clientIP string) string {
+	return "ip:" + clientIP
+}
+
+func lockoutDuration() time.Duration {
+	return time.Duration(config.AppConfig.LoginLockoutMinutes) * time.Minute
+}
+
+// Check implements LoginThrottleService.
+func (l *loginThrottleService) Check(email, clientIP string) error {
+	now := time.Now()
+
+	var wait time.Duration
+	for _, key := range []string{emailThrottleKey(email), ipThrottleKey(clientIP)} {
+		attempt, err := l.store.Get(key)
+		if err != nil {
+			return err
+		}
+
+		if attempt.IsLocked(now) {
+			wait = max(wait, attempt.LockedUntil.Sub(now))
+		}
+	}
+
+	if wait == 0 {
+		return nil
+	}
+
+	l.auditService.Record(&models.AuditEvent{
+		Event:  models.AuditLoginThrottled,
+		Email:  email,
+		IP:     clientIP,
+		Detail: fmt.Sprintf("retry after %s", wait.Round(time.Second)),
+	})
+
+	return &LoginThrottledError{RetryAfter: wait}
+}
+
+// RecordFailure implements LoginThrottleService.
+func (l *loginThrottleService) RecordFailure(email, clientIP string, user *models.User) error {
+	now := time.Now()
+	resetBefore := now.Add(-loginFailureWindow)
+	l.sweep(resetBefore)
+
+	event := &models.AuditEvent{Event: models.AuditLoginFailed, Email: email, IP: clientIP}
+	if user != nil {
+		event.UserID = &user.ID
+	}
+	l.auditService.Record(event)
+
+	emailAttempt, err := l.store.RecordFailure(emailThrottleKey(email), now, resetBefore)
+	if err != nil {
+		return err
+	}
+
+	if emailAttempt.Failures >= config.AppConfig.LoginMaxFailures {
+		until := now.Add(lockoutDuration())
+		if err := l.store.Lock(emailAttempt.ThrottleKey, until); err != nil {
+			return err
+		}
+		l.onAccountLocked(email, clientIP, user, until)
+	} else if emailAttempt.Failures >= loginBackoffFreeAttempts {
+		if err := l.store.Lock(emailAttempt.ThrottleKey, now.Add(backoff(emailAttempt.Failures))); err != nil {
+			return err
+		}
+	}
+
+	ipAttempt, err := l.store.RecordFailure(ipThrottleKey(clientIP), now, resetBefore)
+	if err != nil {
+		return err
+	}
+
+	if ipAttempt.Failures >= config.AppConfig.LoginMaxIPFailures {
+		return l.store.Lock(ipAttempt.ThrottleKey, now.Add(lockoutDuration()))
+	}
+
+	return nil
+}
+
+// RecordSuccess implements LoginThrottleService.
+// Only the email counter is cleared: one valid account must not reset the budget of a noisy IP.
+func (l *loginThrottleService) RecordSuccess(email, clientIP string, user *models.User) error {
+	l.auditService.Record(&models.AuditEvent{
+		Event:  models.AuditLoginSucceeded,
+		UserID: &user.ID,
+		Email:  user.Email,
+		IP:     clientIP,
+	})
+
+	return l.store.Reset(emailThrottleKey(email))
+}
+
+// Unlock implements LoginThrottleService.
+// The link is bound to the lockout it was sent for, so it cannot be reused for a later one.
+func (l *loginThrottleService) Unlock(token string) error {
+	payload, err := utils.VerifySignedPayload(accountUnlockPurpose, token)
+	if err != nil {
+		return ErrInvalidUnlockToken
+	}
+
+	idx := strings.LastIndex(payload, ":")
+	if idx < 0 {
+		return ErrInvalidUnlockToken
+	}
+
+	email := payload[:idx]
+	lockedUntil, err := strconv.ParseInt(payload[idx+1:], 10, 64)
+	if err != nil {
+		return ErrInvalidUnlockToken
+	}
+
+	attempt, err := l.store.Get(emailThrottleKey(email))
+	if err != nil {
+		return err
+	}
+
+	if !attempt.IsLocked(time.Now()) || attempt.LockedUntil.Unix() != lockedUntil {
+		return ErrInvalidUnlockToken
+	}
+
+	if err := l.store.Reset(attempt.ThrottleKey); err != nil {
+		return err
+	}
+
+	l.auditService.Record(&models.AuditEvent{Event: models.AuditAccountUnlock, Email: email})
+	return nil
+}
+
+func (l *loginThrottleService) onAccountLocked(email, clientIP string, user *models.User, until time.Time) {
+	event := &models.AuditEvent{
+		Event:  models.AuditAccountLocked,
+		Email:  email,
+		IP:     clientIP,
+		Detail: "locked until " + until.UTC().Format(time.RFC3339),
+	}
+	if user != nil {
+		event.UserID = &user.ID
+	}
+	l.auditService.Record(event)
+
+	// unknown emails are locked too, but there is nobody to tell
+	if user == nil {
+		return
+	}
+
+	payload := strings.ToLower(strings.TrimSpace(email)) + ":" + strconv.FormatInt(until.Unix(), 10)
+	token := utils.SignPayload(accountUnlockPurpose, payload, until)
+
+	link := fmt.Sprintf("%s/api/v1/auth/unlock?token=%s", config.AppConfig.AppBaseURL, url.QueryEscape(token))
+	mailer.SendAsync(l.mailer, &mailer.Message{
+		To:      user.Email,
+		Subject: "Your account has been temporarily locked",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nWe locked sign-in to your account after several failed login attempts. "+
+				"It unlocks automatically in %d minutes.\n\nIf this was you, you can unlock it now:\n\n%s\n\n"+
+				"If it was not you, consider resetting your password.\n",
+			user.Username, config.AppConfig.LoginLockoutMinutes, link,
+		),
+	})
+}
+
+// sweep drops stale counters at most once per failure window
+func (l *loginThrottleService) sweep(before time.Time) {
+	l.sweepMu.Lock()
+	if time.Since(l.lastSweep) < loginFailureWindow {
+		l.sweepMu.Unlock()
+		return
+	}
+	l.lastSweep = time.Now()
+	l.sweepMu.Unlock()
+
+	if err := l.store.DeleteStale(before); err != nil {
+		log.Printf("Failed to clean up stale login attempts: %v", err)
+	}
+}
+
+// backoff is the wait imposed after the given number of consecutive failures, capped at the lockout
+func backoff(failures int) time.Duration {
+	shift := failures - loginBackoffFreeAttempts
+	if shift >= 20 {
+		return lockoutDuration()
+	}
+
+	return min(loginBackoffBase<<shift, lockoutDuration())
+}
+
+func NewLoginThrottleService(
+	store repository.LoginAttemptStore,
+	auditService AuditService,
+	mailer mailer.Mailer,
+) LoginThrottleService {
+	return &loginThrottleService{
+		store:        store,
+		auditService: auditService,
+		mailer:       mailer,
+		lastSweep:    time.Now(),
+	}
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/config"
+	"github.com/lieucongduy182/go-gin-todo-api/mailer"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/utils"
+)
+
+// magicLinkRateWindow is the period the per-email and per-IP link limits apply to
+const magicLinkRateWindow = time.Hour
+
+var (
+	ErrInvalidMagicLink  = errors.New("invalid or expired login link")
+	ErrTooManyMagicLinks = errors.New("too many login links requested")
+)
+
+// MagicLinkRateLimitedError is returned while a client IP has used up its link budget
+type MagicLinkRateLimitedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *MagicLinkRateLimitedError) Error() string {
+	return ErrTooManyMagicLinks.Error()
+}
+
+func (e *MagicLinkRateLimitedError) Unwrap() error {
+	return ErrTooManyMagicLinks
+}
+
+// MagicLinkService handles passwordless login through emailed single-use links
+type MagicLinkService interface {
+	RequestLink(req *models.MagicLinkRequest, client *models.ClientInfo) error
+	// CheckLink tells whether a link would log in, without using it up
+	CheckLink(token string) error
+	Login(token string, client *models.ClientInfo) (*models.AuthResponse, *models.MFAChallengeResponse, error)
+}
+
+type magicLinkService struct {
+	userRepo      repository.UserRepository
+	magicLinkRepo repository.MagicLinkRepository
+	tokenService  TokenService
+	mfaService    MFAService
+	loginThrottle LoginThrottleService
+	mailer        mailer.Mailer
+}
+
+func magicLinkTTL() time.Duration {
+	return time.Duration(config.AppConfig.MagicLinkMinutes) * time.Minute
+}
+
+// RequestLink implements MagicLinkService.
+// Unknown emails and emails over their limit are ignored silently, so the endpoint
+// reveals nothing about accounts; only the per-IP limit is reported to the caller.
+func (m *magicLinkService) RequestLink(req *models.MagicLinkRequest, client *models.ClientInfo) error {
+	now := time.Now()
+	since := now.Add(-magicLinkRateWindow)
+
+	count, oldest, err := m.magicLinkRepo.RecentByIP(client.IP, since)
+	if err != nil {
+		return err
+	}
+
+	if count >= int64(config.AppConfig.MagicLinkIPLimit) && oldest != nil {
+		return &MagicLinkRateLimitedError{RetryAfter: oldest.Add(magicLinkRateWindow).Sub(now)}
+	}
+
+	user, err := m.userRepo.GetByEmail(req.Email)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if user.IsDisabled() {
+		return nil
+	}
+
+	sent, err := m.magicLinkRepo.CountForUserSince(user.ID, since)
+	if err != nil {
+		return err
+	}
+
+	if sent >= int64(config.AppConfig.MagicLinkEmailLimit) {
+		return nil
+	}
+
+	// only the most recent link should work
+	if err := m.magicLinkRepo.InvalidateForUser(user.ID); err != nil {
+		return err
+	}
+
+	raw, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return err
+	}
+
+	if err := m.magicLinkRepo.Create(&models.MagicLinkToken{
+		UserID:      user.ID,
+		TokenHash:   utils.HashToken(raw),
+		RequestedIP: client.IP,
+		ExpiresAt:   now.Add(magicLinkTTL()),
+	}); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/api/v1/auth/magic-link/callback?token=%s", config.AppConfig.AppBaseURL, url.QueryEscape(raw))
+	mailer.SendAsync(m.mailer, &mailer.Message{
+		To:      user.Email,
+		Subject: "Your sign-in link",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse the link below to sign in to %s:\n\n%s\n\n"+
+				"The link works once and expires in %d minutes. It was requested from %s (%s). "+
+				"If you did not ask for it you can ignore this email.\n",
+			user.Username, config.AppConfig.AppName, link, config.AppConfig.MagicLinkMinutes, client.DeviceName, client.IP,
+		),
+	})
+
+	return nil
+}
+
+// CheckLink implements MagicLinkService.
+// Mail scanners and link previews open links too, so only Login may consume one.
+func (m *magicLinkService) CheckLink(token string) error {
+	record, err := m.magicLinkRepo.GetByHash(utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrMagicLinkNotFound) {
+			return ErrInvalidMagicLink
+		}
+		return err
+	}
+
+	if !record.IsUsable(time.Now()) {
+		return ErrInvalidMagicLink
+	}
+
+	return nil
+}
+
+// Login implements MagicLinkService.
+// Opening the link proves ownership of the address, so it also verifies the email.
+// Users with two-factor enabled still get an MFA challenge instead of tokens.
+func (m *magicLinkService) Login(token string, client *models.ClientInfo) (*models.AuthResponse, *models.MFAChallengeResponse, error) {
+	record, err := m.magicLinkRepo.GetByHash(utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrMagicLinkNotFound) {
+			return nil, nil, ErrInvalidMagicLink
+		}
+		return nil, nil, err
+	}
+
+	if err := m.magicLinkRepo.Consume(record.ID); err != nil {
+		if errors.Is(err, repository.ErrMagicLinkNotFound) {
+			return nil, nil, ErrInvalidMagicLink
+		}
+		return nil, nil, err
+	}
+
+	user, err := m.userRepo.GetByID(record.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, nil, ErrInvalidMagicLink
+		}
+		return nil, nil, err
+	}
+
+	if user.IsDisabled() {
+		return nil, nil, ErrAccountDisabled
+	}
+
+	if !user.IsEmailVerified() {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		if err := m.userRepo.Update(user); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	required, err := m.mfaService.RequiresSecondFactor(user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if required {
+		challenge, err := m.mfaService.NewChallenge(user)
+		return nil, challenge, err
+	}
+
+	if err := m.loginThrottle.RecordSuccess(user.Email, client.IP, user); err != nil {
+		return nil, nil, err
+	}
+
+	response, err := m.tokenService.IssueTokens(user, client)
+	return response, nil, err
+}
+
+func NewMagicLinkService(
+	userRepo repository.UserRepository,
+	magicLinkRepo repository.MagicLinkRepository,
+	tokenService TokenService,
+	mfaService MFAService,
+	loginThrottle LoginThrottleService,
+	mailer mailer.Mailer,
+) MagicLinkService {
+	return &magicLinkService{
+		userRepo:      userRepo,
+		magicLinkRepo: magicLinkRepo,
+		tokenService:  tokenService,
+		mfaService:    mfaService,
+		loginThrottle: loginThrottle,
+		mailer:        mailer,
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/utils"
+)
+
+// fakeMagicLinkRepository holds links that were already sent
+type fakeMagicLinkRepository struct {
+	repository.MagicLinkRepository
+	links []models.MagicLinkToken
+}
+
+func (f *fakeMagicLinkRepository) GetByHash(tokenHash string) (*models.MagicLinkToken, error) {
+	for i := range f.links {
+		if f.links[i].TokenHash == tokenHash {
+			link := f.links[i]
+			return &link, nil
+		}
+	}
+	return nil, repository.ErrMagicLinkNotFound
+}
+
+func (f *fakeMagicLinkRepository) Consume(id uint) error {
+	for i := range f.links {
+		if f.links[i].ID == id && f.links[i].IsUsable(time.Now()) {
+			now := time.Now()
+			f.links[i].UsedAt = &now
+			return nil
+		}
+	}
+	return repository.ErrMagicLinkNotFound
+}
+
+func TestMagicLinkIsOnlyUsedByLogin(t *testing.T) {
+	f := newAuthFixture(t)
+	user := f.createUser(t, "user@example.com", "", models.RoleUser)
+
+	links := &fakeMagicLinkRepository{links: []models.MagicLinkToken{
+		{ID: 1, UserID: user.ID, TokenHash: utils.HashToken("valid"), ExpiresAt: time.Now().Add(time.Minute)},
+		{ID: 2, UserID: user.ID, TokenHash: utils.HashToken("expired"), ExpiresAt: time.Now().Add(-time.Minute)},
+	}}
+	magicLinks := NewMagicLinkService(f.users, links, f.tokens, &fakeMFAService{}, f.throttle, nil)
+
+	// opening the link, by the user or by a mail scanner, leaves it usable
+	for i := 0; i < 2; i++ {
+		if err := magicLinks.CheckLink("valid"); err != nil {
+			t.Fatalf("CheckLink: %v", err)
+		}
+	}
+
+	if _, _, err := magicLinks.Login("valid", f.client); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if len(f.tokens.issued) != 1 {
+		t.Fatalf("issued tokens %d times, want once", len(f.tokens.issued))
+	}
+
+	if err := magicLinks.CheckLink("valid"); !errors.Is(err, ErrInvalidMagicLink) {
+		t.Errorf("used link: err = %v, want ErrInvalidMagicLink", err)
+	}
+	if _, _, err := magicLinks.Login("valid", f.client); !errors.Is(err, ErrInvalidMagicLink) {
+		t.Errorf("second login: err = %v, want ErrInvalidMagicLink", err)
+	}
+
+	for _, token := range []string{"expired", "unknown"} {
+		if err := magicLinks.CheckLink(token); !errors.Is(err, ErrInvalidMagicLink) {
+			t.Errorf("CheckLink(%q): err = %v, want ErrInvalidMagicLink", token, err)
+		}
+	}
+}
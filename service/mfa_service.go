@@ -0,0 +1,404 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/config"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/utils"
+	"github.com/skip2/go-qrcode"
+)
+
+const recoveryCodeCount = 10
+
+var (
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTOTPNotEnrolled    = errors.New("two-factor enrolment has not been started")
+	ErrTOTPNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrInvalidMFACode     = errors.New("invalid authentication code")
+	ErrInvalidMFAToken    = errors.New("invalid or expired mfa token")
+)
+
+// MFAService manages TOTP enrolment, recovery codes and the second login step.
+// Passkeys count as a second factor too; their ceremonies live in WebAuthnService.
+type MFAService interface {
+	EnrollTOTP(userID uint) (*models.TOTPEnrollmentResponse, error)
+	ConfirmTOTP(userID uint, code string, client *models.ClientInfo) (*models.RecoveryCodesResponse, error)
+	DisableTOTP(userID uint, req *models.DisableTOTPRequest, client *models.ClientInfo) error
+	RegenerateRecoveryCodes(userID uint, code string, client *models.ClientInfo) (*models.RecoveryCodesResponse, error)
+	RequiresSecondFactor(user *models.User) (bool, error)
+	NewChallenge(user *models.User) (*models.MFAChallengeResponse, error)
+	CompleteLogin(req *models.MFALoginRequest, client *models.ClientInfo) (*models.AuthResponse, error)
+}
+
+type mfaService struct {
+	userRepo         repository.UserRepository
+	recoveryCodeRepo repository.RecoveryCodeRepository
+	passkeyRepo      repository.WebAuthnCredentialRepository
+	tokenService     TokenService
+	loginThrottle    LoginThrottleService
+}
+
+// EnrollTOTP implements MFAService.
+// A fresh secret is generated on every call; it only becomes active after ConfirmTOTP.
+func (m *mfaService) EnrollTOTP(userID uint) (*models.TOTPEnrollmentResponse, error) {
+	user, err := m.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsTOTPEnabled() {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	uri := utils.TOTPURI(config.AppConfig.AppName, user.Email, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := utils.EncryptString(user.TOTPSecretContext(), secret)
+	if err != nil {
+		return nil, err
+	}
+
+	user.TOTPSecret = sealed
+	user.TOTPLastStep = 0
+	if err := m.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	return &models.TOTPEnrollmentResponse{
+		Secret:     secret,
+		OTPAuthURI: uri,
+		QRCodePNG:  base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+// ConfirmTOTP implements MFAService.
+func (m *mfaService) ConfirmTOTP(userID uint, code string, client *models.ClientInfo) (*models.RecoveryCodesResponse, error) {
+	user, err := m.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsTOTPEnabled() {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	if user.TOTPSecret == "" {
+		return nil, ErrTOTPNotEnrolled
+	}
+
+	if err := m.throttled(user, client, func() error { return m.checkTOTP(user, code) }); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user.TOTPEnabledAt = &now
+	if err := m.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	return issueRecoveryCodes(m.recoveryCodeRepo, user.ID)
+}
+
+// DisableTOTP implements MFAService.
+// Both the password and a current code (or recovery code) are required.
+// Recovery codes survive while passkeys still keep two-factor login on.
+func (m *mfaService) DisableTOTP(userID uint, req *models.DisableTOTPRequest, client *models.ClientInfo) error {
+	user, err := m.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if !user.IsTOTPEnabled() {
+		return ErrTOTPNotEnabled
+	}
+
+	err = m.throttled(user, client, func() error {
+		if err := user.CheckPassword(req.Password); err != nil {
+			return ErrIncorrectPassword
+		}
+
+		if err := m.checkTOTP(user, req.Code); err != nil {
+			if err := m.recoveryCodeRepo.Consume(user.ID, hashRecoveryCode(req.Code)); err != nil {
+				return ErrInvalidMFACode
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	user.TOTPSecret = ""
+	user.TOTPEnabledAt = nil
+	user.TOTPLastStep = 0
+	if err := m.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	passkeys, err := m.passkeyRepo.CountByUser(user.ID)
+	if err != nil {
+		return err
+	}
+
+	if passkeys > 0 {
+		return nil
+	}
+
+	return m.recoveryCodeRepo.DeleteForUser(user.ID)
+}
+
+// RegenerateRecoveryCodes implements MFAService.
+func (m *mfaService) RegenerateRecoveryCodes(userID uint, code string, client *models.ClientInfo) (*models.RecoveryCodesResponse, error) {
+	user, err := m.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsTOTPEnabled() {
+		return nil, ErrTOTPNotEnabled
+	}
+
+	if err := m.throttled(user, client, func() error { return m.checkTOTP(user, code) }); err != nil {
+		return nil, err
+	}
+
+	return issueRecoveryCodes(m.recoveryCodeRepo, user.ID)
+}
+
+// RequiresSecondFactor implements MFAService.
+// A second step is needed once the user has enabled TOTP or registered a passkey.
+func (m *mfaService) RequiresSecondFactor(user *models.User) (bool, error) {
+	if user.IsTOTPEnabled() {
+		return true, nil
+	}
+
+	passkeys, err := m.passkeyRepo.CountByUser(user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	return passkeys > 0, nil
+}
+
+// NewChallenge implements MFAService.
+func (m *mfaService) NewChallenge(user *models.User) (*models.MFAChallengeResponse, error) {
+	passkeys, err := m.passkeyRepo.CountByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var methods []string
+	if user.IsTOTPEnabled() {
+		methods = append(methods, "totp")
+	}
+	if passkeys > 0 {
+		methods = append(methods, "webauthn")
+	}
+	methods = append(methods, "recovery_code")
+
+	token, err := utils.GenerateMFAChallengeToken(user.ID, user.Email)
+	if err != nil {
+		return nil, errors.New("Failed to generate token")
+	}
+
+	return &models.MFAChallengeResponse{
+		MFARequired: true,
+		MFAToken:    token,
+		Methods:     methods,
+		ExpiresIn:   int(utils.MFAChallengeTTL.Seconds()),
+	}, nil
+}
+
+// CompleteLogin implements MFAService.
+// Wrong codes count as failed logins, so the second factor cannot be brute forced either.
+// The mfa_token is spent by the first successful attempt.
+func (m *mfaService) CompleteLogin(req *models.MFALoginRequest, client *models.ClientInfo) (*models.AuthResponse, error) {
+	claims, err := m.tokenService.ValidateMFAChallenge(req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := m.userRepo.GetByID(claims.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrInvalidMFAToken
+		}
+		return nil, err
+	}
+
+	required, err := m.RequiresSecondFactor(user)
+	if err != nil {
+		return nil, err
+	}
+
+	if !required {
+		return nil, ErrInvalidMFAToken
+	}
+
+	if user.IsDisabled() {
+		return nil, ErrAccountDisabled
+	}
+
+	if err := m.loginThrottle.Check(user.Email, client.IP); err != nil {
+		return nil, err
+	}
+
+	if req.Code != "" && !user.IsTOTPEnabled() {
+		// passkey-only accounts have no TOTP secret to check against
+		err = ErrInvalidMFACode
+	} else if req.Code != "" {
+		err = m.checkTOTP(user, req.Code)
+	} else {
+		var unused bool
+		if unused, err = m.recoveryCodeRepo.IsUnused(user.ID, hashRecoveryCode(req.RecoveryCode)); err == nil && !unused {
+			err = ErrInvalidMFACode
+		}
+	}
+
+	if errors.Is(err, ErrInvalidMFACode) {
+		if err := m.loginThrottle.RecordFailure(user.Email, client.IP, user); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidMFACode
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the challenge is spent first, so a request with a used challenge cannot burn a recovery code
+	if err := m.tokenService.ConsumeMFAChallenge(claims); err != nil {
+		return nil, err
+	}
+
+	if req.Code == "" {
+		if err := m.recoveryCodeRepo.Consume(user.ID, hashRecoveryCode(req.RecoveryCode)); err != nil {
+			if errors.Is(err, repository.ErrRecoveryCodeNotFound) {
+				// used by another login since it was checked
+				return nil, ErrInvalidMFACode
+			}
+			return nil, err
+		}
+	}
+
+	if err := m.loginThrottle.RecordSuccess(user.Email, client.IP, user); err != nil {
+		return nil, err
+	}
+
+	return m.tokenService.IssueTokens(user, client)
+}
+
+// throttled runs check under the login throttle of the signed in user.
+// Wrong codes and passwords count as failed logins, so a stolen session cannot guess them either.
+func (m *mfaService) throttled(user *models.User, client *models.ClientInfo, check func() error) error {
+	if err := m.loginThrottle.Check(user.Email, client.IP); err != nil {
+		return err
+	}
+
+	err := check()
+	if errors.Is(err, ErrInvalidMFACode) || errors.Is(err, ErrIncorrectPassword) {
+		if err := m.loginThrottle.RecordFailure(user.Email, client.IP, user); err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+// checkTOTP validates code and consumes its time step so it cannot be replayed
+func (m *mfaService) checkTOTP(user *models.User, code string) error {
+	secret, err := utils.DecryptString(user.TOTPSecretContext(), user.TOTPSecret)
+	if err != nil {
+		return err
+	}
+
+	step, ok := utils.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	advanced, err := m.userRepo.AdvanceTOTPStep(user.ID, step)
+	if err != nil {
+		return err
+	}
+
+	if !advanced {
+		return ErrInvalidMFACode
+	}
+
+	// keep the in-memory copy in sync so a later Update does not roll the step back
+	user.TOTPLastStep = step
+	return nil
+}
+
+// issueRecoveryCodes replaces the user's recovery codes with a fresh set
+func issueRecoveryCodes(recoveryCodeRepo repository.RecoveryCodeRepository, userID uint) (*models.RecoveryCodesResponse, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	if err := recoveryCodeRepo.ReplaceForUser(userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return &models.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// generateRecoveryCode returns a code formatted as xxxxx-xxxxx for readability
+func generateRecoveryCode() (string, error) {
+	buf := make([]byte, 7)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	raw := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf))[:10]
+	return raw[:5] + "-" + raw[5:], nil
+}
+
+// hashRecoveryCode normalises user input before hashing so dashes and case do not matter
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.TrimSpace(code))
+	normalized = strings.NewReplacer("-", "", " ", "").Replace(normalized)
+
+	return utils.HashToken(normalized)
+}
+
+func NewMFAService(
+	userRepo repository.UserRepository,
+	recoveryCodeRepo repository.RecoveryCodeRepository,
+	passkeyRepo repository.WebAuthnCredentialRepository,
+	tokenService TokenService,
+	loginThrottle LoginThrottleService,
+) MFAService {
+	return &mfaService{
+		userRepo:         userRepo,
+		recoveryCodeRepo: recoveryCodeRepo,
+		passkeyRepo:      passkeyRepo,
+		tokenService:     tokenService,
+		loginThrottle:    loginThrottle,
+	}
+}
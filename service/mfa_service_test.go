@@ -0,0 +1,214 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/utils"
+)
+
+// totpCode computes the current RFC 6238 code for a base32 secret, like an authenticator app
+func totpCode(t *testing.T, secret string) string {
+	t.Helper()
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(time.Now().Unix()/30))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:offset+4])&0x7fffffff)%1_000_000)
+}
+
+type mfaFixture struct {
+	*authFixture
+	service MFAService
+	user    *models.User
+}
+
+func newMFAFixture(t *testing.T) *mfaFixture {
+	t.Helper()
+
+	f := &mfaFixture{authFixture: newAuthFixture(t)}
+	f.service = NewMFAService(f.users, f.recoveryCodes, &fakePasskeyRepository{}, f.tokens, f.throttle)
+	f.user = f.createUser(t, "user@example.com", "correct horse battery", models.RoleUser)
+	return f
+}
+
+// enable enrols and confirms TOTP, returning the recovery codes
+func (f *mfaFixture) enable(t *testing.T) []string {
+	t.Helper()
+
+	enrollment, err := f.service.EnrollTOTP(f.user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	codes, err := f.service.ConfirmTOTP(f.user.ID, totpCode(t, enrollment.Secret), f.client)
+	if err != nil {
+		t.Fatalf("ConfirmTOTP: %v", err)
+	}
+
+	return codes.RecoveryCodes
+}
+
+func TestMFAStoresSecretEncrypted(t *testing.T) {
+	f := newMFAFixture(t)
+
+	enrollment, err := f.service.EnrollTOTP(f.user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, _ := f.users.GetByID(f.user.ID)
+	if !utils.IsEncrypted(stored.TOTPSecret) {
+		t.Fatalf("TOTP secret is stored as %q", stored.TOTPSecret)
+	}
+
+	// the ciphertext is bound to its user and cannot be moved to another row
+	other := &models.User{ID: f.user.ID + 1}
+	if _, err := utils.DecryptString(other.TOTPSecretContext(), stored.TOTPSecret); err == nil {
+		t.Errorf("secret decrypted for another user")
+	}
+
+	if _, err := f.service.ConfirmTOTP(f.user.ID, totpCode(t, enrollment.Secret), f.client); err != nil {
+		t.Errorf("ConfirmTOTP with the enrolled secret: %v", err)
+	}
+}
+
+func TestMFACodeChecksAreThrottled(t *testing.T) {
+	f := newMFAFixture(t)
+
+	if _, err := f.service.EnrollTOTP(f.user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.service.ConfirmTOTP(f.user.ID, "000000", f.client); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("wrong code: err = %v, want ErrInvalidMFACode", err)
+	}
+	if f.throttle.failures != 1 {
+		t.Errorf("wrong confirmation code recorded %d failures, want 1", f.throttle.failures)
+	}
+
+	f.throttle.locked = true
+	var throttled *LoginThrottledError
+	if _, err := f.service.ConfirmTOTP(f.user.ID, "000000", f.client); !errors.As(err, &throttled) {
+		t.Errorf("locked out: err = %v, want LoginThrottledError", err)
+	}
+	f.throttle.locked = false
+
+	f.enable(t)
+	failures := f.throttle.failures
+
+	tests := []struct {
+		name string
+		req  models.DisableTOTPRequest
+		want error
+	}{
+		{name: "wrong password", req: models.DisableTOTPRequest{Password: "wrong", Code: "000000"}, want: ErrIncorrectPassword},
+		{name: "wrong code", req: models.DisableTOTPRequest{Password: "correct horse battery", Code: "000000"}, want: ErrInvalidMFACode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := f.service.DisableTOTP(f.user.ID, &tt.req, f.client); !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+
+			failures++
+			if f.throttle.failures != failures {
+				t.Errorf("recorded %d failures, want %d", f.throttle.failures, failures)
+			}
+		})
+	}
+
+	if _, err := f.service.RegenerateRecoveryCodes(f.user.ID, "000000", f.client); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("regenerating with a wrong code: err = %v, want ErrInvalidMFACode", err)
+	}
+	if f.throttle.failures != failures+1 {
+		t.Errorf("wrong code for recovery codes was not counted")
+	}
+}
+
+func TestMFAChallengeIsSingleUse(t *testing.T) {
+	f := newMFAFixture(t)
+	recoveryCodes := f.enable(t)
+
+	challenge, err := f.service.NewChallenge(f.user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.service.CompleteLogin(&models.MFALoginRequest{
+		MFAToken:     challenge.MFAToken,
+		RecoveryCode: recoveryCodes[0],
+	}, f.client); err != nil {
+		t.Fatalf("CompleteLogin: %v", err)
+	}
+
+	_, err = f.service.CompleteLogin(&models.MFALoginRequest{
+		MFAToken:     challenge.MFAToken,
+		RecoveryCode: recoveryCodes[1],
+	}, f.client)
+	if !errors.Is(err, ErrInvalidMFAToken) {
+		t.Fatalf("reused mfa token: err = %v, want ErrInvalidMFAToken", err)
+	}
+
+	if len(f.tokens.issued) != 1 {
+		t.Errorf("issued tokens %d times, want once", len(f.tokens.issued))
+	}
+}
+
+// racingTokenService accepts a challenge that a concurrent login is about to spend
+type racingTokenService struct {
+	*fakeTokenService
+}
+
+func (r *racingTokenService) ValidateMFAChallenge(tokenString string) (*utils.Claims, error) {
+	return utils.ValidateMFAChallengeToken(tokenString)
+}
+
+func TestMFALoginWithSpentChallengeKeepsRecoveryCode(t *testing.T) {
+	f := newMFAFixture(t)
+	recoveryCodes := f.enable(t)
+
+	challenge, err := f.service.NewChallenge(f.user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := utils.ValidateMFAChallengeToken(challenge.MFAToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.tokens.ConsumeMFAChallenge(claims); err != nil {
+		t.Fatal(err)
+	}
+
+	racing := NewMFAService(f.users, f.recoveryCodes, &fakePasskeyRepository{}, &racingTokenService{f.tokens}, f.throttle)
+	_, err = racing.CompleteLogin(&models.MFALoginRequest{
+		MFAToken:     challenge.MFAToken,
+		RecoveryCode: recoveryCodes[0],
+	}, f.client)
+	if !errors.Is(err, ErrInvalidMFAToken) {
+		t.Fatalf("spent mfa token: err = %v, want ErrInvalidMFAToken", err)
+	}
+
+	if unused, _ := f.recoveryCodes.IsUnused(f.user.ID, hashRecoveryCode(recoveryCodes[0])); !unused {
+		t.Errorf("the rejected login used up its recovery code")
+	}
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+)
+
+// maxInboxNotifications caps how many notifications one listing returns
+const maxInboxNotifications = 100
+
+type NotificationService interface {
+	ListNotifications(userID uint, unreadOnly bool) ([]models.Notification, int64, error)
+	MarkRead(notificationID, userID uint) error
+	MarkAllRead(userID uint) error
+}
+
+type notificationService struct {
+	notificationRepo repository.NotificationRepository
+}
+
+// ListNotifications implements NotificationService.
+// Returns the newest notifications together with the number of unread ones.
+func (n *notificationService) ListNotifications(userID uint, unreadOnly bool) ([]models.Notification, int64, error) {
+	notifications, err := n.notificationRepo.ListByUser(userID, unreadOnly, maxInboxNotifications)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	unread, err := n.notificationRepo.CountUnread(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return notifications, unread, nil
+}
+
+// MarkRead implements NotificationService.
+func (n *notificationService) MarkRead(notificationID uint, userID uint) error {
+	return n.notificationRepo.MarkRead(notificationID, userID)
+}
+
+// MarkAllRead implements NotificationService.
+func (n *notificationService) MarkAllRead(userID uint) error {
+	return n.notificationRepo.MarkAllRead(userID)
+}
+
+func NewNotificationService(notificationRepo repository.NotificationRepository) NotificationService {
+	return &notificationService{notificationRepo: notificationRepo}
+}
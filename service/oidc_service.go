@@ -0,0 +1,356 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/lieucongduy182/go-gin-todo-api/config"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/utils"
+	"golang.org/x/oauth2"
+)
+
+const (
+	oidcStatePurpose = "oidc-state"
+
+	// OIDCStateTTL bounds how long a user may spend on the provider's login page
+	OIDCStateTTL = 10 * time.Minute
+
+	oidcHTTPTimeout = 10 * time.Second
+)
+
+var (
+	ErrOIDCProviderNotFound = errors.New("unknown sign-in provider")
+	ErrInvalidOIDCState     = errors.New("invalid or expired sign-in state")
+	ErrOIDCAuthFailed       = errors.New("sign-in with the provider failed")
+	ErrOIDCEmailNotVerified = errors.New("the provider did not return a verified email address")
+	ErrOIDCAccountNotLinked = errors.New("an account with this email exists but its email is not verified; sign in with your password and verify it first")
+)
+
+var usernameDisallowedChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// OIDCAuthRequest starts a login at the provider. State must be handed back to
+// CompleteLogin unchanged, typically through a short-lived cookie.
+type OIDCAuthRequest struct {
+	URL   string
+	State string
+}
+
+// OIDCService implements the authorization code flow with PKCE against the configured providers
+type OIDCService interface {
+	Providers() []string
+	StartLogin(provider string) (*OIDCAuthRequest, error)
+	CompleteLogin(
+		ctx context.Context,
+		provider, code, state, storedState string,
+		clientInfo *models.ClientInfo,
+	) (*models.AuthResponse, *models.MFAChallengeResponse, error)
+}
+
+type oidcClient struct {
+	oauth    *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+type oidcClaims struct {
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+type oidcService struct {
+	userRepo     repository.UserRepository
+	identityRepo repository.UserIdentityRepository
+	tokenService TokenService
+	mfaService   MFAService
+	auditService AuditService
+
+	mu      sync.Mutex
+	clients map[string]*oidcClient
+}
+
+// Providers implements OIDCService.
+func (o *oidcService) Providers() []string {
+	names := make([]string, 0, len(config.AppConfig.OIDCProviders))
+	for _, provider := range config.AppConfig.OIDCProviders {
+		names = append(names, provider.Name)
+	}
+
+	return names
+}
+
+// StartLogin implements OIDCService.
+func (o *oidcService) StartLogin(provider string) (*OIDCAuthRequest, error) {
+	client, err := o.client(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := utils.GenerateRandomToken(24)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := utils.GenerateRandomToken(24)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier := oauth2.GenerateVerifier()
+
+	url := client.oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+	payload := strings.Join([]string{provider, state, nonce, verifier}, "|")
+
+	return &OIDCAuthRequest{
+		URL:   url,
+		State: utils.SignPayload(oidcStatePurpose, payload, time.Now().Add(OIDCStateTTL)),
+	}, nil
+}
+
+// CompleteLogin implements OIDCService.
+// Users with two-factor enabled still get an MFA challenge instead of tokens.
+func (o *oidcService) CompleteLogin(
+	ctx context.Context,
+	provider, code, state, storedState string,
+	clientInfo *models.ClientInfo,
+) (*models.AuthResponse, *models.MFAChallengeResponse, error) {
+	client, err := o.client(provider)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	payload, err := utils.VerifySignedPayload(oidcStatePurpose, storedState)
+	if err != nil {
+		return nil, nil, ErrInvalidOIDCState
+	}
+
+	parts := strings.Split(payload, "|")
+	if len(parts) != 4 || parts[0] != provider || subtle.ConstantTimeCompare([]byte(parts[1]), []byte(state)) != 1 {
+		return nil, nil, ErrInvalidOIDCState
+	}
+	nonce, verifier := parts[2], parts[3]
+
+	ctx = oidc.ClientContext(ctx, &http.Client{Timeout: oidcHTTPTimeout})
+	token, err := client.oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrOIDCAuthFailed, err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: no id_token in token response", ErrOIDCAuthFailed)
+	}
+
+	idToken, err := client.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrOIDCAuthFailed, err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(nonce)) != 1 {
+		return nil, nil, fmt.Errorf("%w: nonce mismatch", ErrOIDCAuthFailed)
+	}
+
+	var claims oidcClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrOIDCAuthFailed, err)
+	}
+
+	user, err := o.resolveUser(provider, idToken.Subject, &claims)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if user.IsDisabled() {
+		return nil, nil, ErrAccountDisabled
+	}
+
+	required, err := o.mfaService.RequiresSecondFactor(user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if required {
+		challenge, err := o.mfaService.NewChallenge(user)
+		return nil, challenge, err
+	}
+
+	o.auditService.Record(&models.AuditEvent{
+		Event:  models.AuditLoginSucceeded,
+		UserID: &user.ID,
+		Email:  user.Email,
+		IP:     clientInfo.IP,
+		Detail: "oidc:" + provider,
+	})
+
+	response, err := o.tokenService.IssueTokens(user, clientInfo)
+	return response, nil, err
+}
+
+// resolveUser finds the user behind an external identity, linking it to an existing
+// account with the same verified email or creating a new account on first sign-in.
+func (o *oidcService) resolveUser(provider, subject string, claims *oidcClaims) (*models.User, error) {
+	now := time.Now()
+
+	identity, err := o.identityRepo.GetByProviderSubject(provider, subject)
+	if err == nil {
+		if err := o.identityRepo.TouchLastLogin(identity.ID, now); err != nil {
+			return nil, err
+		}
+		return o.userRepo.GetByID(identity.UserID)
+	}
+	if !errors.Is(err, repository.ErrUserIdentityNotFound) {
+		return nil, err
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	user, err := o.userRepo.GetByEmail(claims.Email)
+	switch {
+	case err == nil:
+		// linking to an account whose owner never proved the address would hand it to
+		// whoever registered it first
+		if !user.IsEmailVerified() {
+			return nil, ErrOIDCAccountNotLinked
+		}
+	case errors.Is(err, repository.ErrUserNotFound):
+		if user, err = o.createUser(claims, now); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if err := o.identityRepo.Create(&models.UserIdentity{
+		UserID:      user.ID,
+		Provider:    provider,
+		Subject:     subject,
+		Email:       claims.Email,
+		LastLoginAt: &now,
+	}); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// createUser registers an account without a password; the user can set one through the reset flow
+func (o *oidcService) createUser(claims *oidcClaims, now time.Time) (*models.User, error) {
+	username, err := o.availableUsername(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Username:        username,
+		Email:           claims.Email,
+		EmailVerifiedAt: &now,
+	}
+
+	if err := o.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (o *oidcService) availableUsername(claims *oidcClaims) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	base = usernameDisallowedChars.ReplaceAllString(base, "")
+	if len(base) > 40 {
+		base = base[:40]
+	}
+	if len(base) < 3 {
+		base = "user"
+	}
+
+	candidate := base
+	for attempt := 0; attempt < 5; attempt++ {
+		if _, err := o.userRepo.GetByUsername(candidate); errors.Is(err, repository.ErrUserNotFound) {
+			return candidate, nil
+		} else if err != nil {
+			return "", err
+		}
+
+		suffix, err := utils.GenerateRandomToken(3)
+		if err != nil {
+			return "", err
+		}
+		candidate = base + "_" + strings.ToLower(usernameDisallowedChars.ReplaceAllString(suffix, ""))
+	}
+
+	return "", ErrUsernameTaken
+}
+
+// client discovers the provider on first use, so an unreachable provider does not stop the API from starting
+func (o *oidcService) client(name string) (*oidcClient, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if client, ok := o.clients[name]; ok {
+		return client, nil
+	}
+
+	var cfg *config.OIDCProviderConfig
+	for i := range config.AppConfig.OIDCProviders {
+		if config.AppConfig.OIDCProviders[i].Name == name {
+			cfg = &config.AppConfig.OIDCProviders[i]
+			break
+		}
+	}
+	if cfg == nil {
+		return nil, ErrOIDCProviderNotFound
+	}
+
+	// the context outlives this call: the provider keeps using it to refresh signing keys
+	ctx := oidc.ClientContext(context.Background(), &http.Client{Timeout: oidcHTTPTimeout})
+	provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("discover oidc provider %s: %w", name, err)
+	}
+
+	client := &oidcClient{
+		oauth: &oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       cfg.Scopes,
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+	}
+	o.clients[name] = client
+
+	return client, nil
+}
+
+func NewOIDCService(
+	userRepo repository.UserRepository,
+	identityRepo repository.UserIdentityRepository,
+	tokenService TokenService,
+	mfaService MFAService,
+	auditService AuditService,
+) OIDCService {
+	return &oidcService{
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		tokenService: tokenService,
+		mfaService:   mfaService,
+		auditService: auditService,
+		clients:      make(map[string]*oidcClient),
+	}
+}
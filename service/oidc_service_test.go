@@ -0,0 +1,440 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/lieucongduy182/go-gin-todo-api/config"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+)
+
+const (
+	mockOIDCClientID     = "todo-api"
+	mockOIDCClientSecret = "todo-api-secret"
+)
+
+// mockOIDCProvider is an OpenID provider with discovery, JWKS and token endpoints.
+// Tests authorize a login with a set of claims and get the code the browser would bring back.
+type mockOIDCProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	codes  map[string]mockOIDCGrant
+	issued int
+}
+
+type mockOIDCGrant struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &mockOIDCProvider{t: t, key: key, codes: make(map[string]mockOIDCGrant)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("GET /jwks", p.jwks)
+	mux.HandleFunc("POST /token", p.token)
+
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+
+	return p
+}
+
+func (p *mockOIDCProvider) issuer() string {
+	return p.server.URL
+}
+
+func (p *mockOIDCProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                p.issuer(),
+		"authorization_endpoint":                p.issuer() + "/authorize",
+		"token_endpoint":                        p.issuer() + "/token",
+		"jwks_uri":                              p.issuer() + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (p *mockOIDCProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "mock",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+// token exchanges a code for an ID token after checking the client and the PKCE verifier
+func (p *mockOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	clientID, secret, ok := r.BasicAuth()
+	if !ok {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != mockOIDCClientID || secret != mockOIDCClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	p.mu.Lock()
+	grant, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != grant.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, grant.claims)
+	idToken.Header["kid"] = "mock"
+	signed, err := idToken.SignedString(p.key)
+	if err != nil {
+		p.t.Error(err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": "mock-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     signed,
+	})
+}
+
+// authorize plays the user logging in at the provider: it reads the authorization URL
+// built by StartLogin and returns the code and state the callback receives.
+// Claims override the defaults; the nonce is echoed unless the claims set one.
+func (p *mockOIDCProvider) authorize(authURL string, claims jwt.MapClaims) (code, state string) {
+	p.t.Helper()
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	query := parsed.Query()
+
+	if query.Get("client_id") != mockOIDCClientID || query.Get("code_challenge_method") != "S256" {
+		p.t.Fatalf("unexpected authorization request %s", authURL)
+	}
+
+	now := time.Now()
+	grant := jwt.MapClaims{
+		"iss":   p.issuer(),
+		"aud":   mockOIDCClientID,
+		"sub":   "subject-1",
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": query.Get("nonce"),
+	}
+	for name, value := range claims {
+		grant[name] = value
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.issued++
+	code = fmt.Sprintf("code-%d", p.issued)
+	p.codes[code] = mockOIDCGrant{challenge: query.Get("code_challenge"), claims: grant}
+
+	return code, query.Get("state")
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+type fakeIdentityRepository struct {
+	identities []models.UserIdentity
+}
+
+func (f *fakeIdentityRepository) Create(identity *models.UserIdentity) error {
+	identity.ID = uint(len(f.identities) + 1)
+	f.identities = append(f.identities, *identity)
+	return nil
+}
+
+func (f *fakeIdentityRepository) GetByProviderSubject(provider, subject string) (*models.UserIdentity, error) {
+	for _, identity := range f.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return &identity, nil
+		}
+	}
+	return nil, repository.ErrUserIdentityNotFound
+}
+
+func (f *fakeIdentityRepository) TouchLastLogin(id uint, at time.Time) error {
+	return nil
+}
+
+// fakeMFAService only answers the questions the login flows ask
+type fakeMFAService struct {
+	MFAService
+	required bool
+}
+
+func (f *fakeMFAService) RequiresSecondFactor(user *models.User) (bool, error) {
+	return f.required, nil
+}
+
+func (f *fakeMFAService) NewChallenge(user *models.User) (*models.MFAChallengeResponse, error) {
+	return &models.MFAChallengeResponse{MFARequired: true, MFAToken: "challenge", Methods: []string{"totp"}}, nil
+}
+
+type oidcFixture struct {
+	*authFixture
+	service    OIDCService
+	provider   *mockOIDCProvider
+	identities *fakeIdentityRepository
+	mfa        *fakeMFAService
+}
+
+func newOIDCFixture(t *testing.T) *oidcFixture {
+	t.Helper()
+
+	auth := newAuthFixture(t)
+	cfg := config.AppConfig
+	provider := newMockOIDCProvider(t)
+
+	// two providers backed by the same mock, to tell a callback for the wrong one apart
+	for _, name := range []string{"mock", "other"} {
+		cfg.OIDCProviders = append(cfg.OIDCProviders, config.OIDCProviderConfig{
+			Name:         name,
+			Issuer:       provider.issuer(),
+			ClientID:     mockOIDCClientID,
+			ClientSecret: mockOIDCClientSecret,
+			Scopes:       []string{"openid", "email", "profile"},
+			RedirectURL:  cfg.AppBaseURL + "/api/v1/auth/oidc/" + name + "/callback",
+		})
+	}
+
+	f := &oidcFixture{
+		authFixture: auth,
+		provider:    provider,
+		identities:  &fakeIdentityRepository{},
+		mfa:         &fakeMFAService{},
+	}
+	f.service = NewOIDCService(f.users, f.identities, f.tokens, f.mfa, f.audit)
+
+	return f
+}
+
+// login runs StartLogin, the provider round trip with claims and CompleteLogin
+func (f *oidcFixture) login(t *testing.T, claims jwt.MapClaims) (*models.AuthResponse, *models.MFAChallengeResponse, error) {
+	t.Helper()
+
+	start, err := f.service.StartLogin("mock")
+	if err != nil {
+		t.Fatalf("StartLogin: %v", err)
+	}
+
+	code, state := f.provider.authorize(start.URL, claims)
+	return f.service.CompleteLogin(context.Background(), "mock", code, state, start.State, f.client)
+}
+
+func TestOIDCCompleteLoginRejectsForeignState(t *testing.T) {
+	f := newOIDCFixture(t)
+
+	start, err := f.service.StartLogin("mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state := f.provider.authorize(start.URL, jwt.MapClaims{"email": "alice@example.com", "email_verified": true})
+
+	other, err := f.service.StartLogin("mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, provider, state, storedState string
+	}{
+		{name: "state of another login", provider: "mock", state: state, storedState: other.State},
+		{name: "tampered state", provider: "mock", state: "forged", storedState: start.State},
+		{name: "missing cookie", provider: "mock", state: state, storedState: ""},
+		{name: "callback of another provider", provider: "other", state: state, storedState: start.State},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := f.service.CompleteLogin(context.Background(), tt.provider, code, tt.state, tt.storedState, f.client)
+			if !errors.Is(err, ErrInvalidOIDCState) {
+				t.Fatalf("err = %v, want ErrInvalidOIDCState", err)
+			}
+		})
+	}
+
+	if _, _, err := f.service.CompleteLogin(context.Background(), "unknown", code, state, start.State, f.client); !errors.Is(err, ErrOIDCProviderNotFound) {
+		t.Errorf("unknown provider: err = %v, want ErrOIDCProviderNotFound", err)
+	}
+	if len(f.tokens.issued) != 0 {
+		t.Errorf("tokens were issued for %v", f.tokens.issued)
+	}
+}
+
+func TestOIDCCompleteLogin(t *testing.T) {
+	verified := time.Now().Add(-24 * time.Hour)
+
+	tests := []struct {
+		name     string
+		existing *models.User
+		claims   jwt.MapClaims
+		wantErr  error
+		// wantUser is the username tokens are issued for
+		wantUser string
+	}{
+		{
+			name:    "nonce mismatch",
+			claims:  jwt.MapClaims{"email": "alice@example.com", "email_verified": true, "nonce": "replayed"},
+			wantErr: ErrOIDCAuthFailed,
+		},
+		{
+			name:    "token from another issuer",
+			claims:  jwt.MapClaims{"email": "alice@example.com", "email_verified": true, "iss": "https://evil.example.com"},
+			wantErr: ErrOIDCAuthFailed,
+		},
+		{
+			name:    "unverified email",
+			claims:  jwt.MapClaims{"email": "alice@example.com", "email_verified": false},
+			wantErr: ErrOIDCEmailNotVerified,
+		},
+		{
+			name:    "no email",
+			claims:  jwt.MapClaims{},
+			wantErr: ErrOIDCEmailNotVerified,
+		},
+		{
+			name:     "links to a verified existing account",
+			existing: &models.User{Username: "alice", Email: "alice@example.com", Password: "hash", EmailVerifiedAt: &verified},
+			claims:   jwt.MapClaims{"email": "alice@example.com", "email_verified": true, "preferred_username": "ally"},
+			wantUser: "alice",
+		},
+		{
+			name:     "refuses an unverified existing account",
+			existing: &models.User{Username: "alice", Email: "alice@example.com", Password: "hash"},
+			claims:   jwt.MapClaims{"email": "alice@example.com", "email_verified": true},
+			wantErr:  ErrOIDCAccountNotLinked,
+		},
+		{
+			name:     "creates an account on first login",
+			claims:   jwt.MapClaims{"email": "carol@example.com", "email_verified": true, "preferred_username": "carol.c"},
+			wantUser: "carol.c",
+		},
+		{
+			name:     "picks another username when the preferred one is taken",
+			existing: &models.User{Username: "carol", Email: "someone@example.com", Password: "hash"},
+			claims:   jwt.MapClaims{"email": "carol@example.com", "email_verified": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOIDCFixture(t)
+			if tt.existing != nil {
+				if err := f.users.Create(tt.existing); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			auth, _, err := f.login(t, tt.claims)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				if len(f.identities.identities) != 0 || len(f.tokens.issued) != 0 {
+					t.Errorf("a failed login linked %d identities and issued %d tokens", len(f.identities.identities), len(f.tokens.issued))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CompleteLogin: %v", err)
+			}
+
+			user, err := f.users.GetByEmail(tt.claims["email"].(string))
+			if err != nil {
+				t.Fatalf("no account for the provider's email: %v", err)
+			}
+			if auth.User.ID != user.ID {
+				t.Errorf("tokens issued for user %d, want %d", auth.User.ID, user.ID)
+			}
+			if tt.wantUser != "" && user.Username != tt.wantUser {
+				t.Errorf("username = %q, want %q", user.Username, tt.wantUser)
+			}
+			if tt.existing != nil && user.Username == tt.existing.Username && user.ID != tt.existing.ID {
+				t.Errorf("new account reused the username %q", user.Username)
+			}
+			if !user.IsEmailVerified() {
+				t.Errorf("account email is not verified")
+			}
+
+			if len(f.identities.identities) != 1 || f.identities.identities[0].UserID != user.ID {
+				t.Fatalf("identities = %+v, want one linked to user %d", f.identities.identities, user.ID)
+			}
+
+			// the next login goes through the linked identity, even with a changed email
+			again, _, err := f.login(t, jwt.MapClaims{"email": "renamed@example.com", "email_verified": true})
+			if err != nil {
+				t.Fatalf("second login: %v", err)
+			}
+			if again.User.ID != user.ID || len(f.identities.identities) != 1 {
+				t.Errorf("second login resolved user %d with %d identities", again.User.ID, len(f.identities.identities))
+			}
+		})
+	}
+}
+
+func TestOIDCCompleteLoginRequiresSecondFactor(t *testing.T) {
+	f := newOIDCFixture(t)
+	f.mfa.required = true
+
+	auth, challenge, err := f.login(t, jwt.MapClaims{"email": "alice@example.com", "email_verified": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth != nil || challenge == nil || !challenge.MFARequired {
+		t.Fatalf("got tokens %v and challenge %v, want only a challenge", auth, challenge)
+	}
+	if len(f.tokens.issued) != 0 {
+		t.Errorf("tokens were issued before the second factor")
+	}
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/config"
+	"github.com/lieucongduy182/go-gin-todo-api/mailer"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/utils"
+)
+
+var ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+
+// PasswordResetService handles the forgot/reset password flow
+type PasswordResetService interface {
+	RequestReset(req *models.ForgotPasswordRequest) error
+	ResetPassword(req *models.ResetPasswordRequest) error
+}
+
+type passwordResetService struct {
+	userRepo     repository.UserRepository
+	resetRepo    repository.PasswordResetRepository
+	tokenService TokenService
+	mailer       mailer.Mailer
+}
+
+// RequestReset implements PasswordResetService.
+// Unknown emails are ignored silently so the endpoint cannot be used to discover accounts.
+func (p *passwordResetService) RequestReset(req *models.ForgotPasswordRequest) error {
+	user, err := p.userRepo.GetByEmail(req.Email)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	// only the most recent link should work
+	if err := p.resetRepo.InvalidateForUser(user.ID); err != nil {
+		return err
+	}
+
+	raw, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Duration(config.AppConfig.PasswordResetMinutes) * time.Minute
+	if err := p.resetRepo.Create(&models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(raw),
+		ExpiresAt: time.Now().Add(ttl),
+	}); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", config.AppConfig.AppBaseURL, url.QueryEscape(raw))
+	mailer.SendAsync(p.mailer, &mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nWe received a request to reset your password. Use the link below to choose a new one:\n\n%s\n\n"+
+				"The link expires in %d minutes. If you did not request a reset you can ignore this email.\n",
+			user.Username, link, config.AppConfig.PasswordResetMinutes,
+		),
+	})
+
+	return nil
+}
+
+// ResetPassword implements PasswordResetService.
+// A successful reset logs the user out of every existing session.
+func (p *passwordResetService) ResetPassword(req *models.ResetPasswordRequest) error {
+	token, err := p.resetRepo.GetByHash(utils.HashToken(req.Token))
+	if err != nil {
+		if errors.Is(err, repository.ErrResetTokenNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	user, err := p.userRepo.GetByID(token.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	if err := utils.ValidatePassword(req.NewPassword, user.Username, user.Email); err != nil {
+		return err
+	}
+
+	// the token is only spent once the new password is acceptable
+	if err := p.resetRepo.Consume(token.ID); err != nil {
+		if errors.Is(err, repository.ErrResetTokenNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	if err := user.HashPassword(req.NewPassword); err != nil {
+		return errors.New("failed to hash password")
+	}
+
+	if err := p.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	return p.tokenService.LogoutAll(user.ID)
+}
+
+func NewPasswordResetService(
+	userRepo repository.UserRepository,
+	resetRepo repository.PasswordResetRepository,
+	tokenService TokenService,
+	mailer mailer.Mailer,
+) PasswordResetService {
+	return &passwordResetService{
+		userRepo:     userRepo,
+		resetRepo:    resetRepo,
+		tokenService: tokenService,
+		mailer:       mailer,
+	}
+}
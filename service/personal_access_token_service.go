@@ -0,0 +1,142 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/utils"
+)
+
+// lastUsedResolution limits how often last_used_at is written for a busy token
+const lastUsedResolution = time.Minute
+
+var ErrInvalidPersonalAccessToken = errors.New("invalid, expired or revoked personal access token")
+
+// PersonalAccessTokenService manages scoped tokens for scripts and integrations
+type PersonalAccessTokenService interface {
+	Create(userID uint, req *models.CreatePersonalAccessTokenRequest) (*models.CreatedPersonalAccessTokenResponse, error)
+	List(userID uint) ([]models.PersonalAccessTokenResponse, error)
+	Revoke(userID, tokenID uint) error
+	Authenticate(rawToken string) (*models.PersonalAccessToken, *models.User, error)
+}
+
+type personalAccessTokenService struct {
+	userRepo repository.UserRepository
+	patRepo  repository.PersonalAccessTokenRepository
+}
+
+// Create implements PersonalAccessTokenService.
+func (p *personalAccessTokenService) Create(userID uint, req *models.CreatePersonalAccessTokenRequest) (*models.CreatedPersonalAccessTokenResponse, error) {
+	secret, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	raw := models.PATPrefix + secret
+
+	token := &models.PersonalAccessToken{
+		UserID:      userID,
+		Name:        req.Name,
+		TokenHash:   utils.HashToken(raw),
+		TokenPrefix: raw[:len(models.PATPrefix)+6],
+		Scopes:      strings.Join(uniqueScopes(req.Scopes), " "),
+	}
+
+	if req.ExpiresInDays != nil {
+		expiresAt := time.Now().Add(time.Duration(*req.ExpiresInDays) * 24 * time.Hour)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := p.patRepo.Create(token); err != nil {
+		return nil, err
+	}
+
+	return &models.CreatedPersonalAccessTokenResponse{
+		Token: raw,
+		PAT:   token.ToResponse(),
+	}, nil
+}
+
+// List implements PersonalAccessTokenService.
+func (p *personalAccessTokenService) List(userID uint) ([]models.PersonalAccessTokenResponse, error) {
+	tokens, err := p.patRepo.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.PersonalAccessTokenResponse, 0, len(tokens))
+	for _, token := range tokens {
+		responses = append(responses, token.ToResponse())
+	}
+
+	return responses, nil
+}
+
+// Revoke implements PersonalAccessTokenService.
+func (p *personalAccessTokenService) Revoke(userID uint, tokenID uint) error {
+	return p.patRepo.Revoke(tokenID, userID)
+}
+
+// Authenticate implements PersonalAccessTokenService.
+func (p *personalAccessTokenService) Authenticate(rawToken string) (*models.PersonalAccessToken, *models.User, error) {
+	token, err := p.patRepo.GetByHash(utils.HashToken(rawToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrPersonalAccessTokenNotFound) {
+			return nil, nil, ErrInvalidPersonalAccessToken
+		}
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	if !token.IsActive(now) {
+		return nil, nil, ErrInvalidPersonalAccessToken
+	}
+
+	user, err := p.userRepo.GetByID(token.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, nil, ErrInvalidPersonalAccessToken
+		}
+		return nil, nil, err
+	}
+
+	if user.IsDisabled() {
+		return nil, nil, ErrInvalidPersonalAccessToken
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= lastUsedResolution {
+		if err := p.patRepo.TouchLastUsed(token.ID, now); err != nil {
+			// tracking is best effort and must not block the request
+			log.Printf("Failed to record last use of personal access token %d: %v", token.ID, err)
+		}
+	}
+
+	return token, user, nil
+}
+
+func uniqueScopes(scopes []string) []string {
+	seen := make(map[string]bool, len(scopes))
+	result := make([]string, 0, len(scopes))
+
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+
+	return result
+}
+
+func NewPersonalAccessTokenService(
+	userRepo repository.UserRepository,
+	patRepo repository.PersonalAccessTokenRepository,
+) PersonalAccessTokenService {
+	return &personalAccessTokenService{
+		userRepo: userRepo,
+		patRepo:  patRepo,
+	}
+}
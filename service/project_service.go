@@ -0,0 +1,156 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+)
+
+var (
+	ErrProjectArchived          = errors.New("project is archived")
+	ErrInvalidProjectName       = errors.New("project name must not be blank")
+	ErrInvalidProjectDeleteMode = errors.New("mode must be 'inbox' or 'cascade'")
+)
+
+type ProjectService interface {
+	ListProjects(userID uint, includeArchived bool) ([]models.ProjectResponse, error)
+	GetProject(projectID, userID uint) (*models.ProjectResponse, error)
+	CreateProject(userID uint, req *models.CreateProjectRequest) (*models.ProjectResponse, error)
+	UpdateProject(projectID, userID uint, req *models.UpdateProjectRequest) (*models.ProjectResponse, error)
+	DeleteProject(projectID, userID uint, mode string) error
+}
+
+type projectService struct {
+	projectRepo repository.ProjectRepository
+}
+
+// checkProjectAcceptsTasks verifies that projectID is one of the user's projects and is not archived.
+// A nil projectID stands for the inbox, which always accepts tasks.
+func checkProjectAcceptsTasks(projectRepo repository.ProjectRepository, userID uint, projectID *uint) error {
+	if projectID == nil {
+		return nil
+	}
+
+	project, err := projectRepo.GetByID(*projectID, userID)
+	if err != nil {
+		return err
+	}
+
+	if project.Archived {
+		return ErrProjectArchived
+	}
+
+	return nil
+}
+
+// ListProjects implements ProjectService.
+func (p *projectService) ListProjects(userID uint, includeArchived bool) ([]models.ProjectResponse, error) {
+	projects, err := p.projectRepo.ListWithCounts(userID, includeArchived)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.ProjectResponse, 0, len(projects))
+	for _, project := range projects {
+		responses = append(responses, project.ToResponse())
+	}
+
+	return responses, nil
+}
+
+// GetProject implements ProjectService.
+func (p *projectService) GetProject(projectID uint, userID uint) (*models.ProjectResponse, error) {
+	project, err := p.projectRepo.GetByID(projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := project.ToResponse()
+	return &response, nil
+}
+
+// CreateProject implements ProjectService.
+// Without an explicit sort_order the project is appended after the existing ones.
+func (p *projectService) CreateProject(userID uint, req *models.CreateProjectRequest) (*models.ProjectResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrInvalidProjectName
+	}
+
+	project := &models.Project{UserID: userID, Name: name, Color: strings.ToLower(req.Color)}
+	if project.Color == "" {
+		project.Color = models.DefaultProjectColor
+	}
+
+	if req.SortOrder != nil {
+		project.SortOrder = *req.SortOrder
+	} else {
+		next, err := p.projectRepo.NextSortOrder(userID)
+		if err != nil {
+			return nil, err
+		}
+		project.SortOrder = next
+	}
+
+	if err := p.projectRepo.Create(project); err != nil {
+		return nil, err
+	}
+
+	response := project.ToResponse()
+	return &response, nil
+}
+
+// UpdateProject implements ProjectService.
+func (p *projectService) UpdateProject(projectID uint, userID uint, req *models.UpdateProjectRequest) (*models.ProjectResponse, error) {
+	project, err := p.projectRepo.GetByID(projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" {
+		name := strings.TrimSpace(req.Name)
+		if name == "" {
+			return nil, ErrInvalidProjectName
+		}
+		project.Name = name
+	}
+
+	if req.Color != "" {
+		project.Color = strings.ToLower(req.Color)
+	}
+
+	if req.Archived != nil {
+		project.Archived = *req.Archived
+	}
+
+	if req.SortOrder != nil {
+		project.SortOrder = *req.SortOrder
+	}
+
+	if err := p.projectRepo.Update(project); err != nil {
+		return nil, err
+	}
+
+	response := project.ToResponse()
+	return &response, nil
+}
+
+// DeleteProject implements ProjectService.
+// The project's tasks move to the inbox unless mode is ProjectDeleteCascade.
+func (p *projectService) DeleteProject(projectID uint, userID uint, mode string) error {
+	switch mode {
+	case "":
+		mode = models.ProjectDeleteMoveToInbox
+	case models.ProjectDeleteMoveToInbox, models.ProjectDeleteCascade:
+	default:
+		return ErrInvalidProjectDeleteMode
+	}
+
+	return p.projectRepo.Delete(projectID, userID, mode)
+}
+
+func NewProjectService(projectRepo repository.ProjectRepository) ProjectService {
+	return &projectService{projectRepo: projectRepo}
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/notifier"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+)
+
+// Retry backoff for failed deliveries: 1m, 4m, 16m, ... capped at maxReminderBackoff
+const (
+	baseReminderBackoff = time.Minute
+	maxReminderBackoff  = 6 * time.Hour
+)
+
+// ReminderScheduler polls for due reminders and delivers them through the notifier of their channel.
+// Every replica may run one; claims are exclusive, so a reminder is delivered by a single replica.
+type ReminderScheduler struct {
+	reminderRepo    repository.ReminderRepository
+	taskRepo        repository.TaskRepository
+	userRepo        repository.UserRepository
+	notifiers       map[string]notifier.Notifier
+	interval        time.Duration
+	batchSize       int
+	maxAttempts     int
+	deliveryTimeout time.Duration
+}
+
+func NewReminderScheduler(
+	reminderRepo repository.ReminderRepository,
+	taskRepo repository.TaskRepository,
+	userRepo repository.UserRepository,
+	notifiers map[string]notifier.Notifier,
+	interval time.Duration,
+	batchSize int,
+	maxAttempts int,
+	deliveryTimeout time.Duration,
+) *ReminderScheduler {
+	return &ReminderScheduler{
+		reminderRepo:    reminderRepo,
+		taskRepo:        taskRepo,
+		userRepo:        userRepo,
+		notifiers:       notifiers,
+		interval:        interval,
+		batchSize:       batchSize,
+		maxAttempts:     maxAttempts,
+		deliveryTimeout: deliveryTimeout,
+	}
+}
+
+// Run implements Job.
+func (s *ReminderScheduler) Run(ctx context.Context) {
+	log.Printf("Reminder scheduler started, polling every %s", s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Print("Reminder scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll delivers batches until no due reminders are left
+func (s *ReminderScheduler) poll(ctx context.Context) {
+	// the lease must outlast delivering a whole batch, or another replica could claim the rest again
+	lease := time.Duration(s.batchSize)*s.deliveryTimeout + time.Minute
+
+	for ctx.Err() == nil {
+		reminders, err := s.reminderRepo.ClaimDue(time.Now(), lease, s.batchSize)
+		if err != nil {
+			log.Printf("Failed to claim due reminders: %v", err)
+			return
+		}
+
+		for i := range reminders {
+			s.deliver(ctx, &reminders[i])
+		}
+
+		if len(reminders) < s.batchSize {
+			return
+		}
+	}
+}
+
+// deliver sends one claimed reminder and records the outcome
+func (s *ReminderScheduler) deliver(ctx context.Context, reminder *models.Reminder) {
+	if ctx.Err() != nil {
+		s.release(reminder)
+		return
+	}
+
+	notification, err := s.buildNotification(reminder)
+	if err != nil {
+		if errors.Is(err, errReminderObsolete) {
+			reminder.Status = models.ReminderStatusCancelled
+			s.finish(reminder, nil)
+			return
+		}
+
+		log.Printf("Failed to load reminder %d: %v", reminder.ID, err)
+		s.release(reminder)
+		return
+	}
+
+	channel, ok := s.notifiers[reminder.Channel]
+	if !ok {
+		err = errors.New("no notifier for channel " + reminder.Channel)
+	} else {
+		deliveryCtx, cancel := context.WithTimeout(ctx, s.deliveryTimeout)
+		err = channel.Notify(deliveryCtx, notification)
+		cancel()
+	}
+
+	// interrupted by shutdown: not the receiver's fault, try again after restart
+	if err != nil && ctx.Err() != nil {
+		s.release(reminder)
+		return
+	}
+
+	delivery := &models.ReminderDelivery{
+		ReminderID: reminder.ID,
+		Attempt:    reminder.Attempts,
+		Channel:    reminder.Channel,
+		Success:    err == nil,
+	}
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		reminder.Status = models.ReminderStatusSent
+		reminder.SentAt = &now
+		reminder.LastError = ""
+	case reminder.Attempts >= s.maxAttempts:
+		reminder.Status = models.ReminderStatusFailed
+		reminder.LastError = err.Error()
+		delivery.Error = err.Error()
+	default:
+		reminder.Status = models.ReminderStatusPending
+		reminder.FireAt = now.Add(reminderBackoff(reminder.Attempts))
+		reminder.LastError = err.Error()
+		delivery.Error = err.Error()
+	}
+
+	s.finish(reminder, delivery)
+}
+
+var errReminderObsolete = errors.New("reminder no longer applies")
+
+// buildNotification loads what the notifiers need; reminders of completed or deleted tasks
+// and of disabled users are obsolete
+func (s *ReminderScheduler) buildNotification(reminder *models.Reminder) (*notifier.Notification, error) {
+	task, err := s.taskRepo.GetById(reminder.TaskID, reminder.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTaskNotFound) {
+			return nil, errReminderObsolete
+		}
+		return nil, err
+	}
+
+	if task.Completed {
+		return nil, errReminderObsolete
+	}
+
+	user, err := s.userRepo.GetByID(reminder.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, errReminderObsolete
+		}
+		return nil, err
+	}
+
+	if user.IsDisabled() {
+		return nil, errReminderObsolete
+	}
+
+	return &notifier.Notification{
+		ReminderID: reminder.ID,
+		UserID:     user.ID,
+		Email:      user.Email,
+		TaskID:     task.ID,
+		TaskTitle:  task.Title,
+		DueDate:    task.DueDate,
+		WebhookURL: reminder.WebhookURL,
+	}, nil
+}
+
+func (s *ReminderScheduler) finish(reminder *models.Reminder, delivery *models.ReminderDelivery) {
+	if err := s.reminderRepo.Finish(reminder, delivery); err != nil {
+		log.Printf("Failed to record outcome of reminder %d: %v", reminder.ID, err)
+	}
+}
+
+func (s *ReminderScheduler) release(reminder *models.Reminder) {
+	if err := s.reminderRepo.Release(reminder); err != nil {
+		log.Printf("Failed to release reminder %d: %v", reminder.ID, err)
+	}
+}
+
+// reminderBackoff is the wait before retrying after the given number of failed attempts
+func reminderBackoff(attempts int) time.Duration {
+	backoff := baseReminderBackoff
+	for i := 1; i < attempts && backoff < maxReminderBackoff; i++ {
+		backoff *= 4
+	}
+
+	return min(backoff, maxReminderBackoff)
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+)
+
+var (
+	ErrInvalidReminderTime  = errors.New("give either remind_at or minutes_before")
+	ErrReminderNeedsDueDate = errors.New("minutes_before needs a task with a due date")
+	ErrInvalidWebhookURL    = errors.New("webhook_url must be an http or https URL")
+)
+
+type ReminderService interface {
+	ListReminders(taskID, userID uint) ([]models.ReminderResponse, error)
+	CreateReminder(taskID, userID uint, req *models.CreateReminderRequest) (*models.ReminderResponse, error)
+	DeleteReminder(taskID, reminderID, userID uint) error
+}
+
+type reminderService struct {
+	taskRepo     repository.TaskRepository
+	reminderRepo repository.ReminderRepository
+}
+
+// ListReminders implements ReminderService.
+func (r *reminderService) ListReminders(taskID uint, userID uint) ([]models.ReminderResponse, error) {
+	if _, err := r.taskRepo.GetById(taskID, userID); err != nil {
+		return nil, err
+	}
+
+	reminders, err := r.reminderRepo.ListByTask(taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.ReminderResponse, 0, len(reminders))
+	for _, reminder := range reminders {
+		responses = append(responses, reminder.ToResponse())
+	}
+
+	return responses, nil
+}
+
+// CreateReminder implements ReminderService.
+// A reminder relative to the due date follows the task when its due date changes.
+func (r *reminderService) CreateReminder(taskID uint, userID uint, req *models.CreateReminderRequest) (*models.ReminderResponse, error) {
+	task, err := r.taskRepo.GetById(taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if (req.RemindAt == nil) == (req.MinutesBefore == nil) {
+		return nil, ErrInvalidReminderTime
+	}
+
+	reminder := &models.Reminder{
+		UserID:        userID,
+		TaskID:        task.ID,
+		Channel:       req.Channel,
+		RemindAt:      req.RemindAt,
+		MinutesBefore: req.MinutesBefore,
+		Status:        models.ReminderStatusPending,
+	}
+
+	if req.RemindAt != nil {
+		reminder.FireAt = *req.RemindAt
+	} else {
+		if task.DueDate == nil {
+			return nil, ErrReminderNeedsDueDate
+		}
+		reminder.FireAt = task.DueDate.Add(-time.Duration(*req.MinutesBefore) * time.Minute)
+	}
+
+	if req.Channel == models.ReminderChannelWebhook {
+		target, err := url.Parse(req.WebhookURL)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return nil, ErrInvalidWebhookURL
+		}
+		reminder.WebhookURL = req.WebhookURL
+	}
+
+	if err := r.reminderRepo.Create(reminder); err != nil {
+		return nil, err
+	}
+
+	response := reminder.ToResponse()
+	return &response, nil
+}
+
+// DeleteReminder implements ReminderService.
+func (r *reminderService) DeleteReminder(taskID uint, reminderID uint, userID uint) error {
+	return r.reminderRepo.Delete(reminderID, taskID, userID)
+}
+
+func NewReminderService(taskRepo repository.TaskRepository, reminderRepo repository.ReminderRepository) ReminderService {
+	return &reminderService{
+		taskRepo:     taskRepo,
+		reminderRepo: reminderRepo,
+	}
+}
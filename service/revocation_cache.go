@@ -0,0 +1,104 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// revocationCacheTTL bounds how long a "not revoked" answer is trusted.
+// Revocations made by other replicas become visible after at most this long.
+const revocationCacheTTL = 30 * time.Second
+
+type revocationEntry struct {
+	revoked   bool
+	expiresAt time.Time
+}
+
+type cutoffEntry struct {
+	cutoff    *time.Time
+	expiresAt time.Time
+}
+
+// revocationCache is the in-process layer in front of the revoked_tokens table
+type revocationCache struct {
+	mu        sync.RWMutex
+	tokens    map[string]revocationEntry
+	cutoffs   map[uint]cutoffEntry
+	lastSweep time.Time
+}
+
+func newRevocationCache() *revocationCache {
+	return &revocationCache{
+		tokens:    make(map[string]revocationEntry),
+		cutoffs:   make(map[uint]cutoffEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+func (rc *revocationCache) getToken(jti string) (bool, bool) {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+
+	entry, ok := rc.tokens[jti]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return false, false
+	}
+
+	return entry.revoked, true
+}
+
+// setToken caches a lookup result. Revoked tokens are kept until the token itself expires.
+func (rc *revocationCache) setToken(jti string, revoked bool, tokenExpiresAt time.Time) {
+	expiresAt := time.Now().Add(revocationCacheTTL)
+	if revoked {
+		expiresAt = tokenExpiresAt
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.tokens[jti] = revocationEntry{revoked: revoked, expiresAt: expiresAt}
+	rc.sweepLocked()
+}
+
+func (rc *revocationCache) getCutoff(userID uint) (*time.Time, bool) {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+
+	entry, ok := rc.cutoffs[userID]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+
+	return entry.cutoff, true
+}
+
+func (rc *revocationCache) setCutoff(userID uint, cutoff *time.Time) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.cutoffs[userID] = cutoffEntry{cutoff: cutoff, expiresAt: time.Now().Add(revocationCacheTTL)}
+	rc.sweepLocked()
+}
+
+// sweepLocked drops stale entries at most once per TTL; callers must hold the write lock
+func (rc *revocationCache) sweepLocked() {
+	now := time.Now()
+	if now.Sub(rc.lastSweep) < revocationCacheTTL {
+		return
+	}
+
+	for jti, entry := range rc.tokens {
+		if now.After(entry.expiresAt) {
+			delete(rc.tokens, jti)
+		}
+	}
+
+	for userID, entry := range rc.cutoffs {
+		if now.After(entry.expiresAt) {
+			delete(rc.cutoffs, userID)
+		}
+	}
+
+	rc.lastSweep = now
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+)
+
+// roleCacheTTL bounds how long permission changes made on another replica take to apply
+const roleCacheTTL = 30 * time.Second
+
+var (
+	ErrRoleAlreadyExists = errors.New("role already exists")
+	ErrRoleInUse         = errors.New("role is still assigned to users")
+	ErrBuiltinRole       = errors.New("built-in roles cannot be modified")
+)
+
+// RoleService manages custom roles and answers permission checks
+type RoleService interface {
+	ListRoles() ([]models.RoleResponse, error)
+	CreateRole(req *models.CreateRoleRequest) (*models.RoleResponse, error)
+	UpdateRole(name string, req *models.UpdateRoleRequest) (*models.RoleResponse, error)
+	DeleteRole(name string) error
+	RoleExists(name string) (bool, error)
+	HasPermissions(role string, permissions ...string) (bool, error)
+}
+
+type roleService struct {
+	roleRepo repository.RoleRepository
+	userRepo repository.UserRepository
+
+	mu       sync.RWMutex
+	cache    map[string][]string
+	loadedAt time.Time
+}
+
+// ListRoles implements RoleService.
+func (r *roleService) ListRoles() ([]models.RoleResponse, error) {
+	roles, err := r.roleRepo.List()
+	if err != nil {
+		return nil, err
+	}
+
+	responses := []models.RoleResponse{
+		{Name: models.RoleUser, Permissions: models.BuiltinRolePermissions[models.RoleUser], Builtin: true},
+		{Name: models.RoleAdmin, Permissions: models.BuiltinRolePermissions[models.RoleAdmin], Builtin: true},
+	}
+	for _, role := range roles {
+		responses = append(responses, role.ToResponse())
+	}
+
+	return responses, nil
+}
+
+// CreateRole implements RoleService.
+func (r *roleService) CreateRole(req *models.CreateRoleRequest) (*models.RoleResponse, error) {
+	if models.IsBuiltinRole(req.Name) {
+		return nil, ErrRoleAlreadyExists
+	}
+
+	if _, err := r.roleRepo.GetByName(req.Name); err == nil {
+		return nil, ErrRoleAlreadyExists
+	} else if !errors.Is(err, repository.ErrRoleNotFound) {
+		return nil, err
+	}
+
+	role := &models.Role{Name: req.Name, Description: req.Description}
+	role.SetPermissions(req.Permissions)
+
+	if err := r.roleRepo.Create(role); err != nil {
+		return nil, err
+	}
+	r.invalidate()
+
+	response := role.ToResponse()
+	return &response, nil
+}
+
+// UpdateRole implements RoleService.
+func (r *roleService) UpdateRole(name string, req *models.UpdateRoleRequest) (*models.RoleResponse, error) {
+	if models.IsBuiltinRole(name) {
+		return nil, ErrBuiltinRole
+	}
+
+	role, err := r.roleRepo.GetByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Description != nil {
+		role.Description = *req.Description
+	}
+
+	if req.Permissions != nil {
+		role.SetPermissions(req.Permissions)
+	}
+
+	if err := r.roleRepo.Update(role); err != nil {
+		return nil, err
+	}
+	r.invalidate()
+
+	response := role.ToResponse()
+	return &response, nil
+}
+
+// DeleteRole implements RoleService.
+func (r *roleService) DeleteRole(name string) error {
+	if models.IsBuiltinRole(name) {
+		return ErrBuiltinRole
+	}
+
+	count, err := r.userRepo.CountByRole(name)
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return ErrRoleInUse
+	}
+
+	if err := r.roleRepo.Delete(name); err != nil {
+		return err
+	}
+	r.invalidate()
+
+	return nil
+}
+
+// RoleExists implements RoleService.
+func (r *roleService) RoleExists(name string) (bool, error) {
+	permissions, err := r.permissions()
+	if err != nil {
+		return false, err
+	}
+
+	_, ok := permissions[name]
+	return ok, nil
+}
+
+// HasPermissions implements RoleService.
+// Unknown roles have no permissions.
+func (r *roleService) HasPermissions(role string, required ...string) (bool, error) {
+	permissions, err := r.permissions()
+	if err != nil {
+		return false, err
+	}
+
+	granted := permissions[role]
+	for _, permission := range required {
+		if !slices.Contains(granted, permission) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// permissions returns the role -> permissions map, reloading custom roles when the cache is stale
+func (r *roleService) permissions() (map[string][]string, error) {
+	r.mu.RLock()
+	if r.cache != nil && time.Since(r.loadedAt) < roleCacheTTL {
+		defer r.mu.RUnlock()
+		return r.cache, nil
+	}
+	r.mu.RUnlock()
+
+	roles, err := r.roleRepo.List()
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make(map[string][]string, len(roles)+len(models.BuiltinRolePermissions))
+	for name, perms := range models.BuiltinRolePermissions {
+		permissions[name] = perms
+	}
+	for _, role := range roles {
+		permissions[role.Name] = role.PermissionList()
+	}
+
+	r.mu.Lock()
+	r.cache = permissions
+	r.loadedAt = time.Now()
+	r.mu.Unlock()
+
+	return permissions, nil
+}
+
+func (r *roleService) invalidate() {
+	r.mu.Lock()
+	r.cache = nil
+	r.mu.Unlock()
+}
+
+func NewRoleService(
+	roleRepo repository.RoleRepository,
+	userRepo repository.UserRepository,
+) RoleService {
+	return &roleService{
+		roleRepo: roleRepo,
+		userRepo: userRepo,
+	}
+}
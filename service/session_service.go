@@ -0,0 +1,230 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+)
+
+// sessionTouchInterval is the minimum time between two last-seen writes for one session
+const sessionTouchInterval = time.Minute
+
+// SessionService keeps track of signed-in devices. Access tokens carry the session ID,
+// so ending a session rejects its access tokens as well as its refresh tokens.
+type SessionService interface {
+	Start(userID uint, sessionID string, client *models.ClientInfo, expiresAt time.Time) error
+	// Extend is called on refresh; it recreates the record for families issued before sessions existed
+	Extend(userID uint, sessionID string, client *models.ClientInfo, expiresAt time.Time) error
+	IsActive(sessionID string) (bool, error)
+	// Touch records activity. Writes are throttled, so it is cheap to call on every request.
+	Touch(sessionID, ip string)
+	List(userID uint, currentID string) ([]models.SessionResponse, error)
+	Revoke(userID uint, sessionID string) error
+	RevokeAll(userID uint) error
+	// StartedAt returns when the user signed in to an active session
+	StartedAt(userID uint, sessionID string) (time.Time, error)
+}
+
+type sessionCacheEntry struct {
+	active    bool
+	expiresAt time.Time
+}
+
+type sessionService struct {
+	sessionRepo      repository.SessionRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+
+	// status is trusted for revocationCacheTTL, like the token revocation cache
+	mu          sync.Mutex
+	status      map[string]sessionCacheEntry
+	lastTouched map[string]time.Time
+	lastSweep   time.Time
+}
+
+// Start implements SessionService.
+func (s *sessionService) Start(userID uint, sessionID string, client *models.ClientInfo, expiresAt time.Time) error {
+	// housekeeping: drop this user's dead sessions while we are here
+	if err := s.sessionRepo.DeleteExpiredForUser(userID); err != nil {
+		log.Printf("Failed to clean up expired sessions for user %d: %v", userID, err)
+	}
+
+	now := time.Now()
+	return s.sessionRepo.Create(&models.Session{
+		ID:         sessionID,
+		UserID:     userID,
+		DeviceName: client.DeviceName,
+		UserAgent:  client.UserAgent,
+		IP:         client.IP,
+		LastSeenAt: now,
+		ExpiresAt:  expiresAt,
+	})
+}
+
+// Extend implements SessionService.
+func (s *sessionService) Extend(userID uint, sessionID string, client *models.ClientInfo, expiresAt time.Time) error {
+	err := s.sessionRepo.Extend(sessionID, client.IP, time.Now(), expiresAt)
+	if errors.Is(err, repository.ErrSessionNotFound) {
+		return s.Start(userID, sessionID, client, expiresAt)
+	}
+
+	return err
+}
+
+// IsActive implements SessionService.
+func (s *sessionService) IsActive(sessionID string) (bool, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, ok := s.status[sessionID]
+	s.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.active, nil
+	}
+
+	session, err := s.sessionRepo.GetByID(sessionID)
+	if err != nil && !errors.Is(err, repository.ErrSessionNotFound) {
+		return false, err
+	}
+
+	active := session != nil && session.IsActive(now)
+	s.setStatus(sessionID, active)
+
+	return active, nil
+}
+
+// Touch implements SessionService.
+func (s *sessionService) Touch(sessionID, ip string) {
+	now := time.Now()
+
+	s.mu.Lock()
+	if last, ok := s.lastTouched[sessionID]; ok && now.Sub(last) < sessionTouchInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastTouched[sessionID] = now
+	s.mu.Unlock()
+
+	if err := s.sessionRepo.Touch(sessionID, ip, now); err != nil {
+		log.Printf("Failed to update last seen for session %s: %v", sessionID, err)
+	}
+}
+
+// List implements SessionService.
+func (s *sessionService) List(userID uint, currentID string) ([]models.SessionResponse, error) {
+	sessions, err := s.sessionRepo.ListActiveByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, session.ToResponse(currentID))
+	}
+
+	return responses, nil
+}
+
+// Revoke implements SessionService.
+func (s *sessionService) Revoke(userID uint, sessionID string) error {
+	session, err := s.sessionRepo.GetByID(sessionID)
+	if err != nil {
+		return err
+	}
+
+	// someone else's session is reported as missing rather than forbidden
+	if session.UserID != userID {
+		return repository.ErrSessionNotFound
+	}
+
+	if err := s.refreshTokenRepo.RevokeFamily(sessionID); err != nil {
+		return err
+	}
+
+	if err := s.sessionRepo.Revoke(sessionID); err != nil {
+		return err
+	}
+	s.setStatus(sessionID, false)
+
+	return nil
+}
+
+// RevokeAll implements SessionService.
+// The token cutoff only has second precision, so the cached status of each session is dropped as well.
+func (s *sessionService) RevokeAll(userID uint) error {
+	sessions, err := s.sessionRepo.ListActiveByUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.refreshTokenRepo.RevokeAllForUser(userID); err != nil {
+		return err
+	}
+
+	if err := s.sessionRepo.RevokeAllForUser(userID); err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		s.setStatus(session.ID, false)
+	}
+
+	return nil
+}
+
+// StartedAt implements SessionService.
+func (s *sessionService) StartedAt(userID uint, sessionID string) (time.Time, error) {
+	session, err := s.sessionRepo.GetByID(sessionID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if session.UserID != userID || !session.IsActive(time.Now()) {
+		return time.Time{}, repository.ErrSessionNotFound
+	}
+
+	return session.CreatedAt, nil
+}
+
+func (s *sessionService) setStatus(sessionID string, active bool) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status[sessionID] = sessionCacheEntry{active: active, expiresAt: now.Add(revocationCacheTTL)}
+
+	if now.Sub(s.lastSweep) < revocationCacheTTL {
+		return
+	}
+
+	for id, entry := range s.status {
+		if now.After(entry.expiresAt) {
+			delete(s.status, id)
+		}
+	}
+
+	for id, touched := range s.lastTouched {
+		if now.Sub(touched) > sessionTouchInterval {
+			delete(s.lastTouched, id)
+		}
+	}
+
+	s.lastSweep = now
+}
+
+func NewSessionService(
+	sessionRepo repository.SessionRepository,
+	refreshTokenRepo repository.RefreshTokenRepository,
+) SessionService {
+	return &sessionService{
+		sessionRepo:      sessionRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		status:           make(map[string]sessionCacheEntry),
+		lastTouched:      make(map[string]time.Time),
+		lastSweep:        time.Now(),
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+)
+
+var (
+	ErrTagAlreadyExists = errors.New("tag already exists")
+	ErrInvalidTagName   = errors.New("tag names must not be blank")
+	ErrInvalidTagMode   = errors.New("tag_mode must be 'any' or 'all'")
+)
+
+type TagService interface {
+	ListTags(userID uint) ([]models.TagResponse, error)
+	CreateTag(userID uint, req *models.CreateTagRequest) (*models.TagResponse, error)
+	UpdateTag(tagID, userID uint, req *models.UpdateTagRequest) (*models.TagResponse, error)
+	DeleteTag(tagID, userID uint) error
+}
+
+type tagService struct {
+	tagRepo repository.TagRepository
+}
+
+// normalizeTagName makes tag names case-insensitive
+func normalizeTagName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// normalizeTagNames normalizes names and drops duplicates, keeping the first occurrence
+func normalizeTagNames(names []string) ([]string, error) {
+	seen := make(map[string]bool, len(names))
+	normalized := make([]string, 0, len(names))
+
+	for _, name := range names {
+		name = normalizeTagName(name)
+		if name == "" {
+			return nil, ErrInvalidTagName
+		}
+
+		if !seen[name] {
+			seen[name] = true
+			normalized = append(normalized, name)
+		}
+	}
+
+	return normalized, nil
+}
+
+// ListTags implements TagService.
+func (t *tagService) ListTags(userID uint) ([]models.TagResponse, error) {
+	tags, err := t.tagRepo.ListWithCounts(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.TagResponse, 0, len(tags))
+	for _, tag := range tags {
+		responses = append(responses, tag.ToResponse())
+	}
+
+	return responses, nil
+}
+
+// CreateTag implements TagService.
+func (t *tagService) CreateTag(userID uint, req *models.CreateTagRequest) (*models.TagResponse, error) {
+	name := normalizeTagName(req.Name)
+	if name == "" {
+		return nil, ErrInvalidTagName
+	}
+
+	if _, err := t.tagRepo.GetByName(userID, name); err == nil {
+		return nil, ErrTagAlreadyExists
+	} else if !errors.Is(err, repository.ErrTagNotFound) {
+		return nil, err
+	}
+
+	tag := &models.Tag{UserID: userID, Name: name, Color: strings.ToLower(req.Color)}
+	if tag.Color == "" {
+		tag.Color = models.DefaultTagColor
+	}
+
+	if err := t.tagRepo.Create(tag); err != nil {
+		return nil, err
+	}
+
+	response := tag.ToResponse()
+	return &response, nil
+}
+
+// UpdateTag implements TagService.
+func (t *tagService) UpdateTag(tagID uint, userID uint, req *models.UpdateTagRequest) (*models.TagResponse, error) {
+	tag, err := t.tagRepo.GetByID(tagID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" {
+		name := normalizeTagName(req.Name)
+		if name == "" {
+			return nil, ErrInvalidTagName
+		}
+
+		if name != tag.Name {
+			if _, err := t.tagRepo.GetByName(userID, name); err == nil {
+				return nil, ErrTagAlreadyExists
+			} else if !errors.Is(err, repository.ErrTagNotFound) {
+				return nil, err
+			}
+		}
+
+		tag.Name = name
+	}
+
+	if req.Color != "" {
+		tag.Color = strings.ToLower(req.Color)
+	}
+
+	if err := t.tagRepo.Update(tag); err != nil {
+		return nil, err
+	}
+
+	response := tag.ToResponse()
+	return &response, nil
+}
+
+// DeleteTag implements TagService.
+// Tasks keep existing, they just lose the tag.
+func (t *tagService) DeleteTag(tagID uint, userID uint) error {
+	return t.tagRepo.Delete(tagID, userID)
+}
+
+func NewTagService(tagRepo repository.TagRepository) TagService {
+	return &tagService{tagRepo: tagRepo}
+}
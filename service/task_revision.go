@@ -0,0 +1,236 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+)
+
+// revisionFields are the task fields whose changes are recorded, in the order they are reported
+var revisionFields = []string{
+	"title",
+	"description",
+	"priority",
+	"completed",
+	"auto_complete",
+	"due_date",
+	"project_id",
+	"recurrence",
+	"recurrence_tz",
+	"tags",
+}
+
+// taskState maps revision fields to their JSON encoded values
+type taskState map[string]json.RawMessage
+
+// snapshotTask captures the recorded fields of task; a nil task has no fields
+func snapshotTask(task *models.Task) taskState {
+	state := make(taskState, len(revisionFields))
+	if task == nil {
+		return state
+	}
+
+	tags := make([]string, 0, len(task.Tags))
+	for _, tag := range task.Tags {
+		tags = append(tags, tag.Name)
+	}
+	sort.Strings(tags)
+
+	var due *time.Time
+	if task.DueDate != nil {
+		utc := task.DueDate.UTC()
+		due = &utc
+	}
+
+	values := map[string]interface{}{
+		"title":         task.Title,
+		"description":   task.Description,
+		"priority":      task.Priority,
+		"completed":     task.Completed,
+		"auto_complete": task.AutoComplete,
+		"due_date":      due,
+		"project_id":    task.ProjectID,
+		"recurrence":    task.Recurrence,
+		"recurrence_tz": task.RecurrenceTZ,
+		"tags":          tags,
+	}
+
+	for field, value := range values {
+		// plain values, encoding cannot fail
+		encoded, _ := json.Marshal(value)
+		state[field] = encoded
+	}
+
+	return state
+}
+
+// diffStates lists the fields that differ between two snapshots
+func diffStates(before, after taskState) []models.FieldChange {
+	var changes []models.FieldChange
+
+	for _, field := range revisionFields {
+		// a field a new task leaves empty is no change
+		if before[field] == nil && bytes.Equal(after[field], []byte("null")) {
+			continue
+		}
+
+		if !bytes.Equal(before[field], after[field]) {
+			changes = append(changes, models.FieldChange{
+				Field: field,
+				From:  before[field],
+				To:    after[field],
+			})
+		}
+	}
+
+	return changes
+}
+
+// byUser describes a change made by the user through the API
+func byUser(userID uint, action string) models.TaskRevision {
+	return models.TaskRevision{ActorID: &userID, Source: models.RevisionSourceAPI, Action: action}
+}
+
+// byAutomation describes a change the service made on its own
+func byAutomation(action string) models.TaskRevision {
+	return models.TaskRevision{Source: models.RevisionSourceAutomation, Action: action}
+}
+
+// recordRevision stores how task differs from before, which is nil for a new task.
+// Nothing is stored when no recorded field changed.
+func (t *taskService) recordRevision(task *models.Task, before *models.Task, revision models.TaskRevision) error {
+	revision.Changes = diffStates(snapshotTask(before), snapshotTask(task))
+	if len(revision.Changes) == 0 {
+		return nil
+	}
+
+	revision.TaskID = task.ID
+	revision.UserID = task.UserID
+	return t.revisionRepo.Create(&revision)
+}
+
+// GetTaskHistory implements TaskService.
+func (t *taskService) GetTaskHistory(taskID uint, userID uint, page int, pageSize int) (*models.PaginationResponse, error) {
+	if _, err := t.taskRepo.GetById(taskID, userID); err != nil {
+		return nil, err
+	}
+
+	revisions, total, err := t.revisionRepo.ListByTask(taskID, userID, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := (total + int64(pageSize) - 1) / int64(pageSize)
+
+	return &models.PaginationResponse{
+		Data:       revisions,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// RevertTask implements TaskService.
+// The task gets the values it had right after the given revision, found by undoing every later change.
+// The recurrence is left alone: completing an occurrence has already moved the series on to a new task.
+func (t *taskService) RevertTask(taskID uint, revisionID uint, userID uint, version *uint) (*models.TaskResponse, error) {
+	task, err := t.taskRepo.GetById(taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkVersion(task, version); err != nil {
+		return nil, err
+	}
+
+	if _, err := t.revisionRepo.GetByID(revisionID, taskID, userID); err != nil {
+		return nil, err
+	}
+
+	later, err := t.revisionRepo.ListAfter(taskID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	current := snapshotTask(task)
+	target := snapshotTask(task)
+	for _, revision := range later {
+		for _, change := range revision.Changes {
+			target[change.Field] = change.From
+		}
+	}
+
+	before := *task
+	if err := restoreState(task, target); err != nil {
+		return nil, err
+	}
+
+	if task.Recurrence != "" && task.DueDate == nil {
+		return nil, ErrRecurrenceNoDue
+	}
+
+	if !bytes.Equal(current["project_id"], target["project_id"]) {
+		if err := checkProjectAcceptsTasks(t.projectRepo, userID, task.ProjectID); err != nil {
+			return nil, err
+		}
+	}
+
+	if task.Completed && !before.Completed {
+		if err := t.checkNotBlocked(task.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	var tags *[]models.Tag
+	if !bytes.Equal(current["tags"], target["tags"]) {
+		var names []string
+		if err := json.Unmarshal(target["tags"], &names); err != nil {
+			return nil, err
+		}
+
+		resolved, err := t.resolveTags(userID, names)
+		if err != nil {
+			return nil, err
+		}
+		tags = &resolved
+	}
+
+	revision := byUser(userID, models.RevisionActionRevert)
+	revision.RevertedTo = &revisionID
+
+	next, err := t.saveTask(task, &before, tags, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.responseWithNext(task, next)
+}
+
+// restoreState sets the revertable fields of task from a snapshot
+func restoreState(task *models.Task, state taskState) error {
+	// decoding would write through the old pointers, which the caller's copy of the task still shares
+	task.DueDate = nil
+	task.ProjectID = nil
+
+	fields := map[string]interface{}{
+		"title":         &task.Title,
+		"description":   &task.Description,
+		"priority":      &task.Priority,
+		"completed":     &task.Completed,
+		"auto_complete": &task.AutoComplete,
+		"due_date":      &task.DueDate,
+		"project_id":    &task.ProjectID,
+	}
+
+	for field, target := range fields {
+		if err := json.Unmarshal(state[field], target); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
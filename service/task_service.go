@@ -2,27 +2,73 @@ package service
 
 import (
 	"errors"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/lieucongduy182/go-gin-todo-api/config"
 	"github.com/lieucongduy182/go-gin-todo-api/models"
 	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/utils"
+	"gorm.io/gorm"
 )
 
 type TaskService interface {
 	GetTask(taskID, userID uint) (*models.TaskResponse, error)
 	GetUserTask(userID uint) ([]*models.TaskResponse, error)
-	GetUserTaskPaginated(userID uint, page, pageSize int) (*models.PaginationResponse, error)
+	GetUserTaskPaginated(userID uint, filter *models.TaskFilter, page, pageSize int) (*models.PaginationResponse, error)
 	SearchTasks(userID uint, keyword string) ([]*models.TaskResponse, error)
-	GetTaskByStats(userID uint) (map[string]interface{}, error)
+	GetTaskByStats(userID uint, filter *models.TaskFilter) (map[string]interface{}, error)
 	GetTasksByPriority(userID uint, priority string) ([]models.TaskResponse, error)
 	GetTasksByStatus(userID uint, completed bool) ([]models.TaskResponse, error)
 	CreateTask(userID uint, req *models.CreateTaskRequest) (*models.TaskResponse, error)
 	UpdateTask(taskID, userID uint, req *models.UpdateTaskRequest) (*models.TaskResponse, error)
-	DeleteTask(taskID, userID uint) error
+	MoveTask(taskID, userID uint, req *models.MoveTaskRequest) (*models.TaskResponse, error)
+	GetSubtasks(taskID, userID uint) ([]models.TaskResponse, error)
+	GetTaskTree(taskID, userID uint) (*models.TaskTreeNode, error)
+	GetDependencies(taskID, userID uint) (*models.TaskDependenciesResponse, error)
+	AddDependency(taskID, userID uint, req *models.AddDependencyRequest) (*models.TaskDependenciesResponse, error)
+	RemoveDependency(taskID, blockedByID, userID uint) error
+	GetNextTasks(userID uint) ([]models.TaskResponse, error)
+	// version, when set, is the version the client has seen (If-Match); other versions fail the write
+	SkipOccurrence(taskID, userID uint, version *uint) (*models.TaskResponse, error)
+	EndRecurrence(taskID, userID uint, version *uint) (*models.TaskResponse, error)
+	GetTaskHistory(taskID, userID uint, page, pageSize int) (*models.PaginationResponse, error)
+	RevertTask(taskID, revisionID, userID uint, version *uint) (*models.TaskResponse, error)
+	DeleteTask(taskID, userID uint, version *uint) error
 }
 
+var (
+	ErrInvalidPriority    = errors.New("invalid priority values")
+	ErrParentTaskNotFound = errors.New("parent task not found")
+	ErrTaskTooDeep        = errors.New("subtasks are nested too deeply")
+	ErrTaskBlocked        = errors.New("task is blocked by open tasks")
+	ErrBlockerNotFound    = errors.New("blocking task not found")
+	ErrRecurrenceNoDue    = errors.New("recurring tasks need a due date")
+	ErrTaskNotRecurring   = errors.New("task does not recur")
+	ErrSeriesEnded        = errors.New("the recurrence has no further occurrences")
+	ErrPreconditionFailed = errors.New("task has changed since it was read")
+)
+
+// autoCompleteAttempts bounds how often auto-completing a parent is retried when it changes concurrently
+const autoCompleteAttempts = 3
+
 type taskService struct {
-	userRepo repository.UserRepository
-	taskRepo repository.TaskRepository
+	db             *gorm.DB
+	userRepo       repository.UserRepository
+	taskRepo       repository.TaskRepository
+	tagRepo        repository.TagRepository
+	projectRepo    repository.ProjectRepository
+	dependencyRepo repository.TaskDependencyRepository
+	reminderRepo   repository.ReminderRepository
+	revisionRepo   repository.TaskRevisionRepository
+}
+
+// priorityRank orders priorities from most to least urgent
+var priorityRank = map[string]int{
+	"high":   0,
+	"medium": 1,
+	"low":    2,
 }
 
 func isValidPriority(priority string) bool {
@@ -36,13 +82,27 @@ func isValidPriority(priority string) bool {
 
 // CreateTask implements TaskService.
 func (t *taskService) CreateTask(userID uint, req *models.CreateTaskRequest) (*models.TaskResponse, error) {
+	if config.AppConfig.RequireEmailVerification {
+		user, err := t.userRepo.GetByID(userID)
+		if err != nil {
+			return nil, err
+		}
+
+		if !user.IsEmailVerified() {
+			return nil, ErrEmailNotVerified
+		}
+	}
+
 	task := &models.Task{
-		UserID:      userID,
-		Title:       req.Title,
-		Description: req.Description,
-		Priority:    req.Priority,
-		DueDate:     req.DueDate,
-		Completed:   false,
+		UserID:       userID,
+		ProjectID:    req.ProjectID,
+		ParentID:     req.ParentID,
+		AutoComplete: req.AutoComplete,
+		Title:        req.Title,
+		Description:  req.Description,
+		Priority:     req.Priority,
+		DueDate:      req.DueDate,
+		Completed:    false,
 	}
 
 	if task.Priority == "" {
@@ -50,10 +110,44 @@ func (t *taskService) CreateTask(userID uint, req *models.CreateTaskRequest) (*m
 	}
 
 	if !isValidPriority(task.Priority) {
-		return nil, errors.New("invalid priority values")
+		return nil, ErrInvalidPriority
+	}
+
+	if task.ParentID != nil {
+		parent, err := t.checkParent(*task.ParentID, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		if task.ProjectID == nil {
+			task.ProjectID = parent.ProjectID
+		}
+	}
+
+	if err := checkProjectAcceptsTasks(t.projectRepo, userID, task.ProjectID); err != nil {
+		return nil, err
+	}
+
+	if req.Recurrence != "" {
+		if err := setRecurrence(task, req.Recurrence, req.Timezone); err != nil {
+			return nil, err
+		}
+	}
+
+	tags, err := t.resolveTags(userID, req.Tags)
+	if err != nil {
+		return nil, err
 	}
+	task.Tags = tags
+
+	err = t.inTransaction(func(tx *taskService) error {
+		if err := tx.taskRepo.Create(task); err != nil {
+			return err
+		}
 
-	if err := t.taskRepo.Create(task); err != nil {
+		return tx.recordRevision(task, nil, byUser(userID, models.RevisionActionCreate))
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -62,8 +156,26 @@ func (t *taskService) CreateTask(userID uint, req *models.CreateTaskRequest) (*m
 }
 
 // DeleteTask implements TaskService.
-func (t *taskService) DeleteTask(taskID uint, userID uint) error {
-	return t.taskRepo.Delete(taskID, userID)
+func (t *taskService) DeleteTask(taskID uint, userID uint, version *uint) error {
+	task, err := t.taskRepo.GetById(taskID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := checkVersion(task, version); err != nil {
+		return err
+	}
+
+	return t.taskRepo.Delete(task)
+}
+
+// checkVersion fails when the client pinned a write to another version of task than the stored one
+func checkVersion(task *models.Task, version *uint) error {
+	if version != nil && *version != task.Version {
+		return ErrPreconditionFailed
+	}
+
+	return nil
 }
 
 // GetTask implements TaskService.
@@ -73,19 +185,207 @@ func (t *taskService) GetTask(taskID uint, userID uint) (*models.TaskResponse, e
 		return nil, err
 	}
 
-	response := task.ToResponse()
-	return &response, nil
+	responses, err := t.toResponses([]*models.Task{task})
+	if err != nil {
+		return nil, err
+	}
+
+	return &responses[0], nil
+}
+
+// GetSubtasks implements TaskService.
+func (t *taskService) GetSubtasks(taskID uint, userID uint) ([]models.TaskResponse, error) {
+	if _, err := t.taskRepo.GetById(taskID, userID); err != nil {
+		return nil, err
+	}
+
+	tasks, err := t.taskRepo.GetChildren(taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.toResponses(tasks)
+}
+
+// GetTaskTree implements TaskService.
+func (t *taskService) GetTaskTree(taskID uint, userID uint) (*models.TaskTreeNode, error) {
+	tasks, err := t.taskRepo.GetSubtree(taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses, err := t.toResponses(tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[uint]*models.TaskTreeNode, len(tasks))
+	for _, response := range responses {
+		nodes[response.ID] = &models.TaskTreeNode{TaskResponse: response, Subtasks: []*models.TaskTreeNode{}}
+	}
+
+	root, ok := nodes[taskID]
+	if !ok {
+		return nil, repository.ErrTaskNotFound
+	}
+
+	// tasks come oldest first, so siblings keep their creation order
+	for _, task := range tasks {
+		if task.ID == taskID || task.ParentID == nil {
+			continue
+		}
+
+		if parent, ok := nodes[*task.ParentID]; ok {
+			parent.Subtasks = append(parent.Subtasks, nodes[task.ID])
+		}
+	}
+
+	setTreeProgress(root, 0)
+	return root, nil
+}
+
+// setTreeProgress fills in depth and progress for node and everything below it
+func setTreeProgress(node *models.TaskTreeNode, depth int) {
+	node.Depth = depth
+
+	if len(node.Subtasks) == 0 {
+		return
+	}
+
+	var completed int64
+	for _, child := range node.Subtasks {
+		if child.Completed {
+			completed++
+		}
+		setTreeProgress(child, depth+1)
+	}
+	node.Progress = models.NewSubtaskProgress(int64(len(node.Subtasks)), completed)
+}
+
+// toResponses converts tasks to responses carrying their subtask progress and blocked state
+func (t *taskService) toResponses(tasks []*models.Task) ([]models.TaskResponse, error) {
+	ids := make([]uint, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+
+	progress, err := t.taskRepo.SubtaskProgress(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	blocked, err := t.dependencyRepo.BlockedTaskIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.TaskResponse, 0, len(tasks))
+	for _, task := range tasks {
+		response := task.ToResponse()
+		response.Progress = progress[task.ID]
+		response.Blocked = blocked[task.ID]
+		responses = append(responses, response)
+	}
+
+	return responses, nil
+}
+
+// responsePointers is toResponses for the listings that return pointers
+func (t *taskService) responsePointers(tasks []*models.Task) ([]*models.TaskResponse, error) {
+	responses, err := t.toResponses(tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	pointers := make([]*models.TaskResponse, 0, len(responses))
+	for i := range responses {
+		pointers = append(pointers, &responses[i])
+	}
+
+	return pointers, nil
+}
+
+// checkParent loads the parent of a new subtask and makes sure the subtask stays within the depth limit
+func (t *taskService) checkParent(parentID, userID uint) (*models.Task, error) {
+	parent, err := t.taskRepo.GetById(parentID, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTaskNotFound) {
+			return nil, ErrParentTaskNotFound
+		}
+		return nil, err
+	}
+
+	depth, err := t.taskRepo.Depth(parent.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if depth+1 > config.AppConfig.TaskMaxDepth {
+		return nil, ErrTaskTooDeep
+	}
+
+	return parent, nil
+}
+
+// autoCompleteAncestors completes the task with taskID if it auto-completes and all of its subtasks
+// are done. It is saved like any other completion, so a recurring parent moves on to its next
+// occurrence and its own parent is looked at in turn. Must run inside a transaction.
+func (t *taskService) autoCompleteAncestors(taskID *uint, userID uint) error {
+	if taskID == nil {
+		return nil
+	}
+
+	for attempt := 1; ; attempt++ {
+		task, err := t.taskRepo.GetById(*taskID, userID)
+		if err != nil {
+			if errors.Is(err, repository.ErrTaskNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		if !task.AutoComplete || task.Completed {
+			return nil
+		}
+
+		progress, err := t.taskRepo.SubtaskProgress([]uint{task.ID})
+		if err != nil {
+			return err
+		}
+
+		if p := progress[task.ID]; p == nil || p.Completed < p.Total {
+			return nil
+		}
+
+		// a parent waiting on other tasks stays open
+		if err := t.checkNotBlocked(task.ID); err != nil {
+			if errors.Is(err, ErrTaskBlocked) {
+				return nil
+			}
+			return err
+		}
+
+		before := *task
+		task.Completed = true
+		_, err = t.save(task, &before, nil, byAutomation(models.RevisionActionUpdate))
+
+		// changed meanwhile: look at the parent again, but do not fight a busy task forever
+		if errors.Is(err, repository.ErrTaskVersionConflict) && attempt < autoCompleteAttempts {
+			continue
+		}
+		return err
+	}
 }
 
 // GetTaskByStats implements TaskService.
-func (t *taskService) GetTaskByStats(userID uint) (map[string]interface{}, error) {
-	return t.taskRepo.GetStats(userID)
+func (t *taskService) GetTaskByStats(userID uint, filter *models.TaskFilter) (map[string]interface{}, error) {
+	return t.taskRepo.GetStats(userID, filter)
 }
 
 // GetTasksByPriority implements TaskService.
 func (t *taskService) GetTasksByPriority(userID uint, priority string) ([]models.TaskResponse, error) {
 	if !isValidPriority(priority) {
-		return nil, errors.New("invalid priority values")
+		return nil, ErrInvalidPriority
 	}
 
 	tasks, err := t.taskRepo.GetByPriority(userID, priority)
@@ -93,12 +393,7 @@ func (t *taskService) GetTasksByPriority(userID uint, priority string) ([]models
 		return nil, err
 	}
 
-	var responses []models.TaskResponse
-	for _, task := range tasks {
-		responses = append(responses, task.ToResponse())
-	}
-
-	return responses, nil
+	return t.toResponses(tasks)
 }
 
 // GetTasksByStatus implements TaskService.
@@ -108,12 +403,7 @@ func (t *taskService) GetTasksByStatus(userID uint, completed bool) ([]models.Ta
 		return nil, err
 	}
 
-	var responses []models.TaskResponse
-	for _, task := range tasks {
-		responses = append(responses, task.ToResponse())
-	}
-
-	return responses, nil
+	return t.toResponses(tasks)
 }
 
 // GetUserTask implements TaskService.
@@ -123,25 +413,38 @@ func (t *taskService) GetUserTask(userID uint) ([]*models.TaskResponse, error) {
 		return nil, err
 	}
 
-	var responses []*models.TaskResponse
-	for _, task := range *tasks {
-		t := task.ToResponse()
-		responses = append(responses, &t)
+	list := make([]*models.Task, 0, len(*tasks))
+	for i := range *tasks {
+		list = append(list, &(*tasks)[i])
 	}
 
-	return responses, nil
+	return t.responsePointers(list)
 }
 
 // GetUserTaskPaginated implements TaskService.
-func (t *taskService) GetUserTaskPaginated(userID uint, page int, pageSize int) (*models.PaginationResponse, error) {
-	tasks, total, err := t.taskRepo.GetByUserIdPaginated(userID, page, pageSize)
+func (t *taskService) GetUserTaskPaginated(userID uint, filter *models.TaskFilter, page int, pageSize int) (*models.PaginationResponse, error) {
+	switch filter.TagMode {
+	case "":
+		filter.TagMode = models.TagModeAny
+	case models.TagModeAny, models.TagModeAll:
+	default:
+		return nil, ErrInvalidTagMode
+	}
+
+	tags, err := normalizeTagNames(filter.Tags)
 	if err != nil {
 		return nil, err
 	}
+	filter.Tags = tags
 
-	var responses []models.TaskResponse
-	for _, task := range tasks {
-		responses = append(responses, task.ToResponse())
+	tasks, total, err := t.taskRepo.GetByUserIdPaginated(userID, filter, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	responses, err := t.toResponses(tasks)
+	if err != nil {
+		return nil, err
 	}
 
 	totalPages := (total + int64(pageSize) - 1) / int64(pageSize)
@@ -162,12 +465,7 @@ func (t *taskService) SearchTasks(userID uint, keyword string) ([]*models.TaskRe
 		return nil, err
 	}
 
-	var responses []*models.TaskResponse
-	for _, t := range task {
-		resp := t.ToResponse()
-		responses = append(responses, &resp)
-	}
-	return responses, nil
+	return t.responsePointers(task)
 }
 
 // UpdateTask implements TaskService.
@@ -177,6 +475,11 @@ func (t *taskService) UpdateTask(taskID uint, userID uint, req *models.UpdateTas
 		return nil, err
 	}
 
+	if err := checkVersion(task, req.Version); err != nil {
+		return nil, err
+	}
+	before := *task
+
 	if req.Title != "" {
 		task.Title = req.Title
 	}
@@ -185,13 +488,19 @@ func (t *taskService) UpdateTask(taskID uint, userID uint, req *models.UpdateTas
 		task.Description = req.Description
 	}
 
+	if req.Completed != nil && *req.Completed && !task.Completed {
+		if err := t.checkNotBlocked(task.ID); err != nil {
+			return nil, err
+		}
+	}
+
 	if req.Completed != nil {
 		task.Completed = *req.Completed
 	}
 
 	if req.Priority != "" {
 		if !isValidPriority(req.Priority) {
-			return nil, errors.New("invalid priority values")
+			return nil, ErrInvalidPriority
 		}
 
 		task.Priority = req.Priority
@@ -201,20 +510,494 @@ func (t *taskService) UpdateTask(taskID uint, userID uint, req *models.UpdateTas
 		task.DueDate = req.DueDate
 	}
 
+	if req.AutoComplete != nil {
+		task.AutoComplete = *req.AutoComplete
+	}
+
+	if req.Recurrence != nil {
+		timezone := req.Timezone
+		if timezone == "" {
+			timezone = task.RecurrenceTZ
+		}
+
+		if err := setRecurrence(task, *req.Recurrence, timezone); err != nil {
+			return nil, err
+		}
+	}
+
+	var tags *[]models.Tag
+	if req.Tags != nil {
+		resolved, err := t.resolveTags(userID, *req.Tags)
+		if err != nil {
+			return nil, err
+		}
+		tags = &resolved
+	}
+
+	next, err := t.saveTask(task, &before, tags, byUser(userID, models.RevisionActionUpdate))
+	if err != nil {
+		return nil, err
+	}
+
+	// turning auto-complete on for a task whose subtasks are already done completes it right away
+	if req.AutoComplete != nil && *req.AutoComplete && !task.Completed {
+		if err := t.inTransaction(func(tx *taskService) error {
+			return tx.autoCompleteAncestors(&task.ID, userID)
+		}); err != nil {
+			return nil, err
+		}
+
+		if task, err = t.taskRepo.GetById(taskID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	return t.responseWithNext(task, next)
+}
+
+// saveTask stores the changes made to task since before, along with what follows from them:
+// replaced tags, re-armed reminders, the revision, auto-completed parents and, when a recurring
+// task gets completed, its next occurrence, which is returned. It all happens in one transaction.
+func (t *taskService) saveTask(task *models.Task, before *models.Task, tags *[]models.Tag, revision models.TaskRevision) (*models.Task, error) {
+	var next *models.Task
+
+	err := t.inTransaction(func(tx *taskService) error {
+		var err error
+		next, err = tx.save(task, before, tags, revision)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return next, nil
+}
+
+// inTransaction runs fn with a copy of the service whose repositories share one transaction
+func (t *taskService) inTransaction(fn func(tx *taskService) error) error {
+	return t.db.Transaction(func(db *gorm.DB) error {
+		return fn(&taskService{
+			db:             db,
+			userRepo:       repository.NewUserRepository(db),
+			taskRepo:       repository.NewTaskRepository(db),
+			tagRepo:        repository.NewTagRepository(db),
+			projectRepo:    repository.NewProjectRepository(db),
+			dependencyRepo: repository.NewTaskDependencyRepository(db),
+			reminderRepo:   repository.NewReminderRepository(db),
+			revisionRepo:   repository.NewTaskRevisionRepository(db),
+		})
+	})
+}
+
+// save is saveTask within a transaction the caller has opened
+func (t *taskService) save(task *models.Task, before *models.Task, tags *[]models.Tag, revision models.TaskRevision) (*models.Task, error) {
+	completing := task.Completed && !before.Completed
+	dueChanged := task.DueDate != nil && (before.DueDate == nil || !before.DueDate.Equal(*task.DueDate))
+
+	// the series moves on to a new task, the completed occurrence becomes a plain task
+	var next *models.Task
+	if completing && task.Recurrence != "" {
+		var err error
+		if next, err = nextOccurrence(task); err != nil {
+			return nil, err
+		}
+		clearRecurrence(task)
+	}
+
 	if err := t.taskRepo.Update(task); err != nil {
 		return nil, err
 	}
 
-	response := task.ToResponse()
-	return &response, nil
+	if tags != nil {
+		if err := t.taskRepo.ReplaceTags(task, *tags); err != nil {
+			return nil, err
+		}
+		task.Tags = *tags
+	}
+
+	if dueChanged {
+		if err := t.reminderRepo.RescheduleForTask(task.ID, *task.DueDate); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := t.recordRevision(task, before, revision); err != nil {
+		return nil, err
+	}
+
+	if next != nil {
+		next.Tags = task.Tags
+		if err := t.taskRepo.Create(next); err != nil {
+			return nil, err
+		}
+
+		if err := t.reminderRepo.CarryOver(task.ID, next); err != nil {
+			return nil, err
+		}
+
+		if err := t.recordRevision(next, nil, byAutomation(models.RevisionActionCreate)); err != nil {
+			return nil, err
+		}
+	}
+
+	// after the next occurrence exists, so a recurring subtask keeps its parent open
+	if completing {
+		if err := t.autoCompleteAncestors(task.ParentID, task.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	return next, nil
+}
+
+// responseWithNext builds the response for task, with the occurrence that completing it created
+func (t *taskService) responseWithNext(task *models.Task, next *models.Task) (*models.TaskResponse, error) {
+	responses, err := t.toResponses([]*models.Task{task})
+	if err != nil {
+		return nil, err
+	}
+
+	if next != nil {
+		response := next.ToResponse()
+		responses[0].Next = &response
+	}
+
+	return &responses[0], nil
+}
+
+// SkipOccurrence implements TaskService.
+// The task moves to the following occurrence without being completed.
+func (t *taskService) SkipOccurrence(taskID uint, userID uint, version *uint) (*models.TaskResponse, error) {
+	task, err := t.taskRepo.GetById(taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkVersion(task, version); err != nil {
+		return nil, err
+	}
+
+	if task.Recurrence == "" {
+		return nil, ErrTaskNotRecurring
+	}
+
+	next, err := nextOccurrence(task)
+	if err != nil {
+		return nil, err
+	}
+
+	if next == nil {
+		return nil, ErrSeriesEnded
+	}
+
+	before := *task
+	task.DueDate = next.DueDate
+	if _, err := t.saveTask(task, &before, nil, byUser(userID, models.RevisionActionUpdate)); err != nil {
+		return nil, err
+	}
+
+	return t.GetTask(taskID, userID)
+}
+
+// EndRecurrence implements TaskService.
+// The current occurrence stays as a plain task.
+func (t *taskService) EndRecurrence(taskID uint, userID uint, version *uint) (*models.TaskResponse, error) {
+	task, err := t.taskRepo.GetById(taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkVersion(task, version); err != nil {
+		return nil, err
+	}
+
+	if task.Recurrence == "" {
+		return nil, ErrTaskNotRecurring
+	}
+
+	before := *task
+	clearRecurrence(task)
+	if _, err := t.saveTask(task, &before, nil, byUser(userID, models.RevisionActionUpdate)); err != nil {
+		return nil, err
+	}
+
+	return t.GetTask(taskID, userID)
+}
+
+// setRecurrence validates rule and starts a new series at the task's due date; an empty rule ends the series
+func setRecurrence(task *models.Task, rule, timezone string) error {
+	if strings.TrimSpace(rule) == "" {
+		clearRecurrence(task)
+		return nil
+	}
+
+	if task.DueDate == nil {
+		return ErrRecurrenceNoDue
+	}
+
+	if timezone == "" {
+		timezone = "UTC"
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := utils.ParseRRule(rule, loc)
+	if err != nil {
+		return err
+	}
+
+	start := *task.DueDate
+	task.Recurrence = parsed.String()
+	task.RecurrenceTZ = timezone
+	task.SeriesStart = &start
+	return nil
+}
+
+func clearRecurrence(task *models.Task) {
+	task.Recurrence = ""
+	task.RecurrenceTZ = ""
+	task.SeriesStart = nil
+}
+
+// nextOccurrence builds the next open task of the series after task's due date,
+// or returns nil when COUNT or UNTIL have ended the series
+func nextOccurrence(task *models.Task) (*models.Task, error) {
+	loc, err := time.LoadLocation(task.RecurrenceTZ)
+	if err != nil {
+		return nil, err
+	}
+
+	rule, err := utils.ParseRRule(task.Recurrence, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	if task.SeriesStart == nil || task.DueDate == nil {
+		return nil, ErrRecurrenceNoDue
+	}
+
+	due, ok := rule.Next(*task.SeriesStart, *task.DueDate, loc)
+	if !ok {
+		return nil, nil
+	}
+
+	return &models.Task{
+		UserID:       task.UserID,
+		ProjectID:    task.ProjectID,
+		ParentID:     task.ParentID,
+		AutoComplete: task.AutoComplete,
+		Title:        task.Title,
+		Description:  task.Description,
+		Priority:     task.Priority,
+		DueDate:      &due,
+		Recurrence:   task.Recurrence,
+		RecurrenceTZ: task.RecurrenceTZ,
+		SeriesStart:  task.SeriesStart,
+		Tags:         task.Tags,
+	}, nil
+}
+
+// MoveTask implements TaskService.
+// Tasks can be moved out of an archived project but not into one.
+func (t *taskService) MoveTask(taskID uint, userID uint, req *models.MoveTaskRequest) (*models.TaskResponse, error) {
+	task, err := t.taskRepo.GetById(taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkVersion(task, req.Version); err != nil {
+		return nil, err
+	}
+
+	if err := checkProjectAcceptsTasks(t.projectRepo, userID, req.ProjectID); err != nil {
+		return nil, err
+	}
+
+	before := *task
+	task.ProjectID = req.ProjectID
+	if _, err := t.saveTask(task, &before, nil, byUser(userID, models.RevisionActionUpdate)); err != nil {
+		return nil, err
+	}
+
+	return t.GetTask(taskID, userID)
+}
+
+// GetDependencies implements TaskService.
+func (t *taskService) GetDependencies(taskID uint, userID uint) (*models.TaskDependenciesResponse, error) {
+	if _, err := t.taskRepo.GetById(taskID, userID); err != nil {
+		return nil, err
+	}
+
+	blockers, err := t.dependencyRepo.ListBlockers(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	blocking, err := t.dependencyRepo.ListBlocking(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	blockedBy, err := t.toResponses(blockers)
+	if err != nil {
+		return nil, err
+	}
+
+	waiting, err := t.toResponses(blocking)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TaskDependenciesResponse{BlockedBy: blockedBy, Blocking: waiting}, nil
+}
+
+// AddDependency implements TaskService.
+// Both tasks must belong to the user; edges that would close a cycle are rejected by the repository.
+func (t *taskService) AddDependency(taskID uint, userID uint, req *models.AddDependencyRequest) (*models.TaskDependenciesResponse, error) {
+	if _, err := t.taskRepo.GetById(taskID, userID); err != nil {
+		return nil, err
+	}
+
+	if _, err := t.taskRepo.GetById(req.BlockedByID, userID); err != nil {
+		if errors.Is(err, repository.ErrTaskNotFound) {
+			return nil, ErrBlockerNotFound
+		}
+		return nil, err
+	}
+
+	dependency := &models.TaskDependency{TaskID: taskID, BlockedByID: req.BlockedByID}
+	if err := t.dependencyRepo.Add(userID, dependency); err != nil {
+		return nil, err
+	}
+
+	return t.GetDependencies(taskID, userID)
+}
+
+// RemoveDependency implements TaskService.
+func (t *taskService) RemoveDependency(taskID uint, blockedByID uint, userID uint) error {
+	if _, err := t.taskRepo.GetById(taskID, userID); err != nil {
+		return err
+	}
+
+	return t.dependencyRepo.Remove(taskID, blockedByID)
+}
+
+// GetNextTasks implements TaskService.
+// Open tasks are listed in dependency order (Kahn's algorithm): every task comes after all of its
+// open blockers. Tasks that become available at the same step are ordered by priority, due date and age,
+// so the first entries are what can be done right now.
+func (t *taskService) GetNextTasks(userID uint) ([]models.TaskResponse, error) {
+	tasks, err := t.taskRepo.GetByStatus(userID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	edges, err := t.dependencyRepo.OpenEdges(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	waitingOn := make(map[uint]int, len(tasks))
+	unblocks := make(map[uint][]uint)
+	for _, edge := range edges {
+		waitingOn[edge.TaskID]++
+		unblocks[edge.BlockedByID] = append(unblocks[edge.BlockedByID], edge.TaskID)
+	}
+
+	byID := make(map[uint]*models.Task, len(tasks))
+	var ready []*models.Task
+	for _, task := range tasks {
+		byID[task.ID] = task
+		if waitingOn[task.ID] == 0 {
+			ready = append(ready, task)
+		}
+	}
+
+	ordered := make([]*models.Task, 0, len(tasks))
+	for len(ready) > 0 {
+		sortByUrgency(ready)
+		ordered = append(ordered, ready...)
+
+		var next []*models.Task
+		for _, done := range ready {
+			for _, id := range unblocks[done.ID] {
+				waitingOn[id]--
+				if waitingOn[id] == 0 && byID[id] != nil {
+					next = append(next, byID[id])
+				}
+			}
+		}
+		ready = next
+	}
+
+	return t.toResponses(ordered)
+}
+
+// sortByUrgency orders tasks by priority, then due date (undated last), then creation time
+func sortByUrgency(tasks []*models.Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+
+		if priorityRank[a.Priority] != priorityRank[b.Priority] {
+			return priorityRank[a.Priority] < priorityRank[b.Priority]
+		}
+
+		switch {
+		case a.DueDate != nil && b.DueDate != nil && !a.DueDate.Equal(*b.DueDate):
+			return a.DueDate.Before(*b.DueDate)
+		case (a.DueDate == nil) != (b.DueDate == nil):
+			return a.DueDate != nil
+		}
+
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+}
+
+// checkNotBlocked returns ErrTaskBlocked while the task has open blockers
+func (t *taskService) checkNotBlocked(taskID uint) error {
+	blocked, err := t.dependencyRepo.BlockedTaskIDs([]uint{taskID})
+	if err != nil {
+		return err
+	}
+
+	if blocked[taskID] {
+		return ErrTaskBlocked
+	}
+
+	return nil
+}
+
+// resolveTags looks up the user's tags by name, creating the ones that do not exist yet
+func (t *taskService) resolveTags(userID uint, names []string) ([]models.Tag, error) {
+	names, err := normalizeTagNames(names)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.tagRepo.FindOrCreate(userID, names)
 }
 
 func NewTaskService(
+	db *gorm.DB,
 	userRepo repository.UserRepository,
 	taskRepo repository.TaskRepository,
+	tagRepo repository.TagRepository,
+	projectRepo repository.ProjectRepository,
+	dependencyRepo repository.TaskDependencyRepository,
+	reminderRepo repository.ReminderRepository,
+	revisionRepo repository.TaskRevisionRepository,
 ) TaskService {
 	return &taskService{
-		userRepo: userRepo,
-		taskRepo: taskRepo,
+		db:             db,
+		userRepo:       userRepo,
+		taskRepo:       taskRepo,
+		tagRepo:        tagRepo,
+		projectRepo:    projectRepo,
+		dependencyRepo: dependencyRepo,
+		reminderRepo:   reminderRepo,
+		revisionRepo:   revisionRepo,
 	}
 }
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+)
+
+// fakeTaskRepository keeps tasks in memory and checks versions like the database does
+type fakeTaskRepository struct {
+	repository.TaskRepository
+	tasks   map[uint]*models.Task
+	trashed map[uint]*models.Task
+	nextID  uint
+}
+
+func newFakeTaskRepository() *fakeTaskRepository {
+	return &fakeTaskRepository{tasks: make(map[uint]*models.Task), trashed: make(map[uint]*models.Task)}
+}
+
+func (f *fakeTaskRepository) Create(task *models.Task) error {
+	f.nextID++
+	task.ID = f.nextID
+	task.Version = 1
+	stored := *task
+	f.tasks[task.ID] = &stored
+	return nil
+}
+
+func (f *fakeTaskRepository) GetById(id, userID uint) (*models.Task, error) {
+	task, ok := f.tasks[id]
+	if !ok || task.UserID != userID {
+		return nil, repository.ErrTaskNotFound
+	}
+	found := *task
+	return &found, nil
+}
+
+func (f *fakeTaskRepository) Update(task *models.Task) error {
+	stored, ok := f.tasks[task.ID]
+	if !ok || stored.Version != task.Version {
+		return repository.ErrTaskVersionConflict
+	}
+	task.Version++
+	updated := *task
+	f.tasks[task.ID] = &updated
+	return nil
+}
+
+func (f *fakeTaskRepository) ReplaceTags(task *models.Task, tags []models.Tag) error {
+	f.tasks[task.ID].Tags = tags
+	return nil
+}
+
+func (f *fakeTaskRepository) Delete(task *models.Task) error {
+	stored, ok := f.tasks[task.ID]
+	if !ok || stored.Version != task.Version {
+		return repository.ErrTaskVersionConflict
+	}
+	delete(f.tasks, task.ID)
+	f.trashed[task.ID] = stored
+	return nil
+}
+
+func (f *fakeTaskRepository) GetTrashed(id, userID uint) (*models.Task, error) {
+	task, ok := f.trashed[id]
+	if !ok || task.UserID != userID {
+		return nil, repository.ErrTaskNotFound
+	}
+	found := *task
+	return &found, nil
+}
+
+// Restore only restores task itself; like the database it needs a live parent
+func (f *fakeTaskRepository) Restore(task *models.Task) error {
+	stored, ok := f.trashed[task.ID]
+	if !ok || stored.Version != task.Version {
+		return repository.ErrTaskVersionConflict
+	}
+	if task.ParentID != nil && f.tasks[*task.ParentID] == nil {
+		return repository.ErrTaskVersionConflict
+	}
+	delete(f.trashed, task.ID)
+	stored.Version++
+	f.tasks[task.ID] = stored
+	return nil
+}
+
+func (f *fakeTaskRepository) SubtaskProgress(parentIDs []uint) (map[uint]*models.SubtaskProgress, error) {
+	progress := make(map[uint]*models.SubtaskProgress)
+	for _, parentID := range parentIDs {
+		for _, task := range f.tasks {
+			if task.ParentID == nil || *task.ParentID != parentID {
+				continue
+			}
+			if progress[parentID] == nil {
+				progress[parentID] = &models.SubtaskProgress{}
+			}
+			progress[parentID].Total++
+			if task.Completed {
+				progress[parentID].Completed++
+			}
+		}
+	}
+	return progress, nil
+}
+
+// fakeReminderRepository has no reminders to move
+type fakeReminderRepository struct {
+	repository.ReminderRepository
+}
+
+func (f *fakeReminderRepository) RescheduleForTask(taskID uint, due time.Time) error {
+	return nil
+}
+
+func (f *fakeReminderRepository) CarryOver(fromTaskID uint, to *models.Task) error {
+	return nil
+}
+
+// fakeTaskRevisionRepository keeps the recorded revisions
+type fakeTaskRevisionRepository struct {
+	repository.TaskRevisionRepository
+	revisions []models.TaskRevision
+}
+
+func (f *fakeTaskRevisionRepository) Create(revision *models.TaskRevision) error {
+	f.revisions = append(f.revisions, *revision)
+	return nil
+}
+
+// fakeTaskDependencyRepository blocks nothing
+type fakeTaskDependencyRepository struct {
+	repository.TaskDependencyRepository
+}
+
+func (f *fakeTaskDependencyRepository) BlockedTaskIDs(taskIDs []uint) (map[uint]bool, error) {
+	return map[uint]bool{}, nil
+}
+
+// newTaskFixture returns a task service already inside its transaction, as save expects
+func newTaskFixture() (*taskService, *fakeTaskRepository) {
+	tasks := newFakeTaskRepository()
+	return &taskService{
+		taskRepo:       tasks,
+		dependencyRepo: &fakeTaskDependencyRepository{},
+		reminderRepo:   &fakeReminderRepository{},
+		revisionRepo:   &fakeTaskRevisionRepository{},
+	}, tasks
+}
+
+// completeTask marks a stored task completed through save
+func completeTask(t *testing.T, service *taskService, tasks *fakeTaskRepository, id uint) *models.Task {
+	t.Helper()
+
+	task, err := tasks.GetById(id, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := *task
+	task.Completed = true
+	next, err := service.save(task, &before, nil, byUser(1, models.RevisionActionUpdate))
+	if err != nil {
+		t.Fatalf("completing task %d: %v", id, err)
+	}
+	return next
+}
+
+func TestCompletingSubtaskAutoCompletesParent(t *testing.T) {
+	due := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		recurrence    string
+		wantCompleted bool
+	}{
+		{name: "one-off subtask", wantCompleted: true},
+		// the next occurrence is an open subtask, so the parent stays open
+		{name: "recurring subtask", recurrence: "FREQ=DAILY", wantCompleted: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, tasks := newTaskFixture()
+
+			parent := &models.Task{UserID: 1, Title: "parent", AutoComplete: true}
+			if err := tasks.Create(parent); err != nil {
+				t.Fatal(err)
+			}
+
+			subtask := &models.Task{UserID: 1, ParentID: &parent.ID, Title: "subtask", DueDate: &due}
+			if tt.recurrence != "" {
+				subtask.Recurrence = tt.recurrence
+				subtask.RecurrenceTZ = "UTC"
+				subtask.SeriesStart = &due
+			}
+			if err := tasks.Create(subtask); err != nil {
+				t.Fatal(err)
+			}
+
+			next := completeTask(t, service, tasks, subtask.ID)
+
+			stored, _ := tasks.GetById(parent.ID, 1)
+			if stored.Completed != tt.wantCompleted {
+				t.Errorf("parent completed = %v, want %v", stored.Completed, tt.wantCompleted)
+			}
+
+			if tt.recurrence == "" {
+				return
+			}
+			if next == nil || next.ParentID == nil || *next.ParentID != parent.ID || next.Completed {
+				t.Fatalf("next occurrence = %+v, want an open subtask of %d", next, parent.ID)
+			}
+
+			// completing the new occurrence moves the series on again
+			completeTask(t, service, tasks, next.ID)
+			if stored, _ := tasks.GetById(parent.ID, 1); stored.Completed {
+				t.Errorf("parent completed while the series still has an open occurrence")
+			}
+		})
+	}
+}
+
+func TestRestoreSubtaskNeedsLiveParent(t *testing.T) {
+	tasks := newFakeTaskRepository()
+	trash := NewTrashService(tasks, 0)
+
+	parent := &models.Task{UserID: 1, Title: "parent"}
+	if err := tasks.Create(parent); err != nil {
+		t.Fatal(err)
+	}
+	subtask := &models.Task{UserID: 1, ParentID: &parent.ID, Title: "subtask"}
+	if err := tasks.Create(subtask); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, task := range []*models.Task{subtask, parent} {
+		if err := tasks.Delete(task); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := trash.RestoreTask(subtask.ID, 1, nil); !errors.Is(err, ErrParentTaskTrashed) {
+		t.Fatalf("restoring under a trashed parent: err = %v, want ErrParentTaskTrashed", err)
+	}
+
+	if err := trash.RestoreTask(parent.ID, 1, nil); err != nil {
+		t.Fatalf("restoring the parent: %v", err)
+	}
+	if err := trash.RestoreTask(subtask.ID, 1, nil); err != nil {
+		t.Errorf("restoring under a live parent: %v", err)
+	}
+}
@@ -0,0 +1,353 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/config"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/utils"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrInvalidAccessToken  = errors.New("invalid or expired token")
+	ErrTokenRevoked        = errors.New("token has been revoked")
+)
+
+// TokenService issues access/refresh token pairs, rotates refresh tokens
+// and decides whether an access token is still acceptable.
+type TokenService interface {
+	IssueTokens(user *models.User, client *models.ClientInfo) (*models.AuthResponse, error)
+	Refresh(refreshToken string, client *models.ClientInfo) (*models.AuthResponse, error)
+	ValidateAccessToken(tokenString string) (*utils.Claims, error)
+	Logout(claims *utils.Claims, refreshToken string) error
+	LogoutAll(userID uint) error
+	ValidateMFAChallenge(tokenString string) (*utils.Claims, error)
+	ConsumeMFAChallenge(claims *utils.Claims) error
+}
+
+type tokenService struct {
+	userRepo         repository.UserRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+	revokedTokenRepo repository.RevokedTokenRepository
+	sessionService   SessionService
+	cache            *revocationCache
+}
+
+func refreshTokenTTL() time.Duration {
+	return time.Duration(config.AppConfig.RefreshTokenDays) * 24 * time.Hour
+}
+
+// newRefreshToken builds a refresh token record and returns it along with the raw token value
+func newRefreshToken(userID uint, familyID string) (*models.RefreshToken, string, error) {
+	raw, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &models.RefreshToken{
+		UserID:    userID,
+		TokenHash: utils.HashToken(raw),
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(refreshTokenTTL()),
+	}, raw, nil
+}
+
+func (t *tokenService) buildResponse(user *models.User, refreshToken, sessionID string) (*models.AuthResponse, error) {
+	accessToken, err := utils.GenerateToken(user.ID, user.Email, user.Role, sessionID)
+	if err != nil {
+		return nil, errors.New("Failed to generate token")
+	}
+
+	return &models.AuthResponse{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		SessionID:        sessionID,
+		User:             user.ToResponse(),
+		ExpiresIn:        int(utils.AccessTokenTTL().Seconds()),
+		RefreshExpiresIn: int(refreshTokenTTL().Seconds()),
+		TokenType:        "Bearer",
+	}, nil
+}
+
+// IssueTokens implements TokenService.
+// Every call starts a new refresh token family, which doubles as the session ID.
+func (t *tokenService) IssueTokens(user *models.User, client *models.ClientInfo) (*models.AuthResponse, error) {
+	familyID, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	record, raw, err := newRefreshToken(user.ID, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.refreshTokenRepo.Create(record); err != nil {
+		return nil, err
+	}
+
+	if err := t.sessionService.Start(user.ID, familyID, client, record.ExpiresAt); err != nil {
+		return nil, err
+	}
+
+	return t.buildResponse(user, raw, familyID)
+}
+
+// Refresh implements TokenService.
+func (t *tokenService) Refresh(refreshToken string, client *models.ClientInfo) (*models.AuthResponse, error) {
+	current, err := t.refreshTokenRepo.GetByHash(utils.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	// A rotated token showing up again means it has leaked: kill the whole family.
+	if current.UsedAt != nil {
+		return nil, t.revokeFamilyOnReuse(current)
+	}
+
+	if !current.IsActive(time.Now()) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := t.userRepo.GetByID(current.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	if user.IsDisabled() {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	next, raw, err := newRefreshToken(user.ID, current.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.refreshTokenRepo.Rotate(current, next); err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenConsumed) {
+			// lost a race against another refresh with the same token
+			return nil, t.revokeFamilyOnReuse(current)
+		}
+		return nil, err
+	}
+
+	if err := t.sessionService.Extend(user.ID, current.FamilyID, client, next.ExpiresAt); err != nil {
+		return nil, err
+	}
+
+	return t.buildResponse(user, raw, current.FamilyID)
+}
+
+func (t *tokenService) revokeFamilyOnReuse(token *models.RefreshToken) error {
+	log.Printf("Refresh token reuse detected for user %d, revoking family %s", token.UserID, token.FamilyID)
+
+	if err := t.refreshTokenRepo.RevokeFamily(token.FamilyID); err != nil {
+		return err
+	}
+
+	if err := t.sessionService.Revoke(token.UserID, token.FamilyID); err != nil && !errors.Is(err, repository.ErrSessionNotFound) {
+		return err
+	}
+
+	return ErrRefreshTokenReused
+}
+
+// ValidateAccessToken implements TokenService.
+func (t *tokenService) ValidateAccessToken(tokenString string) (*utils.Claims, error) {
+	claims, err := utils.ValidateToken(tokenString)
+	if err != nil || claims.ID == "" || claims.IssuedAt == nil || claims.ExpiresAt == nil {
+		return nil, ErrInvalidAccessToken
+	}
+
+	revoked, err := t.isTokenRevoked(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	if claims.SessionID != "" {
+		active, err := t.sessionService.IsActive(claims.SessionID)
+		if err != nil {
+			return nil, err
+		}
+
+		if !active {
+			return nil, ErrTokenRevoked
+		}
+	}
+
+	cutoff, err := t.userCutoff(claims.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrInvalidAccessToken
+		}
+		return nil, err
+	}
+
+	// iat only has second precision, so a token from the cutoff's own second is still accepted:
+	// tokens signed before the cutoff in that second belonged to sessions it already revoked
+	if cutoff != nil && claims.IssuedAt.Before(cutoff.Truncate(time.Second)) {
+		return nil, ErrTokenRevoked
+	}
+
+	return claims, nil
+}
+
+func (t *tokenService) isTokenRevoked(claims *utils.Claims) (bool, error) {
+	if revoked, ok := t.cache.getToken(claims.ID); ok {
+		return revoked, nil
+	}
+
+	revoked, err := t.revokedTokenRepo.IsRevoked(claims.ID)
+	if err != nil {
+		return false, err
+	}
+
+	t.cache.setToken(claims.ID, revoked, claims.ExpiresAt.Time)
+	return revoked, nil
+}
+
+func (t *tokenService) userCutoff(userID uint) (*time.Time, error) {
+	if cutoff, ok := t.cache.getCutoff(userID); ok {
+		return cutoff, nil
+	}
+
+	user, err := t.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// disabling an account invalidates every token issued before that moment
+	cutoff := user.TokensValidAfter
+	if user.DisabledAt != nil && (cutoff == nil || user.DisabledAt.After(*cutoff)) {
+		cutoff = user.DisabledAt
+	}
+
+	t.cache.setCutoff(userID, cutoff)
+	return cutoff, nil
+}
+
+// Logout implements TokenService.
+// The access token is revoked by jti and its session is ended. A refresh token given
+// explicitly has its family revoked too, which covers tokens issued before sessions existed.
+func (t *tokenService) Logout(claims *utils.Claims, refreshToken string) error {
+	if err := t.revokedTokenRepo.Create(&models.RevokedToken{
+		JTI:       claims.ID,
+		UserID:    claims.UserID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}); err != nil {
+		return err
+	}
+	t.cache.setToken(claims.ID, true, claims.ExpiresAt.Time)
+
+	if claims.SessionID != "" {
+		if err := t.sessionService.Revoke(claims.UserID, claims.SessionID); err != nil && !errors.Is(err, repository.ErrSessionNotFound) {
+			return err
+		}
+	}
+
+	if refreshToken != "" {
+		record, err := t.refreshTokenRepo.GetByHash(utils.HashToken(refreshToken))
+		if err != nil && !errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return err
+		}
+
+		if record != nil && record.UserID == claims.UserID {
+			if err := t.refreshTokenRepo.RevokeFamily(record.FamilyID); err != nil {
+				return err
+			}
+		}
+	}
+
+	// housekeeping: revoked entries are useless once the token has expired
+	if err := t.revokedTokenRepo.DeleteExpired(); err != nil {
+		log.Printf("Failed to clean up expired revoked tokens: %v", err)
+	}
+
+	return nil
+}
+
+// LogoutAll implements TokenService.
+func (t *tokenService) LogoutAll(userID uint) error {
+	user, err := t.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Truncate(time.Second)
+	user.TokensValidAfter = &now
+	if err := t.userRepo.Update(user); err != nil {
+		return err
+	}
+	t.cache.setCutoff(userID, &now)
+
+	return t.sessionService.RevokeAll(userID)
+}
+
+// ValidateMFAChallenge implements TokenService.
+// Spent challenges are rejected before any second factor is looked at.
+func (t *tokenService) ValidateMFAChallenge(tokenString string) (*utils.Claims, error) {
+	claims, err := utils.ValidateMFAChallengeToken(tokenString)
+	if err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	spent, err := t.revokedTokenRepo.IsRevoked(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if spent {
+		return nil, ErrInvalidMFAToken
+	}
+
+	return claims, nil
+}
+
+// ConsumeMFAChallenge implements TokenService.
+// Only one request can consume a challenge, however many pass ValidateMFAChallenge at once.
+func (t *tokenService) ConsumeMFAChallenge(claims *utils.Claims) error {
+	consumed, err := t.revokedTokenRepo.Consume(&models.RevokedToken{
+		JTI:       claims.ID,
+		UserID:    claims.UserID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !consumed {
+		return ErrInvalidMFAToken
+	}
+
+	return nil
+}
+
+func NewTokenService(
+	userRepo repository.UserRepository,
+	refreshTokenRepo repository.RefreshTokenRepository,
+	revokedTokenRepo repository.RevokedTokenRepository,
+	sessionService SessionService,
+) TokenService {
+	return &tokenService{
+		userRepo:         userRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		revokedTokenRepo: revokedTokenRepo,
+		sessionService:   sessionService,
+		cache:            newRevocationCache(),
+	}
+}
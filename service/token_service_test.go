@@ -0,0 +1,63 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+)
+
+func TestLogoutAllKeepsLaterLogins(t *testing.T) {
+	f := newAuthFixture(t)
+	user := f.createUser(t, "user@example.com", "", models.RoleUser)
+	tokens := newTestTokenService(f.users)
+
+	before, err := tokens.IssueTokens(user, f.client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokens.ValidateAccessToken(before.AccessToken); err != nil {
+		t.Fatalf("token before logout-all: %v", err)
+	}
+
+	if err := tokens.LogoutAll(user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// the old token is rejected even when it was signed in the same second as the cutoff
+	if _, err := tokens.ValidateAccessToken(before.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("token before logout-all: err = %v, want ErrTokenRevoked", err)
+	}
+
+	after, err := tokens.IssueTokens(user, f.client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokens.ValidateAccessToken(after.AccessToken); err != nil {
+		t.Errorf("login right after logout-all: %v", err)
+	}
+}
+
+func TestReenabledUserCanSignIn(t *testing.T) {
+	f := newAuthFixture(t)
+	admin := f.createUser(t, "admin@example.com", "", models.RoleAdmin)
+	member := f.createUser(t, "member@example.com", "", models.RoleUser)
+
+	tokens := newTestTokenService(f.users)
+	admins := NewAdminService(f.users, &fakeRoleService{}, tokens, f.audit)
+
+	if _, err := admins.DisableUser(admin.ID, member.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admins.EnableUser(admin.ID, member.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	auth, err := tokens.IssueTokens(member, f.client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokens.ValidateAccessToken(auth.AccessToken); err != nil {
+		t.Errorf("login right after re-enabling: %v", err)
+	}
+}
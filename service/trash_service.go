@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+)
+
+// trashPurgeBatch is how many trashed tasks the retention job purges per transaction
+const trashPurgeBatch = 500
+
+var ErrParentTaskTrashed = errors.New("the parent task is in the trash, restore it first")
+
+type TrashService interface {
+	ListTrash(userID uint, page, pageSize int) (*models.PaginationResponse, error)
+	GetTrashedTask(taskID, userID uint) (*models.TrashedTaskResponse, error)
+	RestoreTask(taskID, userID uint, version *uint) error
+	PurgeTask(taskID, userID uint) error
+	EmptyTrash(userID uint) (int64, error)
+}
+
+type trashService struct {
+	taskRepo  repository.TaskRepository
+	retention time.Duration
+}
+
+// ListTrash implements TrashService.
+func (t *trashService) ListTrash(userID uint, page int, pageSize int) (*models.PaginationResponse, error) {
+	tasks, total, err := t.taskRepo.ListTrash(userID, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.TrashedTaskResponse, 0, len(tasks))
+	for _, task := range tasks {
+		responses = append(responses, t.toResponse(task))
+	}
+
+	totalPages := (total + int64(pageSize) - 1) / int64(pageSize)
+
+	return &models.PaginationResponse{
+		Data:       responses,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// toResponse adds when the trashed task was deleted and when it will be purged
+func (t *trashService) toResponse(task *models.Task) models.TrashedTaskResponse {
+	response := models.TrashedTaskResponse{
+		TaskResponse: task.ToResponse(),
+		DeletedAt:    task.DeletedAt.Time,
+	}
+	if t.retention > 0 {
+		purgeAt := task.DeletedAt.Time.Add(t.retention)
+		response.PurgeAt = &purgeAt
+	}
+
+	return response
+}
+
+// GetTrashedTask implements TrashService.
+func (t *trashService) GetTrashedTask(taskID uint, userID uint) (*models.TrashedTaskResponse, error) {
+	task, err := t.taskRepo.GetTrashed(taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := t.toResponse(task)
+	return &response, nil
+}
+
+// RestoreTask implements TrashService.
+// A subtask can only come back under a live parent.
+func (t *trashService) RestoreTask(taskID uint, userID uint, version *uint) error {
+	task, err := t.taskRepo.GetTrashed(taskID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := checkVersion(task, version); err != nil {
+		return err
+	}
+
+	if err := t.checkParentLive(task); err != nil {
+		return err
+	}
+
+	// the restore itself requires a live parent, in case it was trashed meanwhile
+	if err := t.taskRepo.Restore(task); err != nil {
+		if errors.Is(err, repository.ErrTaskVersionConflict) {
+			if parentErr := t.checkParentLive(task); parentErr != nil {
+				return parentErr
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// checkParentLive returns ErrParentTaskTrashed when task is a subtask of a trashed task
+func (t *trashService) checkParentLive(task *models.Task) error {
+	if task.ParentID == nil {
+		return nil
+	}
+
+	if _, err := t.taskRepo.GetById(*task.ParentID, task.UserID); err != nil {
+		if errors.Is(err, repository.ErrTaskNotFound) {
+			return ErrParentTaskTrashed
+		}
+		return err
+	}
+
+	return nil
+}
+
+// PurgeTask implements TrashService.
+func (t *trashService) PurgeTask(taskID uint, userID uint) error {
+	return t.taskRepo.Purge(taskID, userID)
+}
+
+// EmptyTrash implements TrashService.
+func (t *trashService) EmptyTrash(userID uint) (int64, error) {
+	return t.taskRepo.EmptyTrash(userID)
+}
+
+// NewTrashService returns a TrashService; a zero retention keeps trashed tasks until purged by hand.
+func NewTrashService(taskRepo repository.TaskRepository, retention time.Duration) TrashService {
+	return &trashService{
+		taskRepo:  taskRepo,
+		retention: retention,
+	}
+}
+
+// TrashPurger permanently deletes tasks that have been in the trash longer than the retention period
+type TrashPurger struct {
+	taskRepo  repository.TaskRepository
+	retention time.Duration
+	interval  time.Duration
+}
+
+func NewTrashPurger(taskRepo repository.TaskRepository, retention, interval time.Duration) *TrashPurger {
+	return &TrashPurger{
+		taskRepo:  taskRepo,
+		retention: retention,
+		interval:  interval,
+	}
+}
+
+// Run implements Job.
+func (p *TrashPurger) Run(ctx context.Context) {
+	log.Printf("Trash purger started, purging tasks trashed more than %s ago every %s", p.retention, p.interval)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Print("Trash purger stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purge works through expired tasks in batches so a large backlog does not hold one long transaction
+func (p *TrashPurger) purge(ctx context.Context) {
+	cutoff := time.Now().Add(-p.retention)
+
+	var total int64
+	for ctx.Err() == nil {
+		purged, err := p.taskRepo.PurgeTrashedBefore(cutoff, trashPurgeBatch)
+		if err != nil {
+			log.Printf("Failed to purge trashed tasks: %v", err)
+			break
+		}
+
+		total += purged
+		if purged < trashPurgeBatch {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Printf("Purged %d trashed tasks", total)
+	}
+}
@@ -2,6 +2,8 @@ package service
 
 import (
 	"errors"
+	"log"
+	"time"
 
 	"github.com/lieucongduy182/go-gin-todo-api/models"
 	"github.com/lieucongduy182/go-gin-todo-api/repository"
@@ -10,47 +12,128 @@ import (
 
 // UserService interface the business logic for users
 type UserService interface {
-	Register(req *models.RegisterRequest) (*models.AuthResponse, error)
-	Login(req *models.LoginRequest) (*models.AuthResponse, error)
+	Register(req *models.RegisterRequest, client *models.ClientInfo) (*models.AuthResponse, error)
+	// Login returns either tokens or, for users with two-factor enabled, an MFA challenge
+	Login(req *models.LoginRequest, client *models.ClientInfo) (*models.AuthResponse, *models.MFAChallengeResponse, error)
 	GetProfile(userID uint) (*models.UserResponse, error)
-	ChangePassword(userID uint, req *models.ChangePasswordRequest) error
-	UpdateProfile(userID uint, username string) error
-	DeleteAccount(userID uint) error
+	// ChangePassword signs out every session and returns fresh tokens for the caller
+	ChangePassword(userID uint, req *models.ChangePasswordRequest, client *models.ClientInfo) (*models.AuthResponse, error)
+	UpdateProfile(userID uint, req *models.UpdateProfileRequest) (*models.UserResponse, error)
+	DeleteAccount(userID uint, req *models.DeleteAccountRequest, sessionID string, client *models.ClientInfo) error
 	GetAllUsers(page, pageSize int) (*models.PaginationResponse, error)
 }
 
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrIncorrectPassword  = errors.New("current password incorrect")
+	ErrUserAlreadyExists  = errors.New("user with this email or username already exists")
+	ErrUsernameTaken      = errors.New("username already taken")
+	ErrAccountDisabled    = errors.New("account has been disabled")
+	ErrRecentLoginNeeded  = errors.New("sign in again to confirm this action")
+)
+
+// recentLoginWindow is how fresh a session must be to stand in for a password
+const recentLoginWindow = 10 * time.Minute
+
 type userService struct {
-	userRepo repository.UserRepository
-	taskRepo repository.TaskRepository
+	userRepo            repository.UserRepository
+	taskRepo            repository.TaskRepository
+	tokenService        TokenService
+	verificationService EmailVerificationService
+	mfaService          MFAService
+	loginThrottle       LoginThrottleService
+	sessionService      SessionService
 }
 
 // ChangePassword implements UserService.
-func (u *userService) ChangePassword(userID uint, req *models.ChangePasswordRequest) error {
+// Like a password reset it ends every session, so whoever knew the old password is signed out.
+func (u *userService) ChangePassword(userID uint, req *models.ChangePasswordRequest, client *models.ClientInfo) (*models.AuthResponse, error) {
 	user, err := u.userRepo.GetByID(userID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if err := user.CheckPassword(req.OldPassword); err != nil {
-		return errors.New("current password incorrect")
+	if err := u.confirmPassword(user, req.OldPassword, client); err != nil {
+		return nil, err
+	}
+
+	if err := utils.ValidatePassword(req.NewPassword, user.Username, user.Email); err != nil {
+		return nil, err
 	}
 
 	if err := user.HashPassword(req.NewPassword); err != nil {
-		return errors.New("failed to hash password")
+		return nil, errors.New("failed to hash password")
+	}
+
+	if err := u.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	if err := u.tokenService.LogoutAll(user.ID); err != nil {
+		return nil, err
 	}
 
-	return u.userRepo.Update(user)
+	return u.tokenService.IssueTokens(user, client)
 }
 
 // DeleteAccount implements UserService.
-func (u *userService) DeleteAccount(userID uint) error {
-	if err := u.taskRepo.DeleteByUserId(userID); err != nil {
+// The password confirms the deletion; accounts without one need a session that signed in recently.
+// The repository removes the user's tasks in the same transaction as the user.
+func (u *userService) DeleteAccount(userID uint, req *models.DeleteAccountRequest, sessionID string, client *models.ClientInfo) error {
+	user, err := u.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.HasPassword() {
+		if err := u.confirmPassword(user, req.Password, client); err != nil {
+			return err
+		}
+	} else if err := u.checkRecentLogin(user, sessionID); err != nil {
 		return err
 	}
 
 	return u.userRepo.Delete(userID)
 }
 
+// confirmPassword checks the password of a signed in user under the login throttle,
+// so a stolen session cannot be used to guess it
+func (u *userService) confirmPassword(user *models.User, password string, client *models.ClientInfo) error {
+	if err := u.loginThrottle.Check(user.Email, client.IP); err != nil {
+		return err
+	}
+
+	if err := user.CheckPassword(password); err != nil {
+		if err := u.loginThrottle.RecordFailure(user.Email, client.IP, user); err != nil {
+			return err
+		}
+		return ErrIncorrectPassword
+	}
+
+	return nil
+}
+
+// checkRecentLogin requires the session to have signed in within recentLoginWindow
+func (u *userService) checkRecentLogin(user *models.User, sessionID string) error {
+	if sessionID == "" {
+		return ErrRecentLoginNeeded
+	}
+
+	startedAt, err := u.sessionService.StartedAt(user.ID, sessionID)
+	if err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			return ErrRecentLoginNeeded
+		}
+		return err
+	}
+
+	if time.Since(startedAt) > recentLoginWindow {
+		return ErrRecentLoginNeeded
+	}
+
+	return nil
+}
+
 // GetAllUsers implements UserService.
 func (u *userService) GetAllUsers(page int, pageSize int) (*models.PaginationResponse, error) {
 	users, total, err := u.userRepo.GetAll(page, pageSize)
@@ -59,7 +142,7 @@ func (u *userService) GetAllUsers(page int, pageSize int) (*models.PaginationRes
 	}
 
 	// convert to response DTOs
-	var responses []models.UserResponse
+	responses := make([]models.UserResponse, 0, len(users))
 	for _, user := range users {
 		responses = append(responses, user.ToResponse())
 	}
@@ -87,38 +170,95 @@ func (u *userService) GetProfile(userID uint) (*models.UserResponse, error) {
 }
 
 // Login implements UserService.
-func (u *userService) Login(req *models.LoginRequest) (*models.AuthResponse, error) {
+// Failures are throttled per email and per client IP, whether or not the email exists.
+func (u *userService) Login(req *models.LoginRequest, client *models.ClientInfo) (*models.AuthResponse, *models.MFAChallengeResponse, error) {
+	if err := u.loginThrottle.Check(req.Email, client.IP); err != nil {
+		return nil, nil, err
+	}
+
 	user, err := u.userRepo.GetByEmail(req.Email)
 	if err != nil {
-		return nil, errors.New("invalid email or password")
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, nil, u.loginFailed(req.Email, client.IP, nil)
+		}
+		return nil, nil, err
 	}
 
 	if err := user.CheckPassword(req.Password); err != nil {
-		return nil, errors.New("invalid email or password")
+		return nil, nil, u.loginFailed(req.Email, client.IP, user)
 	}
 
-	token, err := utils.GenerateToken(user.ID, user.Email)
+	u.upgradePasswordHash(user, req.Password)
+
+	if user.IsDisabled() {
+		return nil, nil, ErrAccountDisabled
+	}
+
+	// the counters are only cleared once the second factor passes too,
+	// otherwise knowing the password would reset the budget for guessing codes
+	required, err := u.mfaService.RequiresSecondFactor(user)
 	if err != nil {
-		return nil, errors.New("Failed to generate token")
+		return nil, nil, err
 	}
 
-	return &models.AuthResponse{
-		Token:     token,
-		User:      user.ToResponse(),
-		ExpiresIn: 86400,
-		TokenType: "Bearer",
-	}, nil
+	if required {
+		challenge, err := u.mfaService.NewChallenge(user)
+		return nil, challenge, err
+	}
+
+	if err := u.loginThrottle.RecordSuccess(req.Email, client.IP, user); err != nil {
+		return nil, nil, err
+	}
+
+	response, err := u.tokenService.IssueTokens(user, client)
+	return response, nil, err
+}
+
+// upgradePasswordHash rehashes a legacy or outdated hash while the plain password is at hand.
+// Failure is only logged; the old hash keeps working.
+func (u *userService) upgradePasswordHash(user *models.User, password string) {
+	if !user.PasswordNeedsRehash() {
+		return
+	}
+
+	oldHash := user.Password
+	if err := user.HashPassword(password); err != nil {
+		log.Printf("Failed to rehash password for user %d: %v", user.ID, err)
+		return
+	}
+
+	if err := u.userRepo.ReplacePasswordHash(user.ID, oldHash, user.Password); err != nil {
+		log.Printf("Failed to store rehashed password for user %d: %v", user.ID, err)
+	}
+}
+
+func (u *userService) loginFailed(email, clientIP string, user *models.User) error {
+	if err := u.loginThrottle.RecordFailure(email, clientIP, user); err != nil {
+		return err
+	}
+
+	return ErrInvalidCredentials
 }
 
 // Register implements UserService.
-func (u *userService) Register(req *models.RegisterRequest) (*models.AuthResponse, error) {
+func (u *userService) Register(req *models.RegisterRequest, client *models.ClientInfo) (*models.AuthResponse, error) {
 	exists, err := u.userRepo.UserExists(req.Email)
 	if err != nil {
 		return nil, err
 	}
 
 	if exists {
-		return nil, errors.New("User with this email existing")
+		return nil, ErrUserAlreadyExists
+	}
+
+	if err := utils.ValidatePassword(req.Password, req.Username, req.Email); err != nil {
+		return nil, err
+	}
+
+	if _, err := u.userRepo.GetByUsername(req.Username); err == nil {
+		return nil, ErrUserAlreadyExists
+	} else if !errors.Is(err, repository.ErrUserNotFound) {
+		return nil, err
 	}
 
 	user := &models.User{
@@ -134,36 +274,55 @@ func (u *userService) Register(req *models.RegisterRequest) (*models.AuthRespons
 		return nil, errors.New("Failed to create user")
 	}
 
-	token, err := utils.GenerateToken(user.ID, user.Email)
-	if err != nil {
-		return nil, errors.New("Failed to generate token")
+	if err := u.verificationService.SendVerification(user); err != nil {
+		return nil, err
 	}
 
-	return &models.AuthResponse{
-		Token:     token,
-		User:      user.ToResponse(),
-		ExpiresIn: 86400, // 24 hours
-		TokenType: "Bearer",
-	}, nil
+	return u.tokenService.IssueTokens(user, client)
 }
 
 // UpdateProfile implements UserService.
-func (u *userService) UpdateProfile(userID uint, username string) error {
+func (u *userService) UpdateProfile(userID uint, req *models.UpdateProfileRequest) (*models.UserResponse, error) {
 	user, err := u.userRepo.GetByID(userID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	user.Username = username
-	return u.userRepo.Update(user)
+	if req.Username != user.Username {
+		existing, err := u.userRepo.GetByUsername(req.Username)
+		if err == nil && existing.ID != user.ID {
+			return nil, ErrUsernameTaken
+		}
+		if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+			return nil, err
+		}
+	}
+
+	user.Username = req.Username
+	if err := u.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	response := user.ToResponse()
+	return &response, nil
 }
 
 func NewUserService(
 	userRepo repository.UserRepository,
 	taskRepo repository.TaskRepository,
+	tokenService TokenService,
+	verificationService EmailVerificationService,
+	mfaService MFAService,
+	loginThrottle LoginThrottleService,
+	sessionService SessionService,
 ) UserService {
 	return &userService{
-		userRepo: userRepo,
-		taskRepo: taskRepo,
+		userRepo:            userRepo,
+		taskRepo:            taskRepo,
+		tokenService:        tokenService,
+		verificationService: verificationService,
+		mfaService:          mfaService,
+		loginThrottle:       loginThrottle,
+		sessionService:      sessionService,
 	}
 }
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+)
+
+// fakeSessionService knows when each session signed in
+type fakeSessionService struct {
+	SessionService
+	started map[string]time.Time
+}
+
+func (f *fakeSessionService) StartedAt(userID uint, sessionID string) (time.Time, error) {
+	startedAt, ok := f.started[sessionID]
+	if !ok {
+		return time.Time{}, repository.ErrSessionNotFound
+	}
+	return startedAt, nil
+}
+
+type userFixture struct {
+	*authFixture
+	service  UserService
+	sessions *fakeSessionService
+}
+
+func newUserFixture(t *testing.T) *userFixture {
+	t.Helper()
+
+	f := &userFixture{
+		authFixture: newAuthFixture(t),
+		sessions:    &fakeSessionService{started: make(map[string]time.Time)},
+	}
+	f.service = NewUserService(f.users, nil, f.tokens, nil, nil, f.throttle, f.sessions)
+	return f
+}
+
+func TestChangePasswordSignsOutEverywhere(t *testing.T) {
+	f := newUserFixture(t)
+	user := f.createUser(t, "user@example.com", "plum-Tiger-canoe-42", models.RoleUser)
+
+	_, err := f.service.ChangePassword(user.ID, &models.ChangePasswordRequest{
+		OldPassword: "wrong",
+		NewPassword: "quiet-Harbor-lamp-77",
+	}, f.client)
+	if !errors.Is(err, ErrIncorrectPassword) {
+		t.Fatalf("wrong old password: err = %v, want ErrIncorrectPassword", err)
+	}
+	if f.throttle.failures != 1 || len(f.tokens.loggedOut) != 0 {
+		t.Errorf("wrong old password: %d failures and %d logouts, want 1 and 0", f.throttle.failures, len(f.tokens.loggedOut))
+	}
+
+	auth, err := f.service.ChangePassword(user.ID, &models.ChangePasswordRequest{
+		OldPassword: "plum-Tiger-canoe-42",
+		NewPassword: "quiet-Harbor-lamp-77",
+	}, f.client)
+	if err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+
+	if len(f.tokens.loggedOut) != 1 || f.tokens.loggedOut[0] != user.ID {
+		t.Errorf("signed out %v, want user %d", f.tokens.loggedOut, user.ID)
+	}
+	if auth == nil || auth.User.ID != user.ID {
+		t.Errorf("the caller did not get new tokens")
+	}
+}
+
+func TestDeleteAccountConfirmation(t *testing.T) {
+	tests := []struct {
+		name      string
+		password  string
+		req       models.DeleteAccountRequest
+		signedIn  time.Duration
+		wantErr   error
+		wantFails int
+	}{
+		{name: "correct password", password: "plum-Tiger-canoe-42", req: models.DeleteAccountRequest{Password: "plum-Tiger-canoe-42"}, signedIn: time.Hour},
+		{name: "wrong password", password: "plum-Tiger-canoe-42", req: models.DeleteAccountRequest{Password: "wrong"}, signedIn: time.Minute, wantErr: ErrIncorrectPassword, wantFails: 1},
+		{name: "missing password", password: "plum-Tiger-canoe-42", signedIn: time.Minute, wantErr: ErrIncorrectPassword, wantFails: 1},
+		{name: "passwordless with a recent login", signedIn: time.Minute},
+		{name: "passwordless with an old login", signedIn: time.Hour, wantErr: ErrRecentLoginNeeded},
+		{name: "passwordless without a session", signedIn: -1, wantErr: ErrRecentLoginNeeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newUserFixture(t)
+			user := f.createUser(t, "user@example.com", tt.password, models.RoleUser)
+
+			sessionID := ""
+			if tt.signedIn >= 0 {
+				sessionID = "session"
+				f.sessions.started[sessionID] = time.Now().Add(-tt.signedIn)
+			}
+
+			err := f.service.DeleteAccount(user.ID, &tt.req, sessionID, f.client)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			_, lookupErr := f.users.GetByID(user.ID)
+			if deleted := errors.Is(lookupErr, repository.ErrUserNotFound); deleted != (tt.wantErr == nil) {
+				t.Errorf("deleted = %v, want %v", deleted, tt.wantErr == nil)
+			}
+			if f.throttle.failures != tt.wantFails {
+				t.Errorf("recorded %d failures, want %d", f.throttle.failures, tt.wantFails)
+			}
+		})
+	}
+}
+
+func TestChangePasswordReturnsUsableTokens(t *testing.T) {
+	f := newUserFixture(t)
+	user := f.createUser(t, "user@example.com", "plum-Tiger-canoe-42", models.RoleUser)
+
+	tokens := newTestTokenService(f.users)
+	f.service = NewUserService(f.users, nil, tokens, nil, nil, f.throttle, f.sessions)
+
+	before, err := tokens.IssueTokens(user, f.client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auth, err := f.service.ChangePassword(user.ID, &models.ChangePasswordRequest{
+		OldPassword: "plum-Tiger-canoe-42",
+		NewPassword: "quiet-Harbor-lamp-77",
+	}, f.client)
+	if err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+
+	if _, err := tokens.ValidateAccessToken(auth.AccessToken); err != nil {
+		t.Errorf("token returned by ChangePassword: %v", err)
+	}
+	if _, err := tokens.ValidateAccessToken(before.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("token from before the change: err = %v, want ErrTokenRevoked", err)
+	}
+}
@@ -0,0 +1,626 @@
+package service
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/lieucongduy182/go-gin-todo-api/config"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/utils"
+)
+
+const (
+	// WebAuthnCeremonyTTL bounds the time between the begin and finish call of a ceremony
+	WebAuthnCeremonyTTL = 5 * time.Minute
+
+	webAuthnHandleBytes = 32
+)
+
+var (
+	ErrInvalidPasskey           = errors.New("passkey verification failed")
+	ErrPasskeyCloned            = errors.New("passkey signature counter went backwards, the passkey has been blocked")
+	ErrPasskeyAlreadyRegistered = errors.New("this passkey is already registered")
+	ErrNoPasskeys               = errors.New("no passkeys are registered for this account")
+	ErrInvalidWebAuthnCeremony  = errors.New("invalid or expired passkey ceremony")
+)
+
+// WebAuthnService runs the passkey registration and assertion ceremonies.
+// Passkeys can replace the password entirely (Begin/FinishLogin) or serve
+// as the second step after a password login (Begin/FinishSecondFactor).
+type WebAuthnService interface {
+	BeginRegistration(userID uint) (*models.WebAuthnCeremonyResponse, error)
+	FinishRegistration(userID uint, req *models.WebAuthnRegistrationFinishRequest) (*models.WebAuthnRegistrationResponse, error)
+	ListCredentials(userID uint) ([]models.WebAuthnCredentialResponse, error)
+	DeleteCredential(userID, id uint) error
+	BeginLogin(req *models.WebAuthnLoginBeginRequest) (*models.WebAuthnCeremonyResponse, error)
+	FinishLogin(req *models.WebAuthnLoginFinishRequest, client *models.ClientInfo) (*models.AuthResponse, error)
+	BeginSecondFactor(req *models.WebAuthnMFABeginRequest) (*models.WebAuthnCeremonyResponse, error)
+	FinishSecondFactor(req *models.WebAuthnMFAFinishRequest, client *models.ClientInfo) (*models.AuthResponse, error)
+}
+
+type webAuthnService struct {
+	webAuthn         *webauthn.WebAuthn
+	userRepo         repository.UserRepository
+	passkeyRepo      repository.WebAuthnCredentialRepository
+	ceremonyRepo     repository.WebAuthnCeremonyRepository
+	recoveryCodeRepo repository.RecoveryCodeRepository
+	tokenService     TokenService
+	loginThrottle    LoginThrottleService
+}
+
+// webAuthnUser adapts a user and their stored passkeys to the webauthn.User interface
+type webAuthnUser struct {
+	user     *models.User
+	passkeys []models.WebAuthnCredential
+}
+
+func (w *webAuthnUser) WebAuthnID() []byte {
+	return w.user.WebAuthnHandle
+}
+
+func (w *webAuthnUser) WebAuthnName() string {
+	return w.user.Email
+}
+
+func (w *webAuthnUser) WebAuthnDisplayName() string {
+	return w.user.Username
+}
+
+func (w *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(w.passkeys))
+
+	for _, passkey := range w.passkeys {
+		var transports []protocol.AuthenticatorTransport
+		for _, transport := range passkey.TransportList() {
+			transports = append(transports, protocol.AuthenticatorTransport(transport))
+		}
+
+		credentials = append(credentials, webauthn.Credential{
+			ID:              passkey.CredentialID,
+			PublicKey:       passkey.PublicKey,
+			AttestationType: passkey.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: passkey.BackupEligible,
+				BackupState:    passkey.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    passkey.AAGUID,
+				SignCount: passkey.SignCount,
+			},
+		})
+	}
+
+	return credentials
+}
+
+// find returns the stored passkey with the given credential ID
+func (w *webAuthnUser) find(credentialID []byte) *models.WebAuthnCredential {
+	for i := range w.passkeys {
+		if bytes.Equal(w.passkeys[i].CredentialID, credentialID) {
+			return &w.passkeys[i]
+		}
+	}
+
+	return nil
+}
+
+func (w *webAuthnService) loadUser(user *models.User) (*webAuthnUser, error) {
+	passkeys, err := w.passkeyRepo.ListByUser(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webAuthnUser{user: user, passkeys: passkeys}, nil
+}
+
+// BeginRegistration implements WebAuthnService.
+// The user gets a random, stable WebAuthn user handle the first time around;
+// the numeric ID or email is never handed to authenticators.
+func (w *webAuthnService) BeginRegistration(userID uint) (*models.WebAuthnCeremonyResponse, error) {
+	user, err := w.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(user.WebAuthnHandle) == 0 {
+		handle := make([]byte, webAuthnHandleBytes)
+		if _, err := rand.Read(handle); err != nil {
+			return nil, err
+		}
+
+		user.WebAuthnHandle = handle
+		if err := w.userRepo.Update(user); err != nil {
+			return nil, err
+		}
+	}
+
+	account, err := w.loadUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	// ask for a discoverable credential so the passkey also works without typing an email
+	options, session, err := w.webAuthn.BeginRegistration(
+		account,
+		webauthn.WithExclusions(webauthn.Credentials(account.WebAuthnCredentials()).CredentialDescriptors()),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return w.startCeremony(models.WebAuthnCeremonyRegistration, &user.ID, session, options)
+}
+
+// FinishRegistration implements WebAuthnService.
+// The first passkey of an account without TOTP turns two-factor login on,
+// so recovery codes are issued with it.
+func (w *webAuthnService) FinishRegistration(userID uint, req *models.WebAuthnRegistrationFinishRequest) (*models.WebAuthnRegistrationResponse, error) {
+	ceremony, session, err := w.finishCeremony(models.WebAuthnCeremonyRegistration, req.CeremonyToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if ceremony.UserID == nil || *ceremony.UserID != userID {
+		return nil, ErrInvalidWebAuthnCeremony
+	}
+
+	user, err := w.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := w.loadUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(req.Credential)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPasskey, err)
+	}
+
+	credential, err := w.webAuthn.CreateCredential(account, *session, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPasskey, err)
+	}
+
+	if account.find(credential.ID) != nil {
+		return nil, ErrPasskeyAlreadyRegistered
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = fmt.Sprintf("Passkey %d", len(account.passkeys)+1)
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	passkey := &models.WebAuthnCredential{
+		UserID:          user.ID,
+		Name:            name,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		AAGUID:          credential.Authenticator.AAGUID,
+		Transports:      strings.Join(transports, ","),
+		SignCount:       credential.Authenticator.SignCount,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	}
+	if err := w.passkeyRepo.Create(passkey); err != nil {
+		return nil, err
+	}
+
+	response := &models.WebAuthnRegistrationResponse{Credential: passkey.ToResponse()}
+
+	if len(account.passkeys) == 0 && !user.IsTOTPEnabled() {
+		codes, err := issueRecoveryCodes(w.recoveryCodeRepo, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		response.RecoveryCodes = codes.RecoveryCodes
+	}
+
+	return response, nil
+}
+
+// ListCredentials implements WebAuthnService.
+func (w *webAuthnService) ListCredentials(userID uint) ([]models.WebAuthnCredentialResponse, error) {
+	passkeys, err := w.passkeyRepo.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.WebAuthnCredentialResponse, 0, len(passkeys))
+	for _, passkey := range passkeys {
+		responses = append(responses, passkey.ToResponse())
+	}
+
+	return responses, nil
+}
+
+// DeleteCredential implements WebAuthnService.
+// Removing the last passkey of an account without TOTP also drops its recovery codes.
+func (w *webAuthnService) DeleteCredential(userID uint, id uint) error {
+	if err := w.passkeyRepo.Delete(id, userID); err != nil {
+		return err
+	}
+
+	user, err := w.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.IsTOTPEnabled() {
+		return nil
+	}
+
+	remaining, err := w.passkeyRepo.CountByUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if remaining > 0 {
+		return nil
+	}
+
+	return w.recoveryCodeRepo.DeleteForUser(userID)
+}
+
+// BeginLogin implements WebAuthnService.
+// With an email the options list that account's passkeys. Unknown emails and accounts
+// without passkeys get decoy credentials derived from the email, so the response looks
+// the same whether or not the account exists. Without an email any discoverable
+// passkey for this site is accepted.
+// User verification is required because the passkey is the only factor.
+func (w *webAuthnService) BeginLogin(req *models.WebAuthnLoginBeginRequest) (*models.WebAuthnCeremonyResponse, error) {
+	if req.Email == "" {
+		options, session, err := w.webAuthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+		if err != nil {
+			return nil, err
+		}
+
+		return w.startCeremony(models.WebAuthnCeremonyLogin, nil, session, options)
+	}
+
+	account, err := w.loginAccount(req.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	options, session, err := w.webAuthn.BeginLogin(account, webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		return nil, err
+	}
+
+	// a decoy ceremony has no user, FinishLogin refuses it
+	var userID *uint
+	if account.user.ID != 0 {
+		userID = &account.user.ID
+	}
+
+	return w.startCeremony(models.WebAuthnCeremonyLogin, userID, session, options)
+}
+
+// loginAccount returns the account behind email, or a decoy when it has no passkeys
+func (w *webAuthnService) loginAccount(email string) (*webAuthnUser, error) {
+	user, err := w.userRepo.GetByEmail(email)
+	if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+		return nil, err
+	}
+
+	if user != nil && len(user.WebAuthnHandle) > 0 {
+		account, err := w.loadUser(user)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(account.passkeys) > 0 {
+			return account, nil
+		}
+	}
+
+	return decoyAccount(email), nil
+}
+
+// decoyAccount makes up one or two credentials for email. They are derived from a keyed
+// digest of the email, so repeated requests list the same IDs, like a real account would.
+func decoyAccount(email string) *webAuthnUser {
+	seed := utils.Fingerprint("webauthn-decoy", strings.ToLower(strings.TrimSpace(email)))
+
+	passkeys := make([]models.WebAuthnCredential, 1+int(seed[0]%2))
+	for i := range passkeys {
+		id := utils.Fingerprint("webauthn-decoy-credential", string(seed)+string(rune('0'+i)))
+		passkeys[i] = models.WebAuthnCredential{
+			CredentialID: id[:16+int(seed[1+i]%2)*16],
+			Transports:   "hybrid,internal",
+		}
+	}
+
+	return &webAuthnUser{
+		user:     &models.User{Email: email, WebAuthnHandle: seed},
+		passkeys: passkeys,
+	}
+}
+
+// FinishLogin implements WebAuthnService.
+// A verified passkey counts as a complete login, so no TOTP challenge follows.
+func (w *webAuthnService) FinishLogin(req *models.WebAuthnLoginFinishRequest, client *models.ClientInfo) (*models.AuthResponse, error) {
+	ceremony, session, err := w.finishCeremony(models.WebAuthnCeremonyLogin, req.CeremonyToken)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPasskey, err)
+	}
+
+	// a login ceremony for a specific account without a user is a decoy
+	if ceremony.UserID == nil && len(session.UserID) > 0 {
+		return nil, ErrInvalidPasskey
+	}
+
+	var user *models.User
+	if ceremony.UserID != nil {
+		user, err = w.userRepo.GetByID(*ceremony.UserID)
+	} else if len(parsed.Response.UserHandle) > 0 {
+		user, err = w.userRepo.GetByWebAuthnHandle(parsed.Response.UserHandle)
+	} else {
+		return nil, ErrInvalidPasskey
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrInvalidPasskey
+		}
+		return nil, err
+	}
+
+	if err := w.verifyAssertion(user, ceremony, session, parsed, client); err != nil {
+		return nil, err
+	}
+
+	return w.tokenService.IssueTokens(user, client)
+}
+
+// BeginSecondFactor implements WebAuthnService.
+func (w *webAuthnService) BeginSecondFactor(req *models.WebAuthnMFABeginRequest) (*models.WebAuthnCeremonyResponse, error) {
+	user, _, err := w.challengedUser(req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := w.loadUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(account.passkeys) == 0 {
+		return nil, ErrNoPasskeys
+	}
+
+	options, session, err := w.webAuthn.BeginLogin(account)
+	if err != nil {
+		return nil, err
+	}
+
+	return w.startCeremony(models.WebAuthnCeremonyMFA, &user.ID, session, options)
+}
+
+// FinishSecondFactor implements WebAuthnService.
+func (w *webAuthnService) FinishSecondFactor(req *models.WebAuthnMFAFinishRequest, client *models.ClientInfo) (*models.AuthResponse, error) {
+	user, claims, err := w.challengedUser(req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+
+	ceremony, session, err := w.finishCeremony(models.WebAuthnCeremonyMFA, req.CeremonyToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if ceremony.UserID == nil || *ceremony.UserID != user.ID {
+		return nil, ErrInvalidWebAuthnCeremony
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPasskey, err)
+	}
+
+	if err := w.verifyAssertion(user, ceremony, session, parsed, client); err != nil {
+		return nil, err
+	}
+
+	if err := w.tokenService.ConsumeMFAChallenge(claims); err != nil {
+		return nil, err
+	}
+
+	return w.tokenService.IssueTokens(user, client)
+}
+
+// challengedUser resolves the user behind an mfa_token from the password step
+func (w *webAuthnService) challengedUser(mfaToken string) (*models.User, *utils.Claims, error) {
+	claims, err := w.tokenService.ValidateMFAChallenge(mfaToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := w.userRepo.GetByID(claims.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, nil, ErrInvalidMFAToken
+		}
+		return nil, nil, err
+	}
+
+	return user, claims, nil
+}
+
+// verifyAssertion checks a signed challenge against the user's passkeys and stores the new
+// sign counter. Failures count towards the login throttle like wrong passwords do.
+func (w *webAuthnService) verifyAssertion(
+	user *models.User,
+	ceremony *models.WebAuthnCeremony,
+	session *webauthn.SessionData,
+	parsed *protocol.ParsedCredentialAssertionData,
+	client *models.ClientInfo,
+) error {
+	if user.IsDisabled() {
+		return ErrAccountDisabled
+	}
+
+	if err := w.loginThrottle.Check(user.Email, client.IP); err != nil {
+		return err
+	}
+
+	account, err := w.loadUser(user)
+	if err != nil {
+		return err
+	}
+
+	var credential *webauthn.Credential
+	if ceremony.UserID != nil {
+		credential, err = w.webAuthn.ValidateLogin(account, *session, parsed)
+	} else {
+		credential, err = w.webAuthn.ValidateDiscoverableLogin(func(_, userHandle []byte) (webauthn.User, error) {
+			if !bytes.Equal(userHandle, user.WebAuthnHandle) {
+				return nil, ErrInvalidPasskey
+			}
+			return account, nil
+		}, *session, parsed)
+	}
+	if err != nil {
+		if err := w.loginThrottle.RecordFailure(user.Email, client.IP, user); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %v", ErrInvalidPasskey, err)
+	}
+
+	passkey := account.find(credential.ID)
+	if passkey == nil {
+		return ErrInvalidPasskey
+	}
+
+	now := time.Now()
+	blocked := passkey.CloneWarning || credential.Authenticator.CloneWarning
+
+	passkey.SignCount = credential.Authenticator.SignCount
+	passkey.BackupState = credential.Flags.BackupState
+	passkey.CloneWarning = blocked
+	if !blocked {
+		passkey.LastUsedAt = &now
+	}
+	if err := w.passkeyRepo.UpdateAfterLogin(passkey); err != nil {
+		return err
+	}
+
+	if blocked {
+		log.Printf("Passkey %d of user %d has a sign counter that went backwards, refusing it", passkey.ID, user.ID)
+		if err := w.loginThrottle.RecordFailure(user.Email, client.IP, user); err != nil {
+			return err
+		}
+		return ErrPasskeyCloned
+	}
+
+	return w.loginThrottle.RecordSuccess(user.Email, client.IP, user)
+}
+
+// startCeremony stores the session data of a new ceremony and returns the options for the browser
+func (w *webAuthnService) startCeremony(kind string, userID *uint, session *webauthn.SessionData, options any) (*models.WebAuthnCeremonyResponse, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.ceremonyRepo.Create(&models.WebAuthnCeremony{
+		TokenHash:   utils.HashToken(token),
+		Kind:        kind,
+		UserID:      userID,
+		SessionData: data,
+		ExpiresAt:   time.Now().Add(WebAuthnCeremonyTTL),
+	}); err != nil {
+		return nil, err
+	}
+
+	// housekeeping: abandoned ceremonies are never finished
+	if err := w.ceremonyRepo.DeleteExpired(); err != nil {
+		log.Printf("Failed to clean up expired passkey ceremonies: %v", err)
+	}
+
+	return &models.WebAuthnCeremonyResponse{
+		CeremonyToken: token,
+		Options:       options,
+		ExpiresIn:     int(WebAuthnCeremonyTTL.Seconds()),
+	}, nil
+}
+
+// finishCeremony consumes a ceremony so its challenge cannot be answered twice
+func (w *webAuthnService) finishCeremony(kind, token string) (*models.WebAuthnCeremony, *webauthn.SessionData, error) {
+	ceremony, err := w.ceremonyRepo.Consume(utils.HashToken(token), kind)
+	if err != nil {
+		if errors.Is(err, repository.ErrWebAuthnCeremonyNotFound) {
+			return nil, nil, ErrInvalidWebAuthnCeremony
+		}
+		return nil, nil, err
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(ceremony.SessionData, &session); err != nil {
+		return nil, nil, err
+	}
+
+	return ceremony, &session, nil
+}
+
+// NewWebAuthnService builds the relying party from WEBAUTHN_* settings; invalid settings stop startup.
+func NewWebAuthnService(
+	userRepo repository.UserRepository,
+	passkeyRepo repository.WebAuthnCredentialRepository,
+	ceremonyRepo repository.WebAuthnCeremonyRepository,
+	recoveryCodeRepo repository.RecoveryCodeRepository,
+	tokenService TokenService,
+	loginThrottle LoginThrottleService,
+) WebAuthnService {
+	relyingParty, err := webauthn.New(&webauthn.Config{
+		RPID:          config.AppConfig.WebAuthnRPID,
+		RPDisplayName: config.AppConfig.WebAuthnRPName,
+		RPOrigins:     config.AppConfig.WebAuthnOrigins,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			ResidentKey:      protocol.ResidentKeyRequirementPreferred,
+			UserVerification: protocol.VerificationPreferred,
+		},
+	})
+	if err != nil {
+		log.Fatalf("Invalid WebAuthn configuration: %v", err)
+	}
+
+	return &webAuthnService{
+		webAuthn:         relyingParty,
+		userRepo:         userRepo,
+		passkeyRepo:      passkeyRepo,
+		ceremonyRepo:     ceremonyRepo,
+		recoveryCodeRepo: recoveryCodeRepo,
+		tokenService:     tokenService,
+		loginThrottle:    loginThrottle,
+	}
+}
@@ -0,0 +1,607 @@
+package service
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/lieucongduy182/go-gin-todo-api/models"
+	"github.com/lieucongduy182/go-gin-todo-api/repository"
+	"github.com/lieucongduy182/go-gin-todo-api/utils"
+)
+
+// softAuthenticator is a passkey in software: an ECDSA P-256 key that answers
+// registration and assertion ceremonies the way a browser and authenticator would.
+type softAuthenticator struct {
+	t            *testing.T
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+	rpID         string
+	origin       string
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		t.Fatal(err)
+	}
+
+	return &softAuthenticator{
+		t:            t,
+		key:          key,
+		credentialID: credentialID,
+		rpID:         "todo.example.com",
+		origin:       "https://todo.example.com",
+	}
+}
+
+func (a *softAuthenticator) clientData(kind string, challenge protocol.URLEncodedBase64) []byte {
+	data, err := json.Marshal(map[string]any{
+		"type":      kind,
+		"challenge": challenge.String(),
+		"origin":    a.origin,
+	})
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return data
+}
+
+// authenticatorData builds rpIdHash | flags | signCount, plus attested credential data when attest is set
+func (a *softAuthenticator) authenticatorData(attest bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+
+	flags := byte(protocol.FlagUserPresent | protocol.FlagUserVerified)
+	if attest {
+		flags |= byte(protocol.FlagAttestedCredentialData)
+	}
+
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+
+	if attest {
+		publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+			PublicKeyData: webauthncose.PublicKeyData{
+				KeyType:   int64(webauthncose.EllipticKey),
+				Algorithm: int64(webauthncose.AlgES256),
+			},
+			Curve:  int64(webauthncose.P256),
+			XCoord: a.key.X.FillBytes(make([]byte, 32)),
+			YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+		})
+		if err != nil {
+			a.t.Fatal(err)
+		}
+
+		data = append(data, make([]byte, 16)...) // AAGUID
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, publicKey...)
+	}
+
+	return data
+}
+
+// register answers navigator.credentials.create() with a "none" attestation
+func (a *softAuthenticator) register(options any) json.RawMessage {
+	a.t.Helper()
+
+	creation, ok := options.(*protocol.CredentialCreation)
+	if !ok {
+		a.t.Fatalf("registration options are %T", options)
+	}
+
+	userID, ok := creation.Response.User.ID.(protocol.URLEncodedBase64)
+	if !ok {
+		a.t.Fatalf("user handle is %T", creation.Response.User.ID)
+	}
+	a.userHandle = userID
+
+	attestation, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authenticatorData(true),
+	})
+	if err != nil {
+		a.t.Fatal(err)
+	}
+
+	return a.credential(map[string]any{
+		"clientDataJSON":    encode(a.clientData("webauthn.create", creation.Response.Challenge)),
+		"attestationObject": encode(attestation),
+		"transports":        []string{"internal"},
+	})
+}
+
+// assert answers navigator.credentials.get() with the given sign counter
+func (a *softAuthenticator) assert(options any, signCount uint32) json.RawMessage {
+	a.t.Helper()
+
+	assertion, ok := options.(*protocol.CredentialAssertion)
+	if !ok {
+		a.t.Fatalf("login options are %T", options)
+	}
+
+	a.signCount = signCount
+	authData := a.authenticatorData(false)
+	clientData := a.clientData("webauthn.get", assertion.Response.Challenge)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		a.t.Fatal(err)
+	}
+
+	return a.credential(map[string]any{
+		"clientDataJSON":    encode(clientData),
+		"authenticatorData": encode(authData),
+		"signature":         encode(signature),
+		"userHandle":        encode(a.userHandle),
+	})
+}
+
+func (a *softAuthenticator) credential(response map[string]any) json.RawMessage {
+	data, err := json.Marshal(map[string]any{
+		"id":       encode(a.credentialID),
+		"rawId":    encode(a.credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return data
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+type fakePasskeyRepository struct {
+	passkeys []models.WebAuthnCredential
+	nextID   uint
+}
+
+func (f *fakePasskeyRepository) Create(credential *models.WebAuthnCredential) error {
+	f.nextID++
+	credential.ID = f.nextID
+	f.passkeys = append(f.passkeys, *credential)
+	return nil
+}
+
+func (f *fakePasskeyRepository) ListByUser(userID uint) ([]models.WebAuthnCredential, error) {
+	var passkeys []models.WebAuthnCredential
+	for _, passkey := range f.passkeys {
+		if passkey.UserID == userID {
+			passkeys = append(passkeys, passkey)
+		}
+	}
+	return passkeys, nil
+}
+
+func (f *fakePasskeyRepository) CountByUser(userID uint) (int64, error) {
+	passkeys, _ := f.ListByUser(userID)
+	return int64(len(passkeys)), nil
+}
+
+func (f *fakePasskeyRepository) UpdateAfterLogin(credential *models.WebAuthnCredential) error {
+	for i := range f.passkeys {
+		if f.passkeys[i].ID == credential.ID {
+			f.passkeys[i] = *credential
+			return nil
+		}
+	}
+	return repository.ErrWebAuthnCredentialNotFound
+}
+
+func (f *fakePasskeyRepository) Delete(id, userID uint) error {
+	for i, passkey := range f.passkeys {
+		if passkey.ID == id && passkey.UserID == userID {
+			f.passkeys = append(f.passkeys[:i], f.passkeys[i+1:]...)
+			return nil
+		}
+	}
+	return repository.ErrWebAuthnCredentialNotFound
+}
+
+type fakeCeremonyRepository struct {
+	ceremonies map[string]models.WebAuthnCeremony
+}
+
+func (f *fakeCeremonyRepository) Create(ceremony *models.WebAuthnCeremony) error {
+	f.ceremonies[ceremony.TokenHash] = *ceremony
+	return nil
+}
+
+func (f *fakeCeremonyRepository) Consume(tokenHash, kind string) (*models.WebAuthnCeremony, error) {
+	ceremony, ok := f.ceremonies[tokenHash]
+	if !ok || ceremony.Kind != kind || time.Now().After(ceremony.ExpiresAt) {
+		return nil, repository.ErrWebAuthnCeremonyNotFound
+	}
+	delete(f.ceremonies, tokenHash)
+	return &ceremony, nil
+}
+
+func (f *fakeCeremonyRepository) DeleteExpired() error {
+	return nil
+}
+
+type webAuthnFixture struct {
+	*authFixture
+	service   *webAuthnService
+	passkeys  *fakePasskeyRepository
+	user      *models.User
+	passkey   *softAuthenticator
+	otherUser *models.User
+}
+
+func newWebAuthnFixture(t *testing.T) *webAuthnFixture {
+	t.Helper()
+
+	f := &webAuthnFixture{
+		authFixture: newAuthFixture(t),
+		passkeys:    &fakePasskeyRepository{},
+	}
+	f.service = NewWebAuthnService(
+		f.users,
+		f.passkeys,
+		&fakeCeremonyRepository{ceremonies: make(map[string]models.WebAuthnCeremony)},
+		f.recoveryCodes,
+		f.tokens,
+		f.throttle,
+	).(*webAuthnService)
+
+	f.user = f.createUser(t, "alice@example.com", "", models.RoleUser)
+	f.otherUser = f.createUser(t, "bob@example.com", "", models.RoleUser)
+
+	f.passkey = newSoftAuthenticator(t)
+	return f
+}
+
+// registerPasskey runs a full registration ceremony for the fixture user
+func (f *webAuthnFixture) registerPasskey(t *testing.T, authenticator *softAuthenticator) (*models.WebAuthnRegistrationResponse, error) {
+	t.Helper()
+
+	begin, err := f.service.BeginRegistration(f.user.ID)
+	if err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+
+	return f.service.FinishRegistration(f.user.ID, &models.WebAuthnRegistrationFinishRequest{
+		CeremonyToken: begin.CeremonyToken,
+		Credential:    authenticator.register(begin.Options),
+	})
+}
+
+func (f *webAuthnFixture) login(t *testing.T, email string, signCount uint32) (*models.AuthResponse, error) {
+	t.Helper()
+
+	begin, err := f.service.BeginLogin(&models.WebAuthnLoginBeginRequest{Email: email})
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+
+	return f.service.FinishLogin(&models.WebAuthnLoginFinishRequest{
+		CeremonyToken: begin.CeremonyToken,
+		Credential:    f.passkey.assert(begin.Options, signCount),
+	}, f.client)
+}
+
+func allowedCredentials(t *testing.T, options any) [][]byte {
+	t.Helper()
+
+	assertion, ok := options.(*protocol.CredentialAssertion)
+	if !ok {
+		t.Fatalf("login options are %T", options)
+	}
+
+	ids := make([][]byte, 0, len(assertion.Response.AllowedCredentials))
+	for _, descriptor := range assertion.Response.AllowedCredentials {
+		ids = append(ids, descriptor.CredentialID)
+	}
+	return ids
+}
+
+func TestWebAuthnRegistration(t *testing.T) {
+	f := newWebAuthnFixture(t)
+
+	registered, err := f.registerPasskey(t, f.passkey)
+	if err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+
+	if len(f.passkeys.passkeys) != 1 || !bytes.Equal(f.passkeys.passkeys[0].CredentialID, f.passkey.credentialID) {
+		t.Fatalf("stored passkeys = %+v, want the authenticator's credential", f.passkeys.passkeys)
+	}
+	if registered.Credential.Name != "Passkey 1" {
+		t.Errorf("name = %q, want the default name", registered.Credential.Name)
+	}
+	if len(registered.RecoveryCodes) == 0 || len(f.recoveryCodes.hashes[f.user.ID]) != len(registered.RecoveryCodes) {
+		t.Errorf("first passkey without TOTP should come with recovery codes, got %d", len(registered.RecoveryCodes))
+	}
+
+	user, _ := f.users.GetByID(f.user.ID)
+	if len(user.WebAuthnHandle) != webAuthnHandleBytes || !bytes.Equal(user.WebAuthnHandle, f.passkey.userHandle) {
+		t.Errorf("user handle was not assigned and handed to the authenticator")
+	}
+
+	// the authenticator ignores excludeCredentials, the service still refuses a duplicate
+	if _, err := f.registerPasskey(t, f.passkey); !errors.Is(err, ErrPasskeyAlreadyRegistered) {
+		t.Errorf("second registration of the same passkey: err = %v, want ErrPasskeyAlreadyRegistered", err)
+	}
+
+	second, err := f.registerPasskey(t, newSoftAuthenticator(t))
+	if err != nil {
+		t.Fatalf("registering a second passkey: %v", err)
+	}
+	if len(second.RecoveryCodes) != 0 {
+		t.Errorf("only the first passkey issues recovery codes")
+	}
+}
+
+func TestWebAuthnRegistrationRejectsOtherUsersCeremony(t *testing.T) {
+	f := newWebAuthnFixture(t)
+
+	begin, err := f.service.BeginRegistration(f.user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.service.FinishRegistration(f.otherUser.ID, &models.WebAuthnRegistrationFinishRequest{
+		CeremonyToken: begin.CeremonyToken,
+		Credential:    f.passkey.register(begin.Options),
+	})
+	if !errors.Is(err, ErrInvalidWebAuthnCeremony) {
+		t.Fatalf("err = %v, want ErrInvalidWebAuthnCeremony", err)
+	}
+}
+
+func TestWebAuthnLogin(t *testing.T) {
+	tests := []struct {
+		name  string
+		email string
+	}{
+		{name: "discoverable", email: ""},
+		{name: "email", email: "alice@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newWebAuthnFixture(t)
+			if _, err := f.registerPasskey(t, f.passkey); err != nil {
+				t.Fatal(err)
+			}
+
+			begin, err := f.service.BeginLogin(&models.WebAuthnLoginBeginRequest{Email: tt.email})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			allowed := allowedCredentials(t, begin.Options)
+			if tt.email == "" && len(allowed) != 0 {
+				t.Errorf("discoverable login should not list credentials, got %d", len(allowed))
+			}
+			if tt.email != "" && (len(allowed) != 1 || !bytes.Equal(allowed[0], f.passkey.credentialID)) {
+				t.Errorf("email login should list the user's passkey, got %x", allowed)
+			}
+
+			auth, err := f.service.FinishLogin(&models.WebAuthnLoginFinishRequest{
+				CeremonyToken: begin.CeremonyToken,
+				Credential:    f.passkey.assert(begin.Options, 1),
+			}, f.client)
+			if err != nil {
+				t.Fatalf("FinishLogin: %v", err)
+			}
+
+			if auth.User.ID != f.user.ID || len(f.tokens.issued) != 1 {
+				t.Errorf("tokens issued for %v, want user %d", f.tokens.issued, f.user.ID)
+			}
+			if f.passkeys.passkeys[0].SignCount != 1 || f.passkeys.passkeys[0].LastUsedAt == nil {
+				t.Errorf("sign counter and last use were not stored: %+v", f.passkeys.passkeys[0])
+			}
+			if f.throttle.successes != 1 || f.throttle.failures != 0 {
+				t.Errorf("throttle saw %d successes and %d failures", f.throttle.successes, f.throttle.failures)
+			}
+		})
+	}
+}
+
+func TestWebAuthnLoginDoesNotRevealAccounts(t *testing.T) {
+	f := newWebAuthnFixture(t)
+	if _, err := f.registerPasskey(t, f.passkey); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, email := range []string{"nobody@example.com", f.otherUser.Email} {
+		first, err := f.service.BeginLogin(&models.WebAuthnLoginBeginRequest{Email: email})
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := f.service.BeginLogin(&models.WebAuthnLoginBeginRequest{Email: email})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoys := allowedCredentials(t, first.Options)
+		if len(decoys) == 0 {
+			t.Fatalf("%s: no credentials listed, the response tells the account has no passkeys", email)
+		}
+		if repeated := allowedCredentials(t, second.Options); len(repeated) != len(decoys) || !bytes.Equal(repeated[0], decoys[0]) {
+			t.Errorf("%s: decoy credentials change between requests", email)
+		}
+
+		// a real passkey answering a decoy ceremony must not log anybody in
+		_, err = f.service.FinishLogin(&models.WebAuthnLoginFinishRequest{
+			CeremonyToken: first.CeremonyToken,
+			Credential:    f.passkey.assert(first.Options, 1),
+		}, f.client)
+		if !errors.Is(err, ErrInvalidPasskey) {
+			t.Errorf("%s: finishing a decoy ceremony: err = %v, want ErrInvalidPasskey", email, err)
+		}
+	}
+
+	if len(f.tokens.issued) != 0 {
+		t.Errorf("tokens were issued for %v", f.tokens.issued)
+	}
+}
+
+func TestWebAuthnCeremonyReplay(t *testing.T) {
+	f := newWebAuthnFixture(t)
+	if _, err := f.registerPasskey(t, f.passkey); err != nil {
+		t.Fatal(err)
+	}
+
+	begin, err := f.service.BeginLogin(&models.WebAuthnLoginBeginRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := &models.WebAuthnLoginFinishRequest{
+		CeremonyToken: begin.CeremonyToken,
+		Credential:    f.passkey.assert(begin.Options, 1),
+	}
+	if _, err := f.service.FinishLogin(request, f.client); err != nil {
+		t.Fatalf("first FinishLogin: %v", err)
+	}
+
+	if _, err := f.service.FinishLogin(request, f.client); !errors.Is(err, ErrInvalidWebAuthnCeremony) {
+		t.Errorf("replayed FinishLogin: err = %v, want ErrInvalidWebAuthnCeremony", err)
+	}
+	if len(f.tokens.issued) != 1 {
+		t.Errorf("tokens issued %d times, want once", len(f.tokens.issued))
+	}
+
+	// a login ceremony cannot be finished as a second factor either
+	begin, err = f.service.BeginLogin(&models.WebAuthnLoginBeginRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mfaToken, err := utils.GenerateMFAChallengeToken(f.user.ID, f.user.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.service.FinishSecondFactor(&models.WebAuthnMFAFinishRequest{
+		MFAToken:      mfaToken,
+		CeremonyToken: begin.CeremonyToken,
+		Credential:    f.passkey.assert(begin.Options, 2),
+	}, f.client)
+	if !errors.Is(err, ErrInvalidWebAuthnCeremony) {
+		t.Errorf("login ceremony used as second factor: err = %v, want ErrInvalidWebAuthnCeremony", err)
+	}
+}
+
+func TestWebAuthnSecondFactor(t *testing.T) {
+	f := newWebAuthnFixture(t)
+	if _, err := f.registerPasskey(t, f.passkey); err != nil {
+		t.Fatal(err)
+	}
+
+	mfaToken, err := utils.GenerateMFAChallengeToken(f.user.ID, f.user.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	begin, err := f.service.BeginSecondFactor(&models.WebAuthnMFABeginRequest{MFAToken: mfaToken})
+	if err != nil {
+		t.Fatalf("BeginSecondFactor: %v", err)
+	}
+
+	auth, err := f.service.FinishSecondFactor(&models.WebAuthnMFAFinishRequest{
+		MFAToken:      mfaToken,
+		CeremonyToken: begin.CeremonyToken,
+		Credential:    f.passkey.assert(begin.Options, 1),
+	}, f.client)
+	if err != nil {
+		t.Fatalf("FinishSecondFactor: %v", err)
+	}
+	if auth.User.ID != f.user.ID {
+		t.Errorf("tokens issued for user %d, want %d", auth.User.ID, f.user.ID)
+	}
+
+	// the challenge is spent by the login it completed
+	if _, err := f.service.BeginSecondFactor(&models.WebAuthnMFABeginRequest{MFAToken: mfaToken}); !errors.Is(err, ErrInvalidMFAToken) {
+		t.Errorf("spent mfa token: err = %v, want ErrInvalidMFAToken", err)
+	}
+
+	// another user's challenge cannot finish this user's ceremony
+	mfaToken, err = utils.GenerateMFAChallengeToken(f.user.ID, f.user.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	begin, err = f.service.BeginSecondFactor(&models.WebAuthnMFABeginRequest{MFAToken: mfaToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherToken, err := utils.GenerateMFAChallengeToken(f.otherUser.ID, f.otherUser.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.service.FinishSecondFactor(&models.WebAuthnMFAFinishRequest{
+		MFAToken:      otherToken,
+		CeremonyToken: begin.CeremonyToken,
+		Credential:    f.passkey.assert(begin.Options, 2),
+	}, f.client)
+	if !errors.Is(err, ErrInvalidWebAuthnCeremony) {
+		t.Errorf("ceremony of another user: err = %v, want ErrInvalidWebAuthnCeremony", err)
+	}
+
+	if _, err := f.service.BeginSecondFactor(&models.WebAuthnMFABeginRequest{MFAToken: otherToken}); !errors.Is(err, ErrNoPasskeys) {
+		t.Errorf("second factor without passkeys: err = %v, want ErrNoPasskeys", err)
+	}
+	if _, err := f.service.BeginSecondFactor(&models.WebAuthnMFABeginRequest{MFAToken: "garbage"}); !errors.Is(err, ErrInvalidMFAToken) {
+		t.Errorf("invalid mfa token: err = %v, want ErrInvalidMFAToken", err)
+	}
+}
+
+func TestWebAuthnCloneWarning(t *testing.T) {
+	f := newWebAuthnFixture(t)
+	if _, err := f.registerPasskey(t, f.passkey); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.login(t, f.user.Email, 5); err != nil {
+		t.Fatalf("login with counter 5: %v", err)
+	}
+
+	// a cloned authenticator replays an older counter
+	if _, err := f.login(t, f.user.Email, 3); !errors.Is(err, ErrPasskeyCloned) {
+		t.Fatalf("login with counter 3: err = %v, want ErrPasskeyCloned", err)
+	}
+	if !f.passkeys.passkeys[0].CloneWarning {
+		t.Fatalf("passkey was not flagged")
+	}
+
+	// once flagged the passkey stays blocked, even with a counter that moves forward again
+	if _, err := f.login(t, "", 10); !errors.Is(err, ErrPasskeyCloned) {
+		t.Errorf("login after the clone warning: err = %v, want ErrPasskeyCloned", err)
+	}
+
+	if len(f.tokens.issued) != 1 {
+		t.Errorf("tokens issued %d times, want once", len(f.tokens.issued))
+	}
+	if f.throttle.failures != 2 {
+		t.Errorf("throttle saw %d failures, want 2", f.throttle.failures)
+	}
+}
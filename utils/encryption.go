@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/lieucongduy182/go-gin-todo-api/config"
+)
+
+// encryptedPrefix marks values sealed by EncryptString and versions their format
+const encryptedPrefix = "enc:v1:"
+
+var ErrDecryptionFailed = errors.New("cannot decrypt value")
+
+// EncryptString seals plaintext with AES-256-GCM under the configured ENCRYPTION_KEY.
+// The context is authenticated along with it, so a value copied to another row fails to decrypt.
+func EncryptString(context, plaintext string) (string, error) {
+	aead, err := newAEAD()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(context))
+	return encryptedPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptString opens a value produced by EncryptString for the same context
+func DecryptString(context, value string) (string, error) {
+	encoded, ok := strings.CutPrefix(value, encryptedPrefix)
+	if !ok {
+		return "", ErrDecryptionFailed
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrDecryptionFailed
+	}
+
+	aead, err := newAEAD()
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return "", ErrDecryptionFailed
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(context))
+	if err != nil {
+		return "", ErrDecryptionFailed
+	}
+
+	return string(plaintext), nil
+}
+
+// IsEncrypted reports whether value was produced by EncryptString
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+func newAEAD() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(encryptionKey())
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// encryptionKey decodes ENCRYPTION_KEY. Without one, which config only allows in debug mode,
+// a key is derived from the JWT secret.
+func encryptionKey() []byte {
+	if key, err := base64.StdEncoding.DecodeString(config.AppConfig.EncryptionKey); err == nil && len(key) == 32 {
+		return key
+	}
+
+	return signature("encryption-key", "")
+}
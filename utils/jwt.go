@@ -2,47 +2,116 @@ package utils
 
 import (
 	"errors"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/lieucongduy182/go-gin-todo-api/config"
 )
 
+// Token types; every token carries one so that tokens signed with the same keys
+// can never be used in place of each other
+const (
+	TokenTypeAccess        = "access"
+	TokenTypeImpersonation = "impersonation"
+	// TokenTypeMFAChallenge is the short-lived token handed out between password and second factor
+	TokenTypeMFAChallenge = "mfa_challenge"
+)
+
+// MFAChallengeTTL is how long a user has to enter their second factor after a password login
+const MFAChallengeTTL = 5 * time.Minute
+
 type Claims struct {
 	UserID uint   `json:"user_id"`
 	Email  string `json:"email"`
+	Role   string `json:"role,omitempty"`
+	// SessionID ties the token to a models.Session; ending the session rejects the token
+	SessionID string `json:"sid,omitempty"`
+	// ImpersonatorID is the admin acting as this user, if any
+	ImpersonatorID uint `json:"impersonator_id,omitempty"`
+	// TokenType is one of the TokenType constants
+	TokenType string `json:"token_type"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID uint, email string) (string, error) {
-	expirationTime := time.Now().Add(time.Duration(config.AppConfig.JWTExpirationHours) * time.Hour)
+func GenerateToken(userID uint, email, role, sessionID string) (string, error) {
+	return generateToken(&Claims{
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
+		TokenType: TokenTypeAccess,
+	}, AccessTokenTTL())
+}
 
-	claims := &Claims{
-		UserID: userID,
-		Email:  email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
-	}
+// GenerateImpersonationToken issues an access token for userID on behalf of impersonatorID
+func GenerateImpersonationToken(userID uint, email, role string, impersonatorID uint) (string, error) {
+	return generateToken(&Claims{
+		UserID:         userID,
+		Email:          email,
+		Role:           role,
+		ImpersonatorID: impersonatorID,
+		TokenType:      TokenTypeImpersonation,
+	}, AccessTokenTTL())
+}
+
+// GenerateMFAChallengeToken issues the token exchanged at /auth/login/mfa for real tokens
+func GenerateMFAChallengeToken(userID uint, email string) (string, error) {
+	return generateToken(&Claims{UserID: userID, Email: email, TokenType: TokenTypeMFAChallenge}, MFAChallengeTTL)
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+func generateToken(claims *Claims, ttl time.Duration) (string, error) {
+	expirationTime := time.Now().Add(ttl)
 
-	tokenString, err := token.SignedString([]byte(config.AppConfig.JWTSecret))
+	jti, err := GenerateRandomToken(16)
 	if err != nil {
 		return "", err
 	}
 
-	return tokenString, nil
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Issuer:    config.AppConfig.JWTIssuer,
+		Audience:  jwt.ClaimStrings{config.AppConfig.JWTAudience},
+		ID:        jti,
+		ExpiresAt: jwt.NewNumericDate(expirationTime),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+	}
+
+	if keySet == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString([]byte(config.AppConfig.JWTSecret))
+	}
+
+	key := keySet.signingKey(time.Now())
+	if key == nil {
+		return "", errors.New("no active JWT signing key")
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+
+	return token.SignedString(key.PrivateKey)
 }
 
+// ValidateToken validates an access token, including one issued for impersonation
 func ValidateToken(tokenString string) (*Claims, error) {
+	return validateToken(tokenString, TokenTypeAccess, TokenTypeImpersonation)
+}
+
+// ValidateMFAChallengeToken validates a token issued by GenerateMFAChallengeToken
+func ValidateMFAChallengeToken(tokenString string) (*Claims, error) {
+	return validateToken(tokenString, TokenTypeMFAChallenge)
+}
+
+func validateToken(tokenString string, tokenTypes ...string) (*Claims, error) {
 
 	claims := &Claims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(config.AppConfig.JWTSecret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, claims, verificationKey,
+		jwt.WithValidMethods(validMethods()),
+		jwt.WithIssuer(config.AppConfig.JWTIssuer),
+		jwt.WithAudience(config.AppConfig.JWTAudience),
+		jwt.WithExpirationRequired(),
+	)
 
 	if err != nil {
 		return nil, err
@@ -52,5 +121,48 @@ func ValidateToken(tokenString string) (*Claims, error) {
 		return nil, errors.New("invalid token")
 	}
 
+	if !slices.Contains(tokenTypes, claims.TokenType) {
+		return nil, errors.New("invalid token type")
+	}
+
+	// an impersonator is exactly what makes a token an impersonation token
+	if (claims.TokenType == TokenTypeImpersonation) != (claims.ImpersonatorID != 0) {
+		return nil, errors.New("invalid token type")
+	}
+
 	return claims, nil
 }
+
+// verificationKey picks the key for a token: the shared secret for HS256,
+// otherwise the public key named by the kid header
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	if keySet == nil {
+		return []byte(config.AppConfig.JWTSecret), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key := keySet.lookup(kid)
+	if key == nil {
+		return nil, errors.New("unknown signing key")
+	}
+
+	// the algorithm must belong to the key, never just to the token header
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("signing method does not match key")
+	}
+
+	return key.PublicKey, nil
+}
+
+func validMethods() []string {
+	if keySet == nil {
+		return []string{jwt.SigningMethodHS256.Alg()}
+	}
+
+	return keySet.methods()
+}
+
+// AccessTokenTTL returns how long an access token issued now stays valid
+func AccessTokenTTL() time.Duration {
+	return time.Duration(config.AppConfig.AccessTokenMinutes) * time.Minute
+}
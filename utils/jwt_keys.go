@@ -0,0 +1,247 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is one entry of the JWT key rotation schedule.
+// PrivateKey is nil for verify-only keys.
+type SigningKey struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+	NotBefore  time.Time
+}
+
+// KeySet holds every configured key, ordered by NotBefore.
+// The newest private key whose NotBefore has passed signs new tokens; all keys verify,
+// so upcoming keys are published in the JWKS before they are used and
+// previous keys keep verifying tokens until they are removed from the config.
+// A retired key can stay as its public half alone, which verifies but never signs.
+type KeySet struct {
+	keys []*SigningKey
+}
+
+// keySet is nil when tokens are signed with the shared HS256 secret
+var keySet *KeySet
+
+// LoadSigningKeys parses the JWT_KEYS spec: a comma separated list of
+// kid=path/to/key.pem[@not_before] entries, where not_before is RFC 3339.
+// RSA keys sign with RS256 and Ed25519 keys with EdDSA; a PEM file holding only a
+// public key is verify-only. An empty spec keeps HS256.
+func LoadSigningKeys(spec string) error {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		keySet = nil
+		return nil
+	}
+
+	set := &KeySet{}
+	seen := make(map[string]bool)
+
+	for _, entry := range strings.Split(spec, ",") {
+		key, err := parseKeyEntry(strings.TrimSpace(entry))
+		if err != nil {
+			return err
+		}
+
+		if seen[key.ID] {
+			return fmt.Errorf("duplicate JWT key id %q", key.ID)
+		}
+		seen[key.ID] = true
+
+		set.keys = append(set.keys, key)
+	}
+
+	sort.Slice(set.keys, func(i, j int) bool {
+		return set.keys[i].NotBefore.Before(set.keys[j].NotBefore)
+	})
+
+	if set.signingKey(time.Now()) == nil {
+		return errors.New("no JWT signing key is active yet, JWT_KEYS needs a private key whose not_before has passed")
+	}
+
+	keySet = set
+	return nil
+}
+
+func parseKeyEntry(entry string) (*SigningKey, error) {
+	kid, rest, ok := strings.Cut(entry, "=")
+	if !ok || kid == "" || rest == "" {
+		return nil, fmt.Errorf("invalid JWT key entry %q, expected kid=path[@not_before]", entry)
+	}
+
+	path, notBeforeRaw, hasNotBefore := strings.Cut(rest, "@")
+
+	var notBefore time.Time
+	if hasNotBefore {
+		parsed, err := time.Parse(time.RFC3339, notBeforeRaw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid not_before for JWT key %q: %w", kid, err)
+		}
+		notBefore = parsed
+	}
+
+	key, err := loadKey(path)
+	if err != nil {
+		return nil, fmt.Errorf("load JWT key %q: %w", kid, err)
+	}
+
+	// a verify-only key never signs, so a date to start signing with it is a mistake
+	if key.PrivateKey == nil && hasNotBefore {
+		return nil, fmt.Errorf("JWT key %q is a public key and cannot have a not_before", kid)
+	}
+
+	key.ID = kid
+	key.NotBefore = notBefore
+	return key, nil
+}
+
+// loadKey reads a private key, or a public key for a verify-only entry
+func loadKey(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{}
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.PrivateKey = signer
+		parsed = signer.Public()
+	}
+
+	switch pub := parsed.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, use RSA or Ed25519", parsed)
+	}
+
+	key.PublicKey = parsed
+	return key, nil
+}
+
+// signingKey returns the key that should sign tokens at time now
+func (k *KeySet) signingKey(now time.Time) *SigningKey {
+	var active *SigningKey
+	for _, key := range k.keys {
+		if key.PrivateKey != nil && !key.NotBefore.After(now) {
+			active = key
+		}
+	}
+
+	return active
+}
+
+func (k *KeySet) lookup(kid string) *SigningKey {
+	for _, key := range k.keys {
+		if key.ID == kid {
+			return key
+		}
+	}
+
+	return nil
+}
+
+func (k *KeySet) methods() []string {
+	seen := make(map[string]bool)
+	var methods []string
+
+	for _, key := range k.keys {
+		alg := key.Method.Alg()
+		if !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+
+	return methods
+}
+
+// JWK is the public half of a signing key in RFC 7517 format
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicJWKS returns the verification keys for /.well-known/jwks.json.
+// It is empty when tokens are signed with HS256 since that secret must stay private.
+func PublicJWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	if keySet == nil {
+		return jwks
+	}
+
+	for _, key := range keySet.keys {
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+
+		switch pub := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/lieucongduy182/go-gin-todo-api/config"
+)
+
+func useTestJWTConfig(t *testing.T) {
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{
+		JWTSecret:          "test-secret",
+		JWTIssuer:          "https://todo.example.com",
+		JWTAudience:        "https://todo.example.com",
+		AccessTokenMinutes: 15,
+	}
+	t.Cleanup(func() {
+		config.AppConfig = previous
+		keySet = nil
+	})
+}
+
+// writeEd25519Key writes a fresh key pair as PKCS #8 and PKIX PEM files and returns their paths
+func writeEd25519Key(t *testing.T, name string) (privatePath, publicPath string) {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	privatePath = filepath.Join(dir, name+".pem")
+	publicPath = filepath.Join(dir, name+".pub.pem")
+
+	if err := os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return privatePath, publicPath
+}
+
+func TestVerifyOnlyJWTKey(t *testing.T) {
+	useTestJWTConfig(t)
+
+	oldPrivate, oldPublic := writeEd25519Key(t, "old")
+	newPrivate, _ := writeEd25519Key(t, "new")
+
+	if err := LoadSigningKeys("old=" + oldPrivate); err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := GenerateToken(1, "user@example.com", "user", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the private half of the old key is gone, its public half still verifies
+	if err := LoadSigningKeys("old=" + oldPublic + ",new=" + newPrivate); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ValidateToken(oldToken); err != nil {
+		t.Errorf("token of the verify-only key: %v", err)
+	}
+
+	newToken, err := GenerateToken(1, "user@example.com", "user", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _, err := jwt.NewParser().ParseUnverified(newToken, &Claims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid := parsed.Header["kid"]; kid != "new" {
+		t.Errorf("signed with %v, want the private key", kid)
+	}
+
+	if keys := PublicJWKS().Keys; len(keys) != 2 {
+		t.Errorf("JWKS publishes %d keys, want both", len(keys))
+	}
+}
+
+func TestLoadSigningKeysRejects(t *testing.T) {
+	useTestJWTConfig(t)
+
+	_, public := writeEd25519Key(t, "key")
+
+	tests := []struct {
+		name string
+		spec string
+	}{
+		{name: "only verify-only keys", spec: "old=" + public},
+		{name: "verify-only key with not_before", spec: "old=" + public + "@2026-01-01T00:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := LoadSigningKeys(tt.spec); err == nil {
+				t.Fatalf("LoadSigningKeys(%q) accepted the spec", tt.spec)
+			}
+		})
+	}
+}
+
+func TestTokenTypes(t *testing.T) {
+	useTestJWTConfig(t)
+
+	access, err := GenerateToken(1, "user@example.com", "user", "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	impersonation, err := GenerateImpersonationToken(1, "user@example.com", "user", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge, err := GenerateMFAChallengeToken(1, "user@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ValidateToken(access); err != nil {
+		t.Errorf("access token: %v", err)
+	}
+	if claims, err := ValidateToken(impersonation); err != nil || claims.ImpersonatorID != 2 {
+		t.Errorf("impersonation token: %v, %v", claims, err)
+	}
+	if _, err := ValidateToken(challenge); err == nil {
+		t.Errorf("an MFA challenge token was accepted as an access token")
+	}
+	if _, err := ValidateMFAChallengeToken(access); err == nil {
+		t.Errorf("an access token was accepted as an MFA challenge token")
+	}
+	if _, err := ValidateMFAChallengeToken(challenge); err != nil {
+		t.Errorf("MFA challenge token: %v", err)
+	}
+
+	// the same secret shared with another service does not make its tokens ours
+	config.AppConfig.JWTAudience = "https://other.example.com"
+	if _, err := ValidateToken(access); err == nil {
+		t.Errorf("a token for another audience was accepted")
+	}
+}
@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lieucongduy182/go-gin-todo-api/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Hashes are stored in the PHC string format, e.g.
+//
+//	$argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+//
+// Legacy bcrypt hashes ($2a$, $2b$, $2y$) are still accepted and flagged for rehashing.
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+var (
+	ErrPasswordMismatch    = errors.New("password does not match")
+	ErrUnknownHashFormat   = errors.New("unknown password hash format")
+	errMalformedArgon2Hash = errors.New("malformed argon2id hash")
+)
+
+type argon2Params struct {
+	memory      uint32
+	time        uint32
+	parallelism uint8
+}
+
+func currentArgon2Params() argon2Params {
+	return argon2Params{
+		memory:      uint32(config.AppConfig.Argon2MemoryKB),
+		time:        uint32(config.AppConfig.Argon2Time),
+		parallelism: uint8(config.AppConfig.Argon2Parallelism),
+	}
+}
+
+// HashPassword hashes password with Argon2id using the configured parameters
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	p := currentArgon2Params()
+	key := argon2.IDKey([]byte(password), salt, p.time, p.memory, p.parallelism, argon2KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.memory, p.time, p.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// CheckPassword compares password with a hash produced by HashPassword or by bcrypt
+func CheckPassword(hash, password string) error {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		p, salt, key, err := decodeArgon2Hash(hash)
+		if err != nil {
+			return err
+		}
+
+		other := argon2.IDKey([]byte(password), salt, p.time, p.memory, p.parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(key, other) != 1 {
+			return ErrPasswordMismatch
+		}
+
+		return nil
+	case isBcryptHash(hash):
+		if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return ErrPasswordMismatch
+			}
+			return err
+		}
+
+		return nil
+	default:
+		return ErrUnknownHashFormat
+	}
+}
+
+// PasswordNeedsRehash reports whether hash uses another algorithm or weaker parameters than the current configuration
+func PasswordNeedsRehash(hash string) bool {
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		return true
+	}
+
+	p, _, _, err := decodeArgon2Hash(hash)
+	if err != nil {
+		return true
+	}
+
+	return p != currentArgon2Params()
+}
+
+func isBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func decodeArgon2Hash(hash string) (argon2Params, []byte, []byte, error) {
+	var p argon2Params
+
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return p, nil, nil, errMalformedArgon2Hash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, errMalformedArgon2Hash
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.parallelism); err != nil {
+		return p, nil, nil, errMalformedArgon2Hash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, errMalformedArgon2Hash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, errMalformedArgon2Hash
+	}
+
+	return p, salt, key, nil
+}
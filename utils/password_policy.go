@@ -0,0 +1,169 @@
+package utils
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/lieucongduy182/go-gin-todo-api/config"
+)
+
+// common_passwords.txt holds the most common leaked passwords, one per line.
+// Deployments add a larger list with COMMON_PASSWORDS_FILE.
+//
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+var (
+	commonPasswordsOnce sync.Once
+	commonPasswords     map[string]struct{}
+)
+
+// LoadCommonPasswords adds the passwords in the file at path, one per line, to the built-in list.
+// An empty path keeps the built-in list only.
+func LoadCommonPasswords(path string) error {
+	passwords := make(map[string]struct{})
+	if err := readPasswordList(passwords, strings.NewReader(commonPasswordList)); err != nil {
+		return err
+	}
+
+	if path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if err := readPasswordList(passwords, file); err != nil {
+			return fmt.Errorf("read %s: %w", path, err)
+		}
+	}
+
+	// from now on the lazy default in isCommonPassword must not replace this list
+	commonPasswordsOnce.Do(func() {})
+	commonPasswords = passwords
+	return nil
+}
+
+func readPasswordList(passwords map[string]struct{}, list io.Reader) error {
+	scanner := bufio.NewScanner(list)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			passwords[strings.ToLower(line)] = struct{}{}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// ErrWeakPassword is wrapped by every password policy violation
+var ErrWeakPassword = errors.New("password does not meet the password policy")
+
+// ValidatePassword enforces the password policy: a minimum length, a minimum estimated
+// entropy, no well known passwords and no password built from the user's own details.
+func ValidatePassword(password string, userInputs ...string) error {
+	cfg := config.AppConfig
+
+	if len([]rune(password)) < cfg.PasswordMinLength {
+		return fmt.Errorf("%w: must be at least %d characters", ErrWeakPassword, cfg.PasswordMinLength)
+	}
+
+	if isCommonPassword(password) {
+		return fmt.Errorf("%w: it appears in a list of commonly used passwords", ErrWeakPassword)
+	}
+
+	lower := strings.ToLower(password)
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		if local, _, ok := strings.Cut(input, "@"); ok {
+			input = local
+		}
+
+		if len(input) >= 4 && strings.Contains(lower, input) {
+			return fmt.Errorf("%w: must not contain your username or email", ErrWeakPassword)
+		}
+	}
+
+	if PasswordEntropy(password) < float64(cfg.PasswordMinEntropy) {
+		return fmt.Errorf("%w: too predictable, use a longer password or mix in other kinds of characters", ErrWeakPassword)
+	}
+
+	return nil
+}
+
+// PasswordEntropy estimates the entropy of password in bits from the character classes it uses.
+// Repeated characters and runs like "abc" or "321" do not add to the length.
+func PasswordEntropy(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	length := 0
+	var prev rune
+
+	for i, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII && unicode.IsPrint(r):
+			symbol = true
+		default:
+			other = true
+		}
+
+		if i == 0 || (r != prev && r != prev+1 && r != prev-1) {
+			length++
+		}
+		prev = r
+	}
+
+	pool := 0
+	if lower {
+		pool += 26
+	}
+	if upper {
+		pool += 26
+	}
+	if digit {
+		pool += 10
+	}
+	if symbol {
+		pool += 33
+	}
+	if other {
+		pool += 100
+	}
+
+	if pool == 0 {
+		return 0
+	}
+
+	return float64(length) * math.Log2(float64(pool))
+}
+
+// isCommonPassword also catches the usual decorations, e.g. "Password123!"
+func isCommonPassword(password string) bool {
+	commonPasswordsOnce.Do(func() {
+		commonPasswords = make(map[string]struct{})
+		_ = readPasswordList(commonPasswords, strings.NewReader(commonPasswordList))
+	})
+
+	candidate := strings.ToLower(password)
+	if _, ok := commonPasswords[candidate]; ok {
+		return true
+	}
+
+	stripped := strings.TrimRightFunc(candidate, func(r rune) bool {
+		return unicode.IsDigit(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+	})
+	_, ok := commonPasswords[stripped]
+	return ok
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lieucongduy182/go-gin-todo-api/config"
+)
+
+func TestLoadCommonPasswords(t *testing.T) {
+	previous := config.AppConfig
+	config.AppConfig = &config.Config{PasswordMinLength: 8}
+	t.Cleanup(func() {
+		config.AppConfig = previous
+		_ = LoadCommonPasswords("")
+	})
+
+	path := filepath.Join(t.TempDir(), "common-passwords.txt")
+	if err := os.WriteFile(path, []byte("Marmalade-Otter-1987\r\n\n  lantern-quartz-pony  \n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := LoadCommonPasswords(path); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		password string
+		common   bool
+	}{
+		{password: "marmalade-otter-1987", common: true},
+		{password: "Lantern-Quartz-Pony!!", common: true},
+		{password: "password123", common: true}, // the built-in list still applies
+		{password: "lantern-quartz-pony-otter", common: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			err := ValidatePassword(tt.password)
+			if got := errors.Is(err, ErrWeakPassword); got != tt.common {
+				t.Errorf("ValidatePassword(%q) = %v, want common = %v", tt.password, err, tt.common)
+			}
+		})
+	}
+
+	if err := LoadCommonPasswords(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Errorf("a missing list was accepted")
+	}
+}
@@ -0,0 +1,363 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported RFC 5545 frequencies
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+	FreqYearly  = "YEARLY"
+)
+
+// rruleMaxPeriods bounds the search for the next occurrence so that sparse rules cannot loop forever
+const rruleMaxPeriods = 50000
+
+var ErrInvalidRRule = errors.New("invalid recurrence rule")
+
+var rruleWeekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// RRuleDay is a BYDAY entry; Ordinal selects the nth (negative: nth from last) weekday
+// of the month or year and is 0 for every such weekday.
+type RRuleDay struct {
+	Ordinal int
+	Weekday time.Weekday
+}
+
+// RRule is the subset of RFC 5545 recurrence rules we support:
+// FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, BYDAY, COUNT and UNTIL.
+type RRule struct {
+	Freq     string
+	Interval int
+	ByDay    []RRuleDay
+	Count    int
+	Until    *time.Time
+}
+
+// ParseRRule parses a rule such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;COUNT=10".
+// A leading "RRULE:" is accepted. A date-only UNTIL is interpreted in loc and covers the whole day.
+func ParseRRule(value string, loc *time.Location) (*RRule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return nil, fmt.Errorf("%w: empty rule", ErrInvalidRRule)
+	}
+
+	rule := &RRule{Interval: 1}
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(value, ";") {
+		name, arg, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(strings.TrimSpace(name))
+		arg = strings.ToUpper(strings.TrimSpace(arg))
+		if !ok || arg == "" {
+			return nil, fmt.Errorf("%w: malformed part %q", ErrInvalidRRule, part)
+		}
+
+		if seen[name] {
+			return nil, fmt.Errorf("%w: %s given twice", ErrInvalidRRule, name)
+		}
+		seen[name] = true
+
+		switch name {
+		case "FREQ":
+			switch arg {
+			case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+				rule.Freq = arg
+			default:
+				return nil, fmt.Errorf("%w: unsupported FREQ %s", ErrInvalidRRule, arg)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(arg)
+			if err != nil || interval < 1 {
+				return nil, fmt.Errorf("%w: INTERVAL must be a positive number", ErrInvalidRRule)
+			}
+			rule.Interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(arg)
+			if err != nil || count < 1 {
+				return nil, fmt.Errorf("%w: COUNT must be a positive number", ErrInvalidRRule)
+			}
+			rule.Count = count
+		case "UNTIL":
+			until, err := parseRRuleUntil(arg, loc)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = &until
+		case "BYDAY":
+			days, err := parseRRuleDays(arg)
+			if err != nil {
+				return nil, err
+			}
+			rule.ByDay = days
+		default:
+			return nil, fmt.Errorf("%w: unsupported part %s", ErrInvalidRRule, name)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("%w: FREQ is required", ErrInvalidRRule)
+	}
+
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, fmt.Errorf("%w: COUNT and UNTIL cannot be combined", ErrInvalidRRule)
+	}
+
+	if rule.Freq == FreqDaily || rule.Freq == FreqWeekly {
+		for _, day := range rule.ByDay {
+			if day.Ordinal != 0 {
+				return nil, fmt.Errorf("%w: BYDAY ordinals need FREQ=MONTHLY or YEARLY", ErrInvalidRRule)
+			}
+		}
+	}
+
+	return rule, nil
+}
+
+func parseRRuleUntil(value string, loc *time.Location) (time.Time, error) {
+	if until, err := time.Parse("20060102T150405Z", value); err == nil {
+		return until, nil
+	}
+
+	if until, err := time.ParseInLocation("20060102T150405", value, loc); err == nil {
+		return until, nil
+	}
+
+	if day, err := time.ParseInLocation("20060102", value, loc); err == nil {
+		return day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+
+	return time.Time{}, fmt.Errorf("%w: UNTIL must look like 20261231 or 20261231T170000Z", ErrInvalidRRule)
+}
+
+func parseRRuleDays(value string) ([]RRuleDay, error) {
+	var days []RRuleDay
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) < 2 {
+			return nil, fmt.Errorf("%w: bad BYDAY value %q", ErrInvalidRRule, item)
+		}
+
+		weekday, ok := rruleWeekdays[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("%w: bad BYDAY value %q", ErrInvalidRRule, item)
+		}
+
+		day := RRuleDay{Weekday: weekday}
+		if prefix := item[:len(item)-2]; prefix != "" {
+			ordinal, err := strconv.Atoi(prefix)
+			if err != nil || ordinal == 0 || ordinal > 53 || ordinal < -53 {
+				return nil, fmt.Errorf("%w: bad BYDAY value %q", ErrInvalidRRule, item)
+			}
+			day.Ordinal = ordinal
+		}
+
+		days = append(days, day)
+	}
+
+	return days, nil
+}
+
+// String renders the rule in canonical form
+func (r *RRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			name := strings.ToUpper(day.Weekday.String()[:2])
+			if day.Ordinal != 0 {
+				name = strconv.Itoa(day.Ordinal) + name
+			}
+			days = append(days, name)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence strictly after `after` of the series starting at dtstart,
+// or false once COUNT or UNTIL end the series.
+//
+// Occurrences are computed on the wall clock of loc, so a 09:00 task stays at 09:00 across DST
+// changes. As in RFC 5545, dtstart is always the first occurrence and candidates falling on
+// dates that do not exist (e.g. the 31st in a 30-day month, Feb 29 in a common year) are skipped
+// rather than moved.
+func (r *RRule) Next(dtstart, after time.Time, loc *time.Location) (time.Time, bool) {
+	start := dtstart.In(loc)
+	count := 1
+
+	if start.After(after) {
+		return start, r.Until == nil || !start.After(*r.Until)
+	}
+
+	for period := 0; period < rruleMaxPeriods; period++ {
+		for _, candidate := range r.candidates(start, period, loc) {
+			if !candidate.After(start) {
+				continue
+			}
+
+			if r.Until != nil && candidate.After(*r.Until) {
+				return time.Time{}, false
+			}
+
+			count++
+			if r.Count > 0 && count > r.Count {
+				return time.Time{}, false
+			}
+
+			if candidate.After(after) {
+				return candidate, true
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// candidates lists the occurrences of the nth period of the rule in ascending order,
+// before filtering against dtstart, COUNT and UNTIL
+func (r *RRule) candidates(start time.Time, period int, loc *time.Location) []time.Time {
+	at := func(year int, month time.Month, day int) time.Time {
+		return wallClock(year, month, day, start, loc)
+	}
+	step := period * r.Interval
+
+	switch r.Freq {
+	case FreqDaily:
+		day := at(start.Year(), start.Month(), start.Day()+step)
+		if len(r.ByDay) > 0 && !r.hasWeekday(day.Weekday()) {
+			return nil
+		}
+		return []time.Time{day}
+
+	case FreqWeekly:
+		// weeks start on Monday (RFC 5545 default WKST)
+		offset := (int(start.Weekday()) + 6) % 7
+		monday := at(start.Year(), start.Month(), start.Day()-offset+7*step)
+		if len(r.ByDay) == 0 {
+			return []time.Time{at(monday.Year(), monday.Month(), monday.Day()+offset)}
+		}
+
+		var days []time.Time
+		for i := 0; i < 7; i++ {
+			day := at(monday.Year(), monday.Month(), monday.Day()+i)
+			if r.hasWeekday(day.Weekday()) {
+				days = append(days, day)
+			}
+		}
+		return days
+
+	case FreqMonthly:
+		first := at(start.Year(), start.Month()+time.Month(step), 1)
+		if len(r.ByDay) == 0 {
+			return existingDay(at, first.Year(), first.Month(), start.Day())
+		}
+		return r.weekdaysBetween(first, first.AddDate(0, 1, 0), at)
+
+	case FreqYearly:
+		year := start.Year() + step
+		if len(r.ByDay) == 0 {
+			return existingDay(at, year, start.Month(), start.Day())
+		}
+		first := at(year, time.January, 1)
+		return r.weekdaysBetween(first, first.AddDate(1, 0, 0), at)
+	}
+
+	return nil
+}
+
+// wallClock returns the given date at the time of day of clock in loc.
+// A time skipped by a DST change is read with the offset in effect before the change
+// (RFC 5545 section 3.3.5), so 02:30 on a spring-forward day becomes 03:30.
+func wallClock(year int, month time.Month, day int, clock time.Time, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, clock.Hour(), clock.Minute(), clock.Second(), clock.Nanosecond(), loc)
+	if t.Hour() == clock.Hour() && t.Minute() == clock.Minute() {
+		return t
+	}
+
+	_, offset := t.Add(-24 * time.Hour).Zone()
+	utc := time.Date(year, month, day, clock.Hour(), clock.Minute(), clock.Second(), clock.Nanosecond(), time.UTC)
+	return utc.Add(-time.Duration(offset) * time.Second).In(loc)
+}
+
+// existingDay returns the given date, or nothing if it does not exist in that month
+func existingDay(at func(int, time.Month, int) time.Time, year int, month time.Month, day int) []time.Time {
+	date := at(year, month, day)
+	if date.Month() != month {
+		return nil
+	}
+	return []time.Time{date}
+}
+
+// weekdaysBetween expands BYDAY within [first, end), honouring ordinals
+func (r *RRule) weekdaysBetween(first, end time.Time, at func(int, time.Month, int) time.Time) []time.Time {
+	byWeekday := make(map[time.Weekday][]time.Time)
+	for day := first; day.Before(end); day = at(day.Year(), day.Month(), day.Day()+1) {
+		byWeekday[day.Weekday()] = append(byWeekday[day.Weekday()], day)
+	}
+
+	seen := make(map[time.Time]bool)
+	var days []time.Time
+	add := func(day time.Time) {
+		if !seen[day] {
+			seen[day] = true
+			days = append(days, day)
+		}
+	}
+
+	for _, rule := range r.ByDay {
+		matches := byWeekday[rule.Weekday]
+		switch {
+		case rule.Ordinal == 0:
+			for _, day := range matches {
+				add(day)
+			}
+		case rule.Ordinal > 0 && rule.Ordinal <= len(matches):
+			add(matches[rule.Ordinal-1])
+		case rule.Ordinal < 0 && -rule.Ordinal <= len(matches):
+			add(matches[len(matches)+rule.Ordinal])
+		}
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days
+}
+
+func (r *RRule) hasWeekday(weekday time.Weekday) bool {
+	for _, day := range r.ByDay {
+		if day.Weekday == weekday {
+			return true
+		}
+	}
+	return false
+}
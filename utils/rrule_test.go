@@ -0,0 +1,230 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s is not available: %v", name, err)
+	}
+	return loc
+}
+
+// occurrences expands a series with Next, stopping after limit occurrences or when the series ends
+func occurrences(rule *RRule, dtstart time.Time, loc *time.Location, limit int) []time.Time {
+	var series []time.Time
+
+	after := dtstart.Add(-time.Nanosecond)
+	for len(series) < limit {
+		next, ok := rule.Next(dtstart, after, loc)
+		if !ok {
+			break
+		}
+		series = append(series, next)
+		after = next
+	}
+
+	return series
+}
+
+func TestRRuleNext(t *testing.T) {
+	ny := mustLoadLocation(t, "America/New_York")
+	at := func(year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, ny)
+	}
+
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart time.Time
+		// limit caps the expansion; finite series use more than len(want) to prove they end
+		limit int
+		want  []time.Time
+	}{
+		{
+			name:    "09:00 daily keeps the wall clock across spring-forward",
+			rule:    "FREQ=DAILY",
+			dtstart: at(2026, time.March, 7, 9, 0),
+			limit:   3,
+			want:    []time.Time{at(2026, time.March, 7, 9, 0), at(2026, time.March, 8, 9, 0), at(2026, time.March, 9, 9, 0)},
+		},
+		{
+			name:    "09:00 daily keeps the wall clock across fall-back",
+			rule:    "FREQ=DAILY",
+			dtstart: at(2026, time.October, 31, 9, 0),
+			limit:   3,
+			want:    []time.Time{at(2026, time.October, 31, 9, 0), at(2026, time.November, 1, 9, 0), at(2026, time.November, 2, 9, 0)},
+		},
+		{
+			name:    "09:00 weekly across both DST changes",
+			rule:    "FREQ=WEEKLY;BYDAY=SU;INTERVAL=36",
+			dtstart: at(2026, time.March, 1, 9, 0),
+			limit:   2,
+			want:    []time.Time{at(2026, time.March, 1, 9, 0), at(2026, time.November, 8, 9, 0)},
+		},
+		{
+			name:    "02:30 series moves to 03:30 on the spring-forward day only",
+			rule:    "FREQ=DAILY",
+			dtstart: at(2026, time.March, 7, 2, 30),
+			limit:   3,
+			want: []time.Time{
+				at(2026, time.March, 7, 2, 30),
+				time.Date(2026, time.March, 8, 7, 30, 0, 0, time.UTC), // 03:30 EDT
+				at(2026, time.March, 9, 2, 30),
+			},
+		},
+		{
+			// dtstart is an instant: 02:30 that day only exists as 02:30-05:00, i.e. 03:30 EDT,
+			// and the series keeps the wall clock that instant has
+			name:    "02:30 dtstart on the spring-forward day",
+			rule:    "FREQ=DAILY",
+			dtstart: time.Date(2026, time.March, 8, 2, 30, 0, 0, time.FixedZone("EST", -5*3600)),
+			limit:   2,
+			want:    []time.Time{at(2026, time.March, 8, 3, 30), at(2026, time.March, 9, 3, 30)},
+		},
+		{
+			name:    "monthly on the 31st skips shorter months",
+			rule:    "FREQ=MONTHLY",
+			dtstart: at(2026, time.January, 31, 9, 0),
+			limit:   4,
+			want:    []time.Time{at(2026, time.January, 31, 9, 0), at(2026, time.March, 31, 9, 0), at(2026, time.May, 31, 9, 0), at(2026, time.July, 31, 9, 0)},
+		},
+		{
+			name:    "yearly on Feb 29 skips common years",
+			rule:    "FREQ=YEARLY",
+			dtstart: at(2024, time.February, 29, 9, 0),
+			limit:   3,
+			want:    []time.Time{at(2024, time.February, 29, 9, 0), at(2028, time.February, 29, 9, 0), at(2032, time.February, 29, 9, 0)},
+		},
+		{
+			name:    "last Friday of the month",
+			rule:    "FREQ=MONTHLY;BYDAY=-1FR",
+			dtstart: at(2026, time.January, 30, 9, 0),
+			limit:   4,
+			want:    []time.Time{at(2026, time.January, 30, 9, 0), at(2026, time.February, 27, 9, 0), at(2026, time.March, 27, 9, 0), at(2026, time.April, 24, 9, 0)},
+		},
+		{
+			name:    "fifth Monday skips months that have only four",
+			rule:    "FREQ=MONTHLY;BYDAY=5MO",
+			dtstart: at(2026, time.March, 30, 9, 0),
+			limit:   3,
+			want:    []time.Time{at(2026, time.March, 30, 9, 0), at(2026, time.June, 29, 9, 0), at(2026, time.August, 31, 9, 0)},
+		},
+		{
+			name:    "COUNT includes dtstart",
+			rule:    "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=3",
+			dtstart: at(2026, time.January, 5, 9, 0),
+			limit:   10,
+			want:    []time.Time{at(2026, time.January, 5, 9, 0), at(2026, time.January, 7, 9, 0), at(2026, time.January, 12, 9, 0)},
+		},
+		{
+			name:    "UTC UNTIL is inclusive",
+			rule:    "FREQ=DAILY;INTERVAL=2;UNTIL=20260105T140000Z",
+			dtstart: at(2026, time.January, 1, 9, 0),
+			limit:   10,
+			want:    []time.Time{at(2026, time.January, 1, 9, 0), at(2026, time.January, 3, 9, 0), at(2026, time.January, 5, 9, 0)},
+		},
+		{
+			name:    "date-only UNTIL covers the whole day",
+			rule:    "FREQ=WEEKLY;UNTIL=20260115",
+			dtstart: at(2026, time.January, 1, 21, 0),
+			limit:   10,
+			want:    []time.Time{at(2026, time.January, 1, 21, 0), at(2026, time.January, 8, 21, 0), at(2026, time.January, 15, 21, 0)},
+		},
+		{
+			name:    "UNTIL before dtstart yields nothing",
+			rule:    "FREQ=DAILY;UNTIL=20251231",
+			dtstart: at(2026, time.January, 1, 9, 0),
+			limit:   10,
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRRule(tt.rule, ny)
+			if err != nil {
+				t.Fatalf("ParseRRule(%q): %v", tt.rule, err)
+			}
+
+			got := occurrences(rule, tt.dtstart, ny, tt.limit)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d occurrences %v, want %d %v", len(got), got, len(tt.want), tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("occurrence %d = %v, want %v", i, got[i], tt.want[i].In(ny))
+				}
+			}
+		})
+	}
+}
+
+func TestRRuleNextAfterMidSeries(t *testing.T) {
+	ny := mustLoadLocation(t, "America/New_York")
+
+	rule, err := ParseRRule("FREQ=DAILY;COUNT=5", ny)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dtstart := time.Date(2026, time.March, 6, 9, 0, 0, 0, ny)
+
+	// asking from an arbitrary point still counts from dtstart
+	next, ok := rule.Next(dtstart, time.Date(2026, time.March, 8, 12, 0, 0, 0, ny), ny)
+	if !ok || !next.Equal(time.Date(2026, time.March, 9, 9, 0, 0, 0, ny)) {
+		t.Errorf("Next = %v, %v; want March 9 09:00", next, ok)
+	}
+
+	if _, ok := rule.Next(dtstart, time.Date(2026, time.March, 10, 9, 0, 0, 0, ny), ny); ok {
+		t.Errorf("the fifth occurrence is the last one")
+	}
+}
+
+func TestParseRRule(t *testing.T) {
+	tests := []struct {
+		value     string
+		canonical string
+		invalid   bool
+	}{
+		{value: "RRULE:freq=weekly;interval=2;byday=mo,fr", canonical: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR"},
+		{value: "FREQ=MONTHLY;BYDAY=-1FR;COUNT=6", canonical: "FREQ=MONTHLY;BYDAY=-1FR;COUNT=6"},
+		{value: "FREQ=DAILY;UNTIL=20261231T170000Z", canonical: "FREQ=DAILY;UNTIL=20261231T170000Z"},
+		{value: "", invalid: true},
+		{value: "INTERVAL=2", invalid: true},
+		{value: "FREQ=HOURLY", invalid: true},
+		{value: "FREQ=DAILY;INTERVAL=0", invalid: true},
+		{value: "FREQ=DAILY;COUNT=3;UNTIL=20261231", invalid: true},
+		{value: "FREQ=WEEKLY;BYDAY=2MO", invalid: true},
+		{value: "FREQ=MONTHLY;BYDAY=0MO", invalid: true},
+		{value: "FREQ=MONTHLY;BYDAY=XX", invalid: true},
+		{value: "FREQ=DAILY;FREQ=WEEKLY", invalid: true},
+		{value: "FREQ=DAILY;BYMONTH=1", invalid: true},
+		{value: "FREQ=DAILY;UNTIL=tomorrow", invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			rule, err := ParseRRule(tt.value, time.UTC)
+			if tt.invalid {
+				if !errors.Is(err, ErrInvalidRRule) {
+					t.Fatalf("err = %v, want ErrInvalidRRule", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rule.String(); got != tt.canonical {
+				t.Errorf("String() = %q, want %q", got, tt.canonical)
+			}
+		})
+	}
+}
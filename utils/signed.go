@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lieucongduy182/go-gin-todo-api/config"
+)
+
+var ErrInvalidSignedToken = errors.New("invalid or expired signed token")
+
+// SignPayload produces a tamper-proof, expiring token for payload.
+// The purpose is mixed into the signature so a token minted for one flow
+// (e.g. email verification) cannot be replayed against another.
+func SignPayload(purpose, payload string, expiresAt time.Time) string {
+	body := payload + "|" + strconv.FormatInt(expiresAt.Unix(), 10)
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(body))
+
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(signature(purpose, body))
+}
+
+// VerifySignedPayload checks a token produced by SignPayload and returns its payload
+func VerifySignedPayload(purpose, token string) (string, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", ErrInvalidSignedToken
+	}
+
+	rawBody, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalidSignedToken
+	}
+
+	rawSig, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return "", ErrInvalidSignedToken
+	}
+
+	body := string(rawBody)
+	if !hmac.Equal(rawSig, signature(purpose, body)) {
+		return "", ErrInvalidSignedToken
+	}
+
+	idx := strings.LastIndex(body, "|")
+	if idx < 0 {
+		return "", ErrInvalidSignedToken
+	}
+
+	expiresAt, err := strconv.ParseInt(body[idx+1:], 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return "", ErrInvalidSignedToken
+	}
+
+	return body[:idx], nil
+}
+
+func signature(purpose, body string) []byte {
+	mac := hmac.New(sha256.New, []byte(config.AppConfig.JWTSecret))
+	mac.Write([]byte(purpose))
+	mac.Write([]byte{0})
+	mac.Write([]byte(body))
+
+	return mac.Sum(nil)
+}
+
+// Fingerprint derives a stable, secret-keyed digest of value.
+// Unlike a plain hash it cannot be recomputed without the server secret.
+func Fingerprint(purpose, value string) []byte {
+	return signature(purpose, value)
+}
@@ -0,0 +1,64 @@
+package utils
+
+import "strings"
+
+// DescribeUserAgent turns a User-Agent header into a short label such as "Firefox on Linux".
+// It only needs to be good enough for a user to recognise their own devices.
+func DescribeUserAgent(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	ua := strings.ToLower(userAgent)
+
+	clients := []struct{ token, name string }{
+		{"curl/", "curl"},
+		{"postmanruntime", "Postman"},
+		{"insomnia", "Insomnia"},
+		{"go-http-client", "Go client"},
+		{"python-requests", "Python client"},
+		{"okhttp", "Android app"},
+	}
+	for _, client := range clients {
+		if strings.Contains(ua, client.token) {
+			return client.name
+		}
+	}
+
+	// order matters: Edge and Opera also claim to be Chrome, Chrome also claims to be Safari
+	browser := "Browser"
+	for _, candidate := range []struct{ token, name string }{
+		{"edg/", "Edge"},
+		{"opr/", "Opera"},
+		{"firefox/", "Firefox"},
+		{"chrome/", "Chrome"},
+		{"safari/", "Safari"},
+	} {
+		if strings.Contains(ua, candidate.token) {
+			browser = candidate.name
+			break
+		}
+	}
+
+	os := ""
+	for _, candidate := range []struct{ token, name string }{
+		{"iphone", "iOS"},
+		{"ipad", "iPadOS"},
+		{"android", "Android"},
+		{"windows", "Windows"},
+		{"mac os x", "macOS"},
+		{"cros", "ChromeOS"},
+		{"linux", "Linux"},
+	} {
+		if strings.Contains(ua, candidate.token) {
+			os = candidate.name
+			break
+		}
+	}
+
+	if os == "" {
+		return browser
+	}
+
+	return browser + " on " + os
+}